@@ -0,0 +1,132 @@
+// Command bhavcopy-import loads exchange bhavcopy files into the price
+// history.
+//
+// Every .csv or .zip file under -dir is read; NSE (legacy and UDiFF) and BSE
+// (legacy and UDiFF) layouts are recognised from the header. Each close is
+// stored in stock_price_history and each OHLC row in stock_price_daily_bars.
+// Files already imported are skipped unless -force is given, and re-running
+// an import leaves the same data. With -seed-prices stock_prices is set to the
+// latest close where that is newer than the stored price. The database is
+// configured with the same environment variables as the API.
+//
+//	go run ./cmd/bhavcopy-import -dir ./bhavcopies
+//	go run ./cmd/bhavcopy-import -dir ./bhavcopies -all-symbols -seed-prices
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/LoganX64/stocky-api/internal/bhavcopy"
+	"github.com/LoganX64/stocky-api/internal/config"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory with bhavcopy .csv or .zip files (required)")
+	seed := flag.Bool("seed-prices", false, "set stock_prices to the latest close where it is newer")
+	all := flag.Bool("all-symbols", false, "import every symbol, not only those in stock_prices")
+	series := flag.String("series", strings.Join(bhavcopy.DefaultSeries, ","), "NSE series to import")
+	force := flag.Bool("force", false, "re-import files that were imported before")
+	actor := flag.String("actor", os.Getenv("STOCKY_ACTOR"), "operator recorded as the importer")
+	flag.Parse()
+
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, err := findFiles(*dir)
+	if err != nil {
+		log.Fatalf("reading %s: %v", *dir, err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no .csv or .zip files in %s", *dir)
+	}
+
+	cfg := config.MustLoad()
+	db, err := sql.Open("postgres", fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host, cfg.Database.DbPort, cfg.Database.User,
+		cfg.Database.Password, cfg.Database.DBName, cfg.Database.SSLMode,
+	))
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("failed to ping db: %v", err)
+	}
+
+	opts := bhavcopy.Options{
+		AllSymbols: *all,
+		SeedPrices: *seed,
+		Series:     strings.Split(*series, ","),
+		Force:      *force,
+		Actor:      *actor,
+	}
+
+	var imported, skipped, failed, rows, seeded int
+	start := time.Now()
+	for i, path := range files {
+		prefix := fmt.Sprintf("[%d/%d] %s:", i+1, len(files), filepath.Base(path))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Println(prefix, "error:", err)
+			failed++
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		res, err := bhavcopy.Import(ctx, db, filepath.Base(path), data, opts)
+		cancel()
+		switch {
+		case errors.Is(err, bhavcopy.ErrAlreadyImported):
+			fmt.Println(prefix, "already imported on", res.ImportedAt.Format(time.RFC3339), "(use -force to redo)")
+			skipped++
+		case err != nil:
+			fmt.Println(prefix, "error:", err)
+			failed++
+		default:
+			fmt.Printf("%s %s %s, %d rows, %d imported, %d skipped, %d prices seeded\n",
+				prefix, res.Format, res.TradeDate, res.Rows, res.Imported, res.Skipped, res.Seeded)
+			imported++
+			rows += res.Imported
+			seeded += res.Seeded
+		}
+	}
+
+	fmt.Printf("done in %s: %d files imported (%d prices, %d seeded), %d already imported, %d failed\n",
+		time.Since(start).Round(time.Millisecond), imported, rows, seeded, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findFiles lists bhavcopy candidates under dir in name order.
+func findFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !d.IsDir() && (ext == ".csv" || ext == ".zip") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
@@ -0,0 +1,143 @@
+// Command bulk-adjustments uploads an adjustment CSV to the Stocky API.
+//
+// The CSV needs the header
+// reward_id,type,unit_basis,delta_quantity,delta_amount,reason,reason_code,ticket_ref.
+// By default the file is only validated and per-row errors are printed; pass
+// -commit to create a pending batch, which is applied once approved through
+// POST /api/v1/adjustment-batches/:id/approve.
+//
+//	go run ./cmd/bulk-adjustments -file corrections.csv
+//	go run ./cmd/bulk-adjustments -file corrections.csv -commit -actor ops.alice
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type rowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+type result struct {
+	Message           string     `json:"message"`
+	Error             string     `json:"error"`
+	RowCount          int        `json:"row_count"`
+	Valid             bool       `json:"valid"`
+	Errors            []rowError `json:"errors"`
+	TotalINRValue     float64    `json:"total_inr_value"`
+	RequiredApprovals int        `json:"required_approvals"`
+	Data              *struct {
+		ID                int `json:"id"`
+		RowCount          int `json:"row_count"`
+		RequiredApprovals int `json:"required_approvals"`
+	} `json:"data"`
+}
+
+func main() {
+	apiURL := flag.String("api", "http://localhost:8080", "base URL of the Stocky API")
+	path := flag.String("file", "", "path to the adjustment CSV (required)")
+	actor := flag.String("actor", os.Getenv("STOCKY_ACTOR"), "operator ID sent as X-Actor-ID (required with -commit)")
+	commit := flag.Bool("commit", false, "create a pending batch instead of only validating")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *commit && *actor == "" {
+		log.Fatal("-actor (or STOCKY_ACTOR) is required with -commit")
+	}
+
+	mode := "validate"
+	if *commit {
+		mode = "commit"
+	}
+
+	body, contentType, err := multipartCSV(*path)
+	if err != nil {
+		log.Fatal("Failed to read CSV:", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(*apiURL, "/")+"/api/v1/adjustments/bulk?mode="+mode, body)
+	if err != nil {
+		log.Fatal("Failed to build request:", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if *actor != "" {
+		req.Header.Set("X-Actor-ID", *actor)
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatal("Request failed:", err)
+	}
+	defer resp.Body.Close()
+
+	var res result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		log.Fatalf("Unexpected response (HTTP %d): %v", resp.StatusCode, err)
+	}
+
+	if res.Error != "" {
+		log.Fatalf("HTTP %d: %s", resp.StatusCode, res.Error)
+	}
+
+	for _, e := range res.Errors {
+		if e.Field != "" {
+			fmt.Printf("row %d: %s: %s\n", e.Row, e.Field, e.Error)
+		} else {
+			fmt.Printf("row %d: %s\n", e.Row, e.Error)
+		}
+	}
+
+	if res.Data != nil {
+		fmt.Printf("Created batch %d with %d rows; needs %d approval(s).\n",
+			res.Data.ID, res.Data.RowCount, res.Data.RequiredApprovals)
+		return
+	}
+
+	fmt.Printf("%d rows, %d errors, total value INR %.2f, needs %d approval(s).\n",
+		res.RowCount, len(res.Errors), res.TotalINRValue, res.RequiredApprovals)
+	if res.Message != "" {
+		fmt.Println(res.Message)
+	}
+	if !res.Valid {
+		os.Exit(1)
+	}
+}
+
+func multipartCSV(path string) (io.Reader, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
@@ -0,0 +1,199 @@
+// Command marketsim writes a simulated price path to CSV.
+//
+// Prices follow a geometric Brownian motion from a seed, so the same flags
+// always give the same file. Parameters come from the flags, optionally over
+// a JSON config file in the format the marketsim price provider reads
+// (PRICE_SIM_CONFIG). Steps that fall in an outage window are left out. The
+// output has the columns symbol,price,timestamp, which the file price
+// provider reads as well.
+//
+//	go run ./cmd/marketsim -symbols TCS=3450,INFY=1500 -from 2026-10-19 -to 2026-10-20 -step 15m
+//	go run ./cmd/marketsim -config sim.json -seed 7 -out path.csv
+//	go run ./cmd/marketsim -symbols TCS=3450 -outage 2026-10-19T10:00:00Z/2026-10-19T11:00:00Z
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/marketsim"
+)
+
+// outageFlags collects repeated -outage values of the form
+// start/end[/SYMBOL,SYMBOL].
+type outageFlags []marketsim.Outage
+
+func (o *outageFlags) String() string { return fmt.Sprintf("%d outages", len(*o)) }
+
+func (o *outageFlags) Set(v string) error {
+	parts := strings.Split(v, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("expected start/end[/SYMBOLS], got %q", v)
+	}
+	start, err := parseTime(parts[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(parts[1])
+	if err != nil {
+		return err
+	}
+	outage := marketsim.Outage{Start: start, End: end}
+	if len(parts) == 3 {
+		outage.Symbols = splitList(parts[2])
+	}
+	*o = append(*o, outage)
+	return nil
+}
+
+func main() {
+	var outages outageFlags
+	configFile := flag.String("config", "", "JSON simulation config (seed, epoch, step, symbols, outages)")
+	seed := flag.Int64("seed", 0, "random seed (default: config seed, or 1)")
+	symbols := flag.String("symbols", "", "comma-separated SYMBOL=START list; SYMBOL alone uses the configured start")
+	from := flag.String("from", "", "first time to write, RFC 3339 or YYYY-MM-DD (default: the epoch)")
+	to := flag.String("to", "", "last time to write (default: one day after -from)")
+	step := flag.Duration("step", 0, "time between prices (default: config step, or 1m)")
+	volatility := flag.Float64("volatility", -1, "default annualised volatility, e.g. 0.25")
+	drift := flag.Float64("drift", 0, "default annualised drift, e.g. 0.05")
+	out := flag.String("out", "", "output file (default: stdout)")
+	flag.Var(&outages, "outage", "outage window start/end[/SYMBOL,SYMBOL]; repeatable")
+	flag.Parse()
+
+	cfg := marketsim.DefaultConfig()
+	if *configFile != "" {
+		var err error
+		if cfg, err = marketsim.LoadConfig(*configFile, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// Flags given explicitly win over the config file.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			cfg.Seed = *seed
+		case "step":
+			cfg.Step = *step
+		case "volatility":
+			cfg.Volatility = *volatility
+		case "drift":
+			cfg.Drift = *drift
+		}
+	})
+	cfg.Outages = append(cfg.Outages, outages...)
+
+	var names []string
+	for _, entry := range splitList(*symbols) {
+		name, start, hasStart := strings.Cut(entry, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if hasStart {
+			price, err := strconv.ParseFloat(strings.TrimSpace(start), 64)
+			if err != nil || price <= 0 {
+				log.Fatalf("invalid start price for %s: %q", name, start)
+			}
+			p := cfg.Symbols[name]
+			p.Start = price
+			cfg.Symbols[name] = p
+		}
+		names = append(names, name)
+	}
+
+	sim, err := marketsim.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(names) == 0 {
+		names = sim.Symbols()
+	}
+	if len(names) == 0 {
+		log.Fatal("no symbols: pass -symbols or list them in -config")
+	}
+
+	start := cfg.Epoch
+	if *from != "" {
+		if start, err = parseTime(*from); err != nil {
+			log.Fatal(err)
+		}
+	}
+	end := start.Add(24 * time.Hour)
+	if *to != "" {
+		if end, err = parseTime(*to); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if end.Before(start) {
+		log.Fatal("-to is before -from")
+	}
+
+	points, err := sim.Path(names, start, end)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	written, skipped, err := writeCSV(w, points)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "seed %d: %d prices for %d symbols from %s to %s every %s, %d in outages left out\n",
+		cfg.Seed, written, len(names), start.Format(time.RFC3339), end.Format(time.RFC3339), cfg.Step, skipped)
+}
+
+func writeCSV(w io.Writer, points []marketsim.Point) (written, skipped int, err error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"symbol", "price", "timestamp"}); err != nil {
+		return 0, 0, err
+	}
+	for _, p := range points {
+		if p.Outage {
+			skipped++
+			continue
+		}
+		if err := cw.Write([]string{
+			p.Symbol,
+			strconv.FormatFloat(p.Price, 'f', -1, 64),
+			p.Time.Format(time.RFC3339),
+		}); err != nil {
+			return written, skipped, err
+		}
+		written++
+	}
+	cw.Flush()
+	return written, skipped, cw.Error()
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", s)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
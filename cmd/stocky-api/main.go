@@ -24,6 +24,8 @@ import (
 	"github.com/LoganX64/stocky-api/internal/config"
 	routes "github.com/LoganX64/stocky-api/internal/handlers/stocky"
 	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/leader"
+	"github.com/LoganX64/stocky-api/internal/market"
 )
 
 var db *sql.DB
@@ -53,6 +55,7 @@ func main() {
 	}
 	logrus.Info("connected to Database successfully")
 	routes.InitDB(db)
+	routes.InitConfig(cfg)
 
 	dbURL := fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?sslmode=%s",
@@ -72,7 +75,33 @@ func main() {
 	r := gin.Default()
 	routes.Routes(r)
 
-	go jobs.StartPriceUpdater(db)
+	provider, err := jobs.NewPriceProvider(cfg.PriceProvider)
+	if err != nil {
+		logrus.Fatalf("failed to configure price provider: %v", err)
+	}
+	calendar := market.MustLoad(cfg.Market.Timezone, cfg.Market.SessionOpen, cfg.Market.SessionClose, cfg.Market.HolidaysFile)
+	priceUpdater := jobs.NewPriceUpdater(db, provider, cfg.PriceUpdater, calendar)
+	routes.InitPriceUpdater(priceUpdater)
+	corporateActions := jobs.NewCorporateActions(db, cfg.CorporateActions, calendar.Location)
+	routes.InitCorporateActions(corporateActions)
+
+	// Only the elected instance runs the scheduled jobs, so replicas can
+	// share one database.
+	elector := leader.New(db, cfg.Leader)
+	priceUpdater.SetLeadership(elector)
+	corporateActions.SetLeadership(elector)
+	routes.InitLeader(elector)
+	go elector.Run()
+	go priceUpdater.Start()
+	go corporateActions.Start()
+
+	// Prices are announced through Postgres, so streams on every replica see
+	// the prices the leader stores.
+	priceListener, err := jobs.NewPriceListener(connStr)
+	if err != nil {
+		logrus.Fatalf("failed to listen for price updates: %v", err)
+	}
+	go priceListener.Run()
 
 	port := cfg.HTTPServer.Port
 	if port == "" {
@@ -83,6 +112,9 @@ func main() {
 		Addr:    port,
 		Handler: r,
 	}
+	// Streaming responses never finish on their own; end them so Shutdown
+	// can drain the server.
+	srv.RegisterOnShutdown(jobs.ClosePriceStreams)
 
 	go func() {
 		logrus.Infof("starting server on %s", port)
@@ -102,6 +134,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logrus.Fatal("Server forced to shutdown: ", err)
 	}
+	priceListener.Stop()
+	// Hand leadership over now rather than when the connection times out.
+	elector.Stop()
 	logrus.Info("Server exited")
 
 }
@@ -0,0 +1,329 @@
+package bhavcopy
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SourceBhavcopy marks prices loaded from an exchange file.
+const SourceBhavcopy = "bhavcopy"
+
+// DefaultSeries are the NSE series imported unless configured otherwise:
+// regular equity and trade-for-trade.
+var DefaultSeries = []string{"EQ", "BE"}
+
+// ErrAlreadyImported is returned when a file with the same contents was
+// imported before and Options.Force is not set.
+var ErrAlreadyImported = errors.New("bhavcopy already imported")
+
+// ErrInvalidFile wraps errors reading the file itself.
+var ErrInvalidFile = errors.New("invalid bhavcopy")
+
+// Options controls an import.
+type Options struct {
+	// AllSymbols imports every symbol in the file instead of only those
+	// already in stock_prices.
+	AllSymbols bool
+	// SeedPrices sets stock_prices to the file's close when it is newer than
+	// the stored price, adding symbols that are not there yet.
+	SeedPrices bool
+	// Series lists the NSE series to import; empty means DefaultSeries. BSE
+	// rows are not filtered by group.
+	Series []string
+	// Force re-imports a file that was imported before.
+	Force bool
+	// Actor is recorded as the importer.
+	Actor string
+}
+
+// Result summarises one imported file.
+type Result struct {
+	FileName    string    `json:"file_name"`
+	Format      string    `json:"format"`
+	TradeDate   string    `json:"trade_date"`
+	Rows        int       `json:"rows"`
+	Imported    int       `json:"imported"`
+	Skipped     int       `json:"skipped"`
+	Seeded      int       `json:"seeded"`
+	AlreadyDone bool      `json:"already_imported"`
+	ImportedAt  time.Time `json:"imported_at"`
+}
+
+// Checksum identifies a file by content.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Import parses data and loads it. The history rows, daily bars and, with
+// SeedPrices, the current prices are upserted in one transaction, so running
+// an import twice leaves the same state; the file is also recorded by
+// checksum so that repeats are reported instead of silently redone.
+func Import(ctx context.Context, db *sql.DB, name string, data []byte, opts Options) (Result, error) {
+	res := Result{FileName: name}
+
+	checksum := Checksum(data)
+	if !opts.Force {
+		var importedAt time.Time
+		err := db.QueryRowContext(ctx, `SELECT imported_at FROM price_import_files WHERE sha256 = $1`, checksum).Scan(&importedAt)
+		if err == nil {
+			res.AlreadyDone, res.ImportedAt = true, importedAt
+			return res, ErrAlreadyImported
+		}
+		if err != sql.ErrNoRows {
+			return res, err
+		}
+	}
+
+	file, err := Parse(name, data)
+	if err != nil {
+		return res, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	res.Format, res.Rows = file.Format, file.Rows
+	res.TradeDate = file.TradeDate().Format("2006-01-02")
+
+	known, err := knownSymbols(ctx, db)
+	if err != nil {
+		return res, err
+	}
+	records := selectRecords(file.Records, known, opts)
+	res.Imported = len(records)
+	res.Skipped = file.Rows - len(records)
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	if len(records) > 0 {
+		if err := writeHistory(ctx, tx, records); err != nil {
+			return res, err
+		}
+		if opts.SeedPrices {
+			if res.Seeded, err = seedPrices(ctx, tx, records); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	actor := opts.Actor
+	if actor == "" {
+		actor = "system"
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO price_import_files
+			(file_name, sha256, format, trade_date, rows_total, rows_imported, prices_seeded, imported_by, imported_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (sha256) DO UPDATE
+		SET file_name = EXCLUDED.file_name,
+			rows_imported = EXCLUDED.rows_imported,
+			prices_seeded = EXCLUDED.prices_seeded,
+			imported_by = EXCLUDED.imported_by,
+			imported_at = NOW()
+		RETURNING imported_at
+	`, name, checksum, file.Format, res.TradeDate, file.Rows, res.Imported, res.Seeded, actor).Scan(&res.ImportedAt)
+	if err != nil {
+		return res, err
+	}
+	return res, tx.Commit()
+}
+
+// knownSymbols maps upper-case symbols to their stored spelling. An
+// instrument's exchange symbols, ISIN and aliases map to its stored symbol
+// too, so a file still using a former symbol updates the renamed stock. Rows
+// come lowest precedence first and later ones win, as in resolve_instrument.
+func knownSymbols(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT key, stock_symbol FROM (
+			SELECT k.key, sp.stock_symbol, k.rank, NULL::timestamptz AS since
+			FROM stock_prices sp
+			JOIN instruments i ON i.id = sp.instrument_id
+			CROSS JOIN LATERAL (VALUES (1, sp.stock_symbol), (2, i.nse_symbol), (3, i.bse_symbol), (4, i.isin)) k(rank, key)
+			WHERE k.key IS NOT NULL
+			UNION ALL
+			SELECT a.alias, sp.stock_symbol, 5, a.created_at
+			FROM instrument_aliases a
+			JOIN stock_prices sp ON sp.instrument_id = a.instrument_id
+		) m
+		ORDER BY rank DESC, since
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[string]string)
+	for rows.Next() {
+		var key, s string
+		if err := rows.Scan(&key, &s); err != nil {
+			return nil, err
+		}
+		known[strings.ToUpper(key)] = s
+	}
+	return known, rows.Err()
+}
+
+// selectRecords keeps the rows to import, one per symbol and date, spelled
+// as stored.
+func selectRecords(records []Record, known map[string]string, opts Options) []Record {
+	series := opts.Series
+	if len(series) == 0 {
+		series = DefaultSeries
+	}
+	allowed := make(map[string]bool, len(series))
+	for _, s := range series {
+		allowed[strings.ToUpper(strings.TrimSpace(s))] = true
+	}
+
+	seen := make(map[string]bool)
+	var out []Record
+	for _, r := range records {
+		if r.Exchange == "NSE" && !allowed[r.Series] {
+			continue
+		}
+		symbol, ok := known[r.Symbol]
+		if !ok && !opts.AllSymbols {
+			continue
+		}
+		if ok {
+			r.Symbol = symbol
+		}
+		key := r.Symbol + "|" + r.Date.Format("2006-01-02")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, r)
+	}
+	return out
+}
+
+type recordColumns struct {
+	symbols []string
+	dates   []string
+	open    []float64
+	high    []float64
+	low     []float64
+	close   []float64
+}
+
+func columnsOf(records []Record) []interface{} {
+	var rc recordColumns
+	for _, r := range records {
+		rc.symbols = append(rc.symbols, r.Symbol)
+		rc.dates = append(rc.dates, r.Date.Format("2006-01-02"))
+		rc.open = append(rc.open, r.Open)
+		rc.high = append(rc.high, r.High)
+		rc.low = append(rc.low, r.Low)
+		rc.close = append(rc.close, r.Close)
+	}
+	return []interface{}{
+		pq.Array(rc.symbols), pq.Array(rc.dates), pq.Array(rc.open),
+		pq.Array(rc.high), pq.Array(rc.low), pq.Array(rc.close),
+	}
+}
+
+const recordUnnest = `
+	unnest($1::text[], $2::date[], $3::numeric[], $4::numeric[], $5::numeric[], $6::numeric[])
+		AS v(symbol, date, open, high, low, close)`
+
+// recordRows is recordUnnest with the instrument of each symbol, registering
+// symbols not seen before.
+const recordRows = `(
+	SELECT v.*, register_instrument(v.symbol) AS instrument_id
+	FROM ` + recordUnnest + `
+) r`
+
+// writeHistory stores each close as the day's history price and the file's
+// OHLC as the day's bar. The exchange close replaces what the updater
+// recorded for that day, except a pinned override.
+func writeHistory(ctx context.Context, tx *sql.Tx, records []Record) error {
+	args := columnsOf(records)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_price_history
+			(instrument_id, stock_symbol, price, date, price_source, provider, provider_timestamp, fetch_attempts)
+		SELECT r.instrument_id, r.symbol, r.close, r.date, 'bhavcopy', NULL, NULL, 0
+		FROM `+recordRows+`
+		ON CONFLICT (instrument_id, date) DO UPDATE
+		SET price = EXCLUDED.price,
+			price_source = EXCLUDED.price_source,
+			provider = NULL,
+			provider_timestamp = NULL,
+			fetch_attempts = 0,
+			consensus = NULL,
+			divergent = FALSE
+		WHERE stock_price_history.price_source <> 'override'
+	`, args...); err != nil {
+		return err
+	}
+
+	// Imported bars have no ticks; a bar built from live ticks keeps its
+	// count but takes the exchange's prices.
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_price_daily_bars (instrument_id, stock_symbol, date, open, high, low, close, tick_count, updated_at)
+		SELECT r.instrument_id, r.symbol, r.date, r.open, r.high, r.low, r.close, 0, NOW()
+		FROM `+recordRows+`
+		ON CONFLICT (instrument_id, date) DO UPDATE
+		SET open = EXCLUDED.open,
+			high = EXCLUDED.high,
+			low = EXCLUDED.low,
+			close = EXCLUDED.close,
+			updated_at = NOW()
+	`, args...)
+	return err
+}
+
+// seedPrices sets the current price from the latest close per symbol. A
+// stored price is only replaced if it was last updated before that trading
+// day ended and no override pins it.
+func seedPrices(ctx context.Context, tx *sql.Tx, records []Record) (int, error) {
+	latest := make(map[string]Record)
+	for _, r := range records {
+		if cur, ok := latest[r.Symbol]; !ok || r.Date.After(cur.Date) {
+			latest[r.Symbol] = r
+		}
+	}
+	closes := make([]Record, 0, len(latest))
+	for _, r := range latest {
+		closes = append(closes, r)
+	}
+	args := columnsOf(closes)
+
+	updated, err := tx.ExecContext(ctx, `
+		UPDATE stock_prices sp
+		SET price = r.close, updated_at = r.date + INTERVAL '1 day', price_source = 'bhavcopy',
+			provider = NULL, provider_timestamp = NULL, fetch_attempts = 0, consensus = NULL, divergent = FALSE
+		FROM `+recordRows+`
+		WHERE sp.instrument_id = r.instrument_id
+		  AND sp.updated_at < r.date + INTERVAL '1 day'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM stock_price_overrides o
+		      WHERE o.instrument_id = sp.instrument_id
+		        AND o.released_at IS NULL AND o.expires_at > NOW()
+		  )
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_prices (instrument_id, stock_symbol, price, updated_at, price_source)
+		SELECT r.instrument_id, r.symbol, r.close, r.date + INTERVAL '1 day', 'bhavcopy'
+		FROM `+recordRows+`
+		WHERE NOT EXISTS (SELECT 1 FROM stock_prices sp WHERE sp.instrument_id = r.instrument_id)
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	u, _ := updated.RowsAffected()
+	i, _ := inserted.RowsAffected()
+	return int(u + i), nil
+}
@@ -0,0 +1,89 @@
+package bhavcopy
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSelectRecords(t *testing.T) {
+	d1, d2 := day(2024, 7, 8), day(2024, 7, 9)
+	nse := func(symbol, series string, date time.Time, close float64) Record {
+		return Record{Exchange: "NSE", Symbol: symbol, Series: series, Date: date, Close: close}
+	}
+	known := map[string]string{"TCS": "TCS", "INFY": "Infy", "OLDNAME": "NEWNAME"}
+
+	tests := []struct {
+		name    string
+		records []Record
+		opts    Options
+		want    []string
+	}{
+		{
+			name:    "duplicate symbol and date keeps the first row",
+			records: []Record{nse("TCS", "EQ", d1, 1), nse("TCS", "EQ", d1, 2)},
+			want:    []string{"TCS 2024-07-08 1"},
+		},
+		{
+			name:    "same symbol on different dates",
+			records: []Record{nse("TCS", "EQ", d1, 1), nse("TCS", "EQ", d2, 2)},
+			want:    []string{"TCS 2024-07-08 1", "TCS 2024-07-09 2"},
+		},
+		{
+			name:    "duplicates across series count once",
+			records: []Record{nse("TCS", "BE", d1, 1), nse("TCS", "EQ", d1, 2)},
+			want:    []string{"TCS 2024-07-08 1"},
+		},
+		{
+			name:    "former symbol collides with the current one",
+			records: []Record{nse("NEWNAME", "EQ", d1, 1), nse("OLDNAME", "EQ", d1, 2)},
+			opts:    Options{AllSymbols: true},
+			want:    []string{"NEWNAME 2024-07-08 1"},
+		},
+		{
+			name:    "symbols are spelled as stored",
+			records: []Record{nse("INFY", "EQ", d1, 1)},
+			want:    []string{"Infy 2024-07-08 1"},
+		},
+		{
+			name:    "unknown symbols are skipped",
+			records: []Record{nse("WIPRO", "EQ", d1, 1)},
+		},
+		{
+			name:    "unknown symbols with AllSymbols",
+			records: []Record{nse("WIPRO", "EQ", d1, 1)},
+			opts:    Options{AllSymbols: true},
+			want:    []string{"WIPRO 2024-07-08 1"},
+		},
+		{
+			name:    "nse series outside the default are skipped",
+			records: []Record{nse("TCS", "BL", d1, 1), nse("TCS", "EQ", d1, 2)},
+			want:    []string{"TCS 2024-07-08 2"},
+		},
+		{
+			name:    "configured series",
+			records: []Record{nse("TCS", "EQ", d1, 1), nse("TCS", "BL", d1, 2)},
+			opts:    Options{Series: []string{" bl "}},
+			want:    []string{"TCS 2024-07-08 2"},
+		},
+		{
+			name:    "bse groups are not filtered",
+			records: []Record{{Exchange: "BSE", Symbol: "TCS", Series: "A", Date: d1, Close: 1}},
+			want:    []string{"TCS 2024-07-08 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectRecords(tt.records, known, tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, r := range got {
+				if s := r.Symbol + " " + r.Date.Format("2006-01-02") + " " + strconv.FormatFloat(r.Close, 'f', -1, 64); s != tt.want[i] {
+					t.Errorf("record %d = %s, want %s", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package bhavcopy reads end-of-day exchange price files ("bhavcopies") and
+// loads them into the price history.
+package bhavcopy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported file layouts.
+const (
+	// FormatNSELegacy is the NSE cash market bhavcopy used until July 2024
+	// (cmDDMMMYYYYbhav.csv).
+	FormatNSELegacy = "nse_cm"
+	// FormatUDiFF is the common bhavcopy layout both exchanges publish since
+	// July 2024.
+	FormatUDiFF = "udiff"
+	// FormatBSELegacy is the BSE equity bhavcopy (EQDDMMYY.CSV). It has no
+	// date column, so the trade date comes from the file name.
+	FormatBSELegacy = "bse_eq"
+)
+
+// Record is one instrument's prices for one trading day.
+type Record struct {
+	Exchange string
+	Symbol   string
+	Series   string
+	ISIN     string
+	Date     time.Time
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+}
+
+// File is a parsed bhavcopy.
+type File struct {
+	Name    string
+	Format  string
+	Records []Record
+	// Rows counts the data rows read, including ones without a usable price.
+	Rows int
+}
+
+// TradeDate returns the latest date in the file.
+func (f File) TradeDate() time.Time {
+	var latest time.Time
+	for _, r := range f.Records {
+		if r.Date.After(latest) {
+			latest = r.Date
+		}
+	}
+	return latest
+}
+
+var errNoCSV = errors.New("zip archive contains no CSV file")
+
+// Parse reads a bhavcopy CSV, or a zip archive holding one as the exchanges
+// publish them. name is used to detect zips and, for the BSE legacy layout,
+// the trade date.
+func Parse(name string, data []byte) (File, error) {
+	if strings.EqualFold(filepath.Ext(name), ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return File{}, fmt.Errorf("reading zip: %w", err)
+		}
+		for _, zf := range zr.File {
+			if !strings.EqualFold(filepath.Ext(zf.Name), ".csv") {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return File{}, err
+			}
+			defer rc.Close()
+			inner, err := io.ReadAll(rc)
+			if err != nil {
+				return File{}, err
+			}
+			f, err := parseCSV(filepath.Base(zf.Name), inner)
+			f.Name = name
+			return f, err
+		}
+		return File{}, errNoCSV
+	}
+	return parseCSV(filepath.Base(name), data)
+}
+
+// columns maps upper-cased header names to their index.
+type columns map[string]int
+
+func (c columns) has(names ...string) bool {
+	for _, n := range names {
+		if _, ok := c[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c columns) get(rec []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+func parseCSV(name string, data []byte) (File, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return File{}, fmt.Errorf("reading header: %w", err)
+	}
+	cols := make(columns, len(header))
+	for i, h := range header {
+		cols[strings.ToUpper(strings.TrimSpace(h))] = i
+	}
+
+	f := File{Name: name}
+	var row func([]string) (Record, error)
+	switch {
+	case cols.has("TCKRSYMB", "TRADDT", "CLSPRIC"):
+		f.Format = FormatUDiFF
+		row = udiffRow(cols)
+	case cols.has("SYMBOL", "SERIES", "CLOSE", "TIMESTAMP"):
+		f.Format = FormatNSELegacy
+		row = nseLegacyRow(cols)
+	case cols.has("SC_CODE", "SC_NAME", "CLOSE"):
+		date, err := bseLegacyDate(name)
+		if err != nil {
+			return f, err
+		}
+		f.Format = FormatBSELegacy
+		row = bseLegacyRow(cols, date)
+	default:
+		return f, errors.New("unrecognised bhavcopy header")
+	}
+
+	line := 1
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return f, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		f.Rows++
+		r, err := row(rec)
+		if err != nil {
+			return f, fmt.Errorf("line %d: %w", line, err)
+		}
+		if r.Symbol == "" || r.Close <= 0 {
+			continue
+		}
+		f.Records = append(f.Records, r)
+	}
+	if len(f.Records) == 0 {
+		return f, errors.New("no priced rows found")
+	}
+	return f, nil
+}
+
+func parsePrice(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" || s == "-" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// ohlc reads the four prices. Missing open/high/low fall back to the close.
+func ohlc(cols columns, rec []string, open, high, low, close string) (o, h, l, c float64, err error) {
+	if c, err = parsePrice(cols.get(rec, close)); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("invalid close price: %w", err)
+	}
+	prices := []*float64{&o, &h, &l}
+	for i, name := range []string{open, high, low} {
+		v, err := parsePrice(cols.get(rec, name))
+		if err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("invalid %s: %w", strings.ToLower(name), err)
+		}
+		if v <= 0 {
+			v = c
+		}
+		*prices[i] = v
+	}
+	return o, h, l, c, nil
+}
+
+func udiffRow(cols columns) func([]string) (Record, error) {
+	return func(rec []string) (Record, error) {
+		r := Record{
+			Exchange: strings.ToUpper(cols.get(rec, "SRC")),
+			Symbol:   strings.ToUpper(cols.get(rec, "TCKRSYMB")),
+			Series:   strings.ToUpper(cols.get(rec, "SCTYSRS")),
+			ISIN:     strings.ToUpper(cols.get(rec, "ISIN")),
+		}
+		if tp := cols.get(rec, "FININSTRMTP"); tp != "" && !strings.EqualFold(tp, "STK") {
+			return Record{}, nil
+		}
+		date, err := time.Parse("2006-01-02", cols.get(rec, "TRADDT"))
+		if err != nil {
+			return r, fmt.Errorf("invalid TradDt %q", cols.get(rec, "TRADDT"))
+		}
+		r.Date = date
+		r.Open, r.High, r.Low, r.Close, err = ohlc(cols, rec, "OPNPRIC", "HGHPRIC", "LWPRIC", "CLSPRIC")
+		return r, err
+	}
+}
+
+func nseLegacyRow(cols columns) func([]string) (Record, error) {
+	return func(rec []string) (Record, error) {
+		r := Record{
+			Exchange: "NSE",
+			Symbol:   strings.ToUpper(cols.get(rec, "SYMBOL")),
+			Series:   strings.ToUpper(cols.get(rec, "SERIES")),
+			ISIN:     strings.ToUpper(cols.get(rec, "ISIN")),
+		}
+		date, err := time.Parse("02-Jan-2006", cols.get(rec, "TIMESTAMP"))
+		if err != nil {
+			return r, fmt.Errorf("invalid TIMESTAMP %q", cols.get(rec, "TIMESTAMP"))
+		}
+		r.Date = date
+		r.Open, r.High, r.Low, r.Close, err = ohlc(cols, rec, "OPEN", "HIGH", "LOW", "CLOSE")
+		return r, err
+	}
+}
+
+func bseLegacyRow(cols columns, date time.Time) func([]string) (Record, error) {
+	return func(rec []string) (Record, error) {
+		r := Record{
+			Exchange: "BSE",
+			Symbol:   strings.ToUpper(cols.get(rec, "SC_NAME")),
+			Series:   strings.ToUpper(cols.get(rec, "SC_GROUP")),
+			ISIN:     strings.ToUpper(cols.get(rec, "ISIN_CODE")),
+			Date:     date,
+		}
+		var err error
+		r.Open, r.High, r.Low, r.Close, err = ohlc(cols, rec, "OPEN", "HIGH", "LOW", "CLOSE")
+		return r, err
+	}
+}
+
+var bseLegacyName = regexp.MustCompile(`(?i)^EQ(\d{6})`)
+
+// bseLegacyDate reads the trade date from a name like EQ080724.CSV.
+func bseLegacyDate(name string) (time.Time, error) {
+	m := bseLegacyName.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return time.Time{}, fmt.Errorf("BSE bhavcopy name %q does not carry a date (expected EQDDMMYY.CSV)", name)
+	}
+	date, err := time.Parse("020106", m[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("BSE bhavcopy name %q: invalid date", name)
+	}
+	return date, nil
+}
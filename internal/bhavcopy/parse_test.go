@@ -0,0 +1,199 @@
+package bhavcopy
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		data       string
+		wantFormat string
+		wantRows   int
+		want       []Record
+		wantErr    string
+	}{
+		{
+			name: "udiff",
+			file: "BhavCopy_NSE_CM_0_0_0_20240708_F_0000.csv",
+			data: "TradDt,BizDt,Src,FinInstrmTp,ISIN,TckrSymb,SctySrs,OpnPric,HghPric,LwPric,ClsPric\n" +
+				"2024-07-08,2024-07-08,NSE,STK,INE467B01029,TCS,EQ,3950,3990.5,3940,3982.15\n" +
+				"2024-07-08,2024-07-08,NSE,IDX,,NIFTY,,24300,24350,24200,24320\n",
+			wantFormat: FormatUDiFF,
+			wantRows:   2,
+			want: []Record{{Exchange: "NSE", Symbol: "TCS", Series: "EQ", ISIN: "INE467B01029", Date: day(2024, 7, 8),
+				Open: 3950, High: 3990.5, Low: 3940, Close: 3982.15}},
+		},
+		{
+			name: "udiff with BOM, lower-case header and padded cells",
+			file: "udiff.csv",
+			data: "\xef\xbb\xbftraddt, src, tckrsymb, sctysrs, clspric\n" +
+				"2024-07-08, bse, infy , a, \"1,650.40\"\n",
+			wantFormat: FormatUDiFF,
+			wantRows:   1,
+			want: []Record{{Exchange: "BSE", Symbol: "INFY", Series: "A", Date: day(2024, 7, 8),
+				Open: 1650.4, High: 1650.4, Low: 1650.4, Close: 1650.4}},
+		},
+		{
+			name: "nse legacy with trailing comma",
+			file: "cm08JUL2024bhav.csv",
+			data: "SYMBOL,SERIES,OPEN,HIGH,LOW,CLOSE,LAST,PREVCLOSE,TOTTRDQTY,TOTTRDVAL,TIMESTAMP,TOTALTRADES,ISIN,\n" +
+				"RELIANCE,EQ,3180,3200,3170,3190.55,3191,3175,100,319000,08-JUL-2024,10,INE002A01018,\n" +
+				"RELIANCE,EQ,3180,3200,3170,3190.55,3191,3175,100,319000,08-JUL-2024,10,INE002A01018,\n",
+			wantFormat: FormatNSELegacy,
+			wantRows:   2,
+			want: []Record{
+				{Exchange: "NSE", Symbol: "RELIANCE", Series: "EQ", ISIN: "INE002A01018", Date: day(2024, 7, 8), Open: 3180, High: 3200, Low: 3170, Close: 3190.55},
+				{Exchange: "NSE", Symbol: "RELIANCE", Series: "EQ", ISIN: "INE002A01018", Date: day(2024, 7, 8), Open: 3180, High: 3200, Low: 3170, Close: 3190.55},
+			},
+		},
+		{
+			name: "bse legacy takes the date from the name",
+			file: "EQ080724.CSV",
+			data: "SC_CODE,SC_NAME,SC_GROUP,SC_TYPE,OPEN,HIGH,LOW,CLOSE\n" +
+				"500325,RELIANCE    ,A ,Q,3180,-,0,3189.9\n",
+			wantFormat: FormatBSELegacy,
+			wantRows:   1,
+			want: []Record{{Exchange: "BSE", Symbol: "RELIANCE", Series: "A", Date: day(2024, 7, 8),
+				Open: 3180, High: 3189.9, Low: 3189.9, Close: 3189.9}},
+		},
+		{
+			name: "blank lines are not rows, unpriced rows are counted but dropped",
+			file: "cm08JUL2024bhav.csv",
+			data: "SYMBOL,SERIES,OPEN,HIGH,LOW,CLOSE,TIMESTAMP\n" +
+				"\n" +
+				"TCS,EQ,1,2,1,3,08-JUL-2024\n" +
+				"NOCLOSE,EQ,1,2,1,,08-JUL-2024\n" +
+				",EQ,1,2,1,5,08-JUL-2024\n" +
+				"ZERO,EQ,1,2,1,0,08-JUL-2024\n",
+			wantFormat: FormatNSELegacy,
+			wantRows:   4,
+			want: []Record{{Exchange: "NSE", Symbol: "TCS", Series: "EQ", Date: day(2024, 7, 8),
+				Open: 1, High: 2, Low: 1, Close: 3}},
+		},
+		{
+			name:    "invalid close price",
+			file:    "cm08JUL2024bhav.csv",
+			data:    "SYMBOL,SERIES,CLOSE,TIMESTAMP\nTCS,EQ,3950,08-JUL-2024\nINFY,EQ,abc,08-JUL-2024\n",
+			wantErr: "line 3: invalid close price",
+		},
+		{
+			name:    "invalid open price",
+			file:    "udiff.csv",
+			data:    "TradDt,TckrSymb,OpnPric,ClsPric\n2024-07-08,TCS,x,3950\n",
+			wantErr: "line 2: invalid opnpric",
+		},
+		{
+			name:    "invalid udiff date",
+			file:    "udiff.csv",
+			data:    "TradDt,TckrSymb,ClsPric\n08/07/2024,TCS,3950\n",
+			wantErr: `invalid TradDt "08/07/2024"`,
+		},
+		{
+			name:    "invalid nse legacy date",
+			file:    "cm08JUL2024bhav.csv",
+			data:    "SYMBOL,SERIES,CLOSE,TIMESTAMP\nTCS,EQ,3950,2024-07-08\n",
+			wantErr: `invalid TIMESTAMP "2024-07-08"`,
+		},
+		{
+			name:    "malformed csv",
+			file:    "udiff.csv",
+			data:    "TradDt,TckrSymb,ClsPric\n2024-07-08,\"TCS,3950\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "bse legacy name without a date",
+			file:    "bhav.csv",
+			data:    "SC_CODE,SC_NAME,CLOSE\n500325,RELIANCE,3189.9\n",
+			wantErr: "does not carry a date",
+		},
+		{
+			name:    "unknown header",
+			file:    "prices.csv",
+			data:    "symbol,price\nTCS,3950\n",
+			wantErr: "unrecognised bhavcopy header",
+		},
+		{
+			name:    "header only",
+			file:    "udiff.csv",
+			data:    "TradDt,TckrSymb,ClsPric\n",
+			wantErr: "no priced rows found",
+		},
+		{
+			name:    "empty file",
+			file:    "udiff.csv",
+			wantErr: "reading header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.file, []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.Format != tt.wantFormat {
+				t.Errorf("format = %s, want %s", f.Format, tt.wantFormat)
+			}
+			if f.Rows != tt.wantRows {
+				t.Errorf("rows = %d, want %d", f.Rows, tt.wantRows)
+			}
+			if len(f.Records) != len(tt.want) {
+				t.Fatalf("got %d records, want %d: %+v", len(f.Records), len(tt.want), f.Records)
+			}
+			for i := range tt.want {
+				if f.Records[i] != tt.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, f.Records[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("README.txt"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := zw.Create("EQ080724.CSV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("SC_CODE,SC_NAME,CLOSE\n500325,RELIANCE,3189.9\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Parse("EQ080724_CSV.ZIP", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Name != "EQ080724_CSV.ZIP" || f.Format != FormatBSELegacy {
+		t.Errorf("got %s in %s, want bse_eq in the zip's name", f.Format, f.Name)
+	}
+	if !f.TradeDate().Equal(day(2024, 7, 8)) {
+		t.Errorf("trade date = %v, want 2024-07-08 from the inner file name", f.TradeDate())
+	}
+
+	var empty bytes.Buffer
+	zip.NewWriter(&empty).Close()
+	if _, err := Parse("empty.zip", empty.Bytes()); err != errNoCSV {
+		t.Errorf("err = %v, want errNoCSV", err)
+	}
+}
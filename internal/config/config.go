@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type HTTPServer struct {
@@ -18,10 +21,117 @@ type Database struct {
 	SSLMode  string
 }
 
+// Adjustments controls the maker-checker workflow for reward adjustments.
+type Adjustments struct {
+	// DualApprovalThresholdINR is the INR value above which an adjustment
+	// needs two distinct approvers instead of one.
+	DualApprovalThresholdINR float64
+}
+
+// PriceProvider selects where the price updater gets quotes from.
+type PriceProvider struct {
+	// Kind is one of "simulated", "marketsim", "file" or "http", or a
+	// comma-separated list of them to query several sources per symbol. The
+	// first listed is the primary source.
+	Kind    string
+	Timeout time.Duration
+
+	// With several sources, the primary's price is used while it is within
+	// ConsensusTolerancePct of the others' median; otherwise the median of
+	// all sources wins. A spread between sources above DivergencePct flags
+	// the price as divergent. Both are in percent.
+	ConsensusTolerancePct float64
+	DivergencePct         float64
+
+	// Simulated provider: probability of a failed fetch and the maximum
+	// relative move per fetch.
+	SimFailureRate float64
+	SimMaxMove     float64
+
+	// Market simulator: seed, annualised volatility and drift of the price
+	// paths, and an optional JSON file with per-symbol parameters and
+	// outage windows.
+	SimSeed       int64
+	SimVolatility float64
+	SimDrift      float64
+	SimConfigFile string
+
+	// File provider: a CSV (symbol,price[,timestamp]) or JSON file.
+	FilePath string
+
+	// HTTP provider: URL with a {symbol} placeholder, and dot-separated paths
+	// of the price and optional timestamp in the JSON response.
+	HTTPURL            string
+	HTTPPriceField     string
+	HTTPTimestampField string
+	HTTPAuthHeader     string
+	HTTPAuthToken      string
+}
+
+// Market describes the exchange's trading session.
+type Market struct {
+	Timezone string
+	// SessionOpen and SessionClose are HH:MM in Timezone.
+	SessionOpen  string
+	SessionClose string
+	// HolidaysFile lists exchange holidays as "YYYY-MM-DD,description" lines.
+	HolidaysFile string
+}
+
+// PriceUpdater controls the scheduled price refresh.
+type PriceUpdater struct {
+	// Schedule is the cron spec of the refresh, in the market time zone.
+	// Scheduled refreshes outside the trading session are skipped.
+	Schedule string
+	// SnapshotSchedule is the cron spec of the closing-price snapshot.
+	SnapshotSchedule string
+	// Workers is the number of symbols fetched concurrently.
+	Workers int
+	// SymbolTimeout bounds all fetch attempts for one symbol.
+	SymbolTimeout time.Duration
+	// RunTimeout bounds the fetch phase of a whole run; symbols not reached
+	// by then are skipped until the next run.
+	RunTimeout time.Duration
+	// BandPct is the default circuit band around the previous close and
+	// MaxMovePct the default largest move between two refreshes, both in
+	// percent. Provider prices outside them are quarantined; 0 disables.
+	BandPct    float64
+	MaxMovePct float64
+}
+
+// CorporateActions controls when stock events are posted to the ledger.
+type CorporateActions struct {
+	// Schedule is the cron spec of the run, in the market time zone. Events
+	// take effect at the start of their date, so shortly after midnight.
+	Schedule string
+	// DividendTDSPct is withheld from a dividend once the holder's dividends
+	// in the financial year exceed DividendTDSThresholdINR.
+	DividendTDSPct          float64
+	DividendTDSThresholdINR float64
+}
+
+// Leader controls the election of the instance that runs scheduled jobs.
+type Leader struct {
+	// InstanceID names this instance in the leaders table and on /health.
+	InstanceID string
+	// LockKey is the Postgres advisory lock held by the leader. Instances
+	// sharing a database must use the same key.
+	LockKey int64
+	// RenewInterval is how often the leader confirms its lock and a
+	// follower tries to take it over.
+	RenewInterval time.Duration
+}
+
 type Config struct {
-	Env        string
-	Database   Database
-	HTTPServer HTTPServer
+	Env              string
+	Database         Database
+	HTTPServer       HTTPServer
+	Adjustments      Adjustments
+	PriceProvider    PriceProvider
+	PriceUpdater     PriceUpdater
+	Market           Market
+	Leader           Leader
+	CorporateActions CorporateActions
 }
 
 func LoadFromEnv() *Config {
@@ -31,6 +141,54 @@ func LoadFromEnv() *Config {
 		}
 		return defaultVal
 	}
+	getEnvFloat := func(key string, defaultVal float64) float64 {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			return defaultVal
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Printf("invalid value %q for %s, using default %v", v, key, defaultVal)
+			return defaultVal
+		}
+		return f
+	}
+	getEnvInt := func(key string, defaultVal int) int {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			return defaultVal
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("invalid value %q for %s, using default %v", v, key, defaultVal)
+			return defaultVal
+		}
+		return n
+	}
+	getEnvInt64 := func(key string, defaultVal int64) int64 {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			return defaultVal
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("invalid value %q for %s, using default %v", v, key, defaultVal)
+			return defaultVal
+		}
+		return n
+	}
+	getEnvDuration := func(key string, defaultVal time.Duration) time.Duration {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			return defaultVal
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("invalid value %q for %s, using default %v", v, key, defaultVal)
+			return defaultVal
+		}
+		return d
+	}
 
 	cfg := &Config{
 		Env: getEnv("ENV", "dev"),
@@ -45,11 +203,67 @@ func LoadFromEnv() *Config {
 		HTTPServer: HTTPServer{
 			Port: ":" + getEnv("HTTP_PORT", "8080"),
 		},
+		Adjustments: Adjustments{
+			DualApprovalThresholdINR: getEnvFloat("ADJUSTMENT_DUAL_APPROVAL_THRESHOLD_INR", 100000),
+		},
+		PriceProvider: PriceProvider{
+			Kind:                  getEnv("PRICE_PROVIDER", "simulated"),
+			Timeout:               getEnvDuration("PRICE_PROVIDER_TIMEOUT", 5*time.Second),
+			ConsensusTolerancePct: getEnvFloat("PRICE_CONSENSUS_TOLERANCE_PCT", 0.5),
+			DivergencePct:         getEnvFloat("PRICE_DIVERGENCE_PCT", 2),
+			SimFailureRate:        getEnvFloat("PRICE_SIM_FAILURE_RATE", 0.1),
+			SimMaxMove:            getEnvFloat("PRICE_SIM_MAX_MOVE", 0.05),
+			SimSeed:               getEnvInt64("PRICE_SIM_SEED", 1),
+			SimVolatility:         getEnvFloat("PRICE_SIM_VOLATILITY", 0.25),
+			SimDrift:              getEnvFloat("PRICE_SIM_DRIFT", 0),
+			SimConfigFile:         getEnv("PRICE_SIM_CONFIG", ""),
+			FilePath:              getEnv("PRICE_FILE_PATH", ""),
+			HTTPURL:               getEnv("PRICE_HTTP_URL", ""),
+			HTTPPriceField:        getEnv("PRICE_HTTP_PRICE_FIELD", "price"),
+			HTTPTimestampField:    getEnv("PRICE_HTTP_TIMESTAMP_FIELD", ""),
+			HTTPAuthHeader:        getEnv("PRICE_HTTP_AUTH_HEADER", "Authorization"),
+			HTTPAuthToken:         getEnv("PRICE_HTTP_AUTH_TOKEN", ""),
+		},
+		PriceUpdater: PriceUpdater{
+			Schedule:         getEnv("PRICE_UPDATE_CRON", "0 * * * *"),
+			SnapshotSchedule: getEnv("PRICE_CLOSING_SNAPSHOT_CRON", "45 15 * * 1-5"),
+			Workers:          getEnvInt("PRICE_UPDATER_WORKERS", 8),
+			SymbolTimeout:    getEnvDuration("PRICE_SYMBOL_TIMEOUT", 15*time.Second),
+			RunTimeout:       getEnvDuration("PRICE_RUN_TIMEOUT", 10*time.Minute),
+			BandPct:          getEnvFloat("PRICE_BAND_PCT", 20),
+			MaxMovePct:       getEnvFloat("PRICE_MAX_MOVE_PCT", 10),
+		},
+		Market: Market{
+			Timezone:     getEnv("MARKET_TIMEZONE", "Asia/Kolkata"),
+			SessionOpen:  getEnv("MARKET_SESSION_OPEN", "09:15"),
+			SessionClose: getEnv("MARKET_SESSION_CLOSE", "15:30"),
+			HolidaysFile: getEnv("MARKET_HOLIDAYS_FILE", "internal/market/nse_holidays.csv"),
+		},
+		CorporateActions: CorporateActions{
+			Schedule:                getEnv("CORPORATE_ACTIONS_CRON", "5 0 * * *"),
+			DividendTDSPct:          getEnvFloat("DIVIDEND_TDS_PCT", 10),
+			DividendTDSThresholdINR: getEnvFloat("DIVIDEND_TDS_THRESHOLD_INR", 5000),
+		},
+		Leader: Leader{
+			InstanceID:    getEnv("INSTANCE_ID", defaultInstanceID()),
+			LockKey:       getEnvInt64("LEADER_LOCK_KEY", 7_301_042),
+			RenewInterval: getEnvDuration("LEADER_RENEW_INTERVAL", 5*time.Second),
+		},
 	}
 
 	return cfg
 }
 
+// defaultInstanceID is the host name (the container ID under Docker) and
+// process ID.
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "stocky"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 func MustLoad() *Config {
 	cfg := LoadFromEnv()
 	if cfg == nil {
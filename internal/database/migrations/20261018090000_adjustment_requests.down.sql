@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS adjustment_approvals;
+DROP TABLE IF EXISTS adjustment_requests;
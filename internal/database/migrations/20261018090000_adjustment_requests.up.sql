@@ -0,0 +1,30 @@
+-- Maker-checker workflow: adjustments are first recorded as requests and only
+-- written to the adjustments table and ledger once approved.
+CREATE TABLE IF NOT EXISTS adjustment_requests (
+    id                 SERIAL PRIMARY KEY,
+    reward_id          INT NOT NULL REFERENCES rewards(id),
+    adjustment_type    TEXT NOT NULL,
+    delta_quantity     NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    delta_amount       NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    reason             TEXT NOT NULL DEFAULT '',
+    inr_value          NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    required_approvals INT NOT NULL DEFAULT 1 CHECK (required_approvals BETWEEN 1 AND 2),
+    status             TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    initiated_by       TEXT NOT NULL,
+    adjustment_id      INT REFERENCES adjustments(id),
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_adjustment_requests_status ON adjustment_requests(status);
+CREATE INDEX IF NOT EXISTS idx_adjustment_requests_reward_id ON adjustment_requests(reward_id);
+
+CREATE TABLE IF NOT EXISTS adjustment_approvals (
+    id         SERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES adjustment_requests(id) ON DELETE CASCADE,
+    approver   TEXT NOT NULL,
+    decision   TEXT NOT NULL CHECK (decision IN ('approve', 'reject')),
+    comment    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (request_id, approver)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_adjustments_created_at;
+DROP INDEX IF EXISTS idx_adjustments_reward_id;
+DROP INDEX IF EXISTS idx_ledger_adjustment_id;
+ALTER TABLE ledger DROP COLUMN IF EXISTS adjustment_id;
@@ -0,0 +1,8 @@
+-- Link ledger rows to the adjustment that produced them so adjustment
+-- history can show its ledger effect. Rows written before this migration
+-- stay unlinked.
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS adjustment_id INT REFERENCES adjustments(id);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_adjustment_id ON ledger(adjustment_id);
+CREATE INDEX IF NOT EXISTS idx_adjustments_reward_id ON adjustments(reward_id);
+CREATE INDEX IF NOT EXISTS idx_adjustments_created_at ON adjustments(created_at);
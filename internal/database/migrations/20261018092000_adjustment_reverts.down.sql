@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uq_adjustment_requests_pending_revert;
+ALTER TABLE adjustment_requests DROP COLUMN IF EXISTS reverts_adjustment_id;
+DROP INDEX IF EXISTS uq_adjustments_reverts_adjustment_id;
+ALTER TABLE adjustments DROP COLUMN IF EXISTS reverts_adjustment_id;
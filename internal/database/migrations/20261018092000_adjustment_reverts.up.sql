@@ -0,0 +1,12 @@
+-- A revert is a compensating adjustment linked to the one it undoes. Each
+-- adjustment can be reverted at most once, and only one revert request may be
+-- pending for it at a time.
+ALTER TABLE adjustments ADD COLUMN IF NOT EXISTS reverts_adjustment_id INT REFERENCES adjustments(id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_adjustments_reverts_adjustment_id
+    ON adjustments(reverts_adjustment_id)
+    WHERE reverts_adjustment_id IS NOT NULL;
+
+ALTER TABLE adjustment_requests ADD COLUMN IF NOT EXISTS reverts_adjustment_id INT REFERENCES adjustments(id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_adjustment_requests_pending_revert
+    ON adjustment_requests(reverts_adjustment_id)
+    WHERE reverts_adjustment_id IS NOT NULL AND status = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_adjustment_requests_batch_id;
+ALTER TABLE adjustment_requests DROP COLUMN IF EXISTS batch_id;
+DROP TABLE IF EXISTS adjustment_batches;
@@ -0,0 +1,18 @@
+-- Bulk adjustments uploaded from CSV. All rows of a batch are approved or
+-- rejected together and applied in a single transaction.
+CREATE TABLE IF NOT EXISTS adjustment_batches (
+    id                 SERIAL PRIMARY KEY,
+    file_name          TEXT NOT NULL DEFAULT '',
+    row_count          INT NOT NULL,
+    total_inr_value    NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    required_approvals INT NOT NULL DEFAULT 1 CHECK (required_approvals BETWEEN 1 AND 2),
+    status             TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    initiated_by       TEXT NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_adjustment_batches_status ON adjustment_batches(status);
+
+ALTER TABLE adjustment_requests ADD COLUMN IF NOT EXISTS batch_id INT REFERENCES adjustment_batches(id);
+CREATE INDEX IF NOT EXISTS idx_adjustment_requests_batch_id ON adjustment_requests(batch_id);
@@ -0,0 +1,19 @@
+-- The view definitions replaced by the up migration come from the initial
+-- schema migrations; re-apply those to restore them after rolling back.
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+DROP VIEW IF EXISTS historical_rewards;
+DROP VIEW IF EXISTS reward_lots;
+
+ALTER TABLE adjustment_requests
+    DROP COLUMN IF EXISTS unit_multiplier,
+    DROP COLUMN IF EXISTS input_delta_quantity,
+    DROP COLUMN IF EXISTS unit_basis;
+ALTER TABLE adjustments
+    DROP COLUMN IF EXISTS unit_multiplier,
+    DROP COLUMN IF EXISTS input_delta_quantity,
+    DROP COLUMN IF EXISTS unit_basis;
+
+DROP FUNCTION IF EXISTS reward_current_symbol(TEXT, DATE, DATE);
+DROP FUNCTION IF EXISTS reward_unit_multiplier(TEXT, DATE, DATE);
+DROP AGGREGATE IF EXISTS numeric_product(NUMERIC);
@@ -0,0 +1,127 @@
+-- Corporate-action unit conversion shared by the reward views and adjustment
+-- validation. A reward lot's units are multiplied by the ratio of every split,
+-- bonus and merger on its symbol that became effective after the reward date.
+-- A merger also moves the lot to the event's target symbol.
+
+ALTER TABLE stock_events ADD COLUMN IF NOT EXISTS target_symbol TEXT;
+
+CREATE OR REPLACE AGGREGATE numeric_product(NUMERIC) (
+    SFUNC = numeric_mul,
+    STYPE = NUMERIC,
+    INITCOND = '1'
+);
+
+CREATE OR REPLACE FUNCTION reward_unit_multiplier(p_symbol TEXT, p_reward_date DATE, p_as_of DATE DEFAULT CURRENT_DATE)
+RETURNS NUMERIC AS $$
+    SELECT COALESCE(numeric_product(ratio_num::NUMERIC / ratio_den), 1)
+    FROM stock_events
+    WHERE UPPER(stock_symbol) = UPPER(p_symbol)
+      AND event_type IN ('split', 'bonus', 'merger')
+      AND effective_date > p_reward_date
+      AND effective_date <= p_as_of
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION reward_current_symbol(p_symbol TEXT, p_reward_date DATE, p_as_of DATE DEFAULT CURRENT_DATE)
+RETURNS TEXT AS $$
+    SELECT COALESCE((
+        SELECT target_symbol
+        FROM stock_events
+        WHERE UPPER(stock_symbol) = UPPER(p_symbol)
+          AND event_type = 'merger'
+          AND target_symbol IS NOT NULL
+          AND effective_date > p_reward_date
+          AND effective_date <= p_as_of
+        ORDER BY effective_date DESC
+        LIMIT 1
+    ), p_symbol)
+$$ LANGUAGE SQL STABLE;
+
+-- Adjustments state the unit basis of their delta. delta_quantity is always
+-- stored in pre-event units (the reward's own units) so that
+-- (quantity + SUM(delta_quantity)) * multiplier stays the holding in current
+-- units; input_delta_quantity keeps what the operator entered.
+ALTER TABLE adjustments
+    ADD COLUMN IF NOT EXISTS unit_basis TEXT NOT NULL DEFAULT 'pre_event' CHECK (unit_basis IN ('pre_event', 'current')),
+    ADD COLUMN IF NOT EXISTS input_delta_quantity NUMERIC(18, 6),
+    ADD COLUMN IF NOT EXISTS unit_multiplier NUMERIC(24, 12) NOT NULL DEFAULT 1;
+UPDATE adjustments SET input_delta_quantity = delta_quantity WHERE input_delta_quantity IS NULL;
+
+ALTER TABLE adjustment_requests
+    ADD COLUMN IF NOT EXISTS unit_basis TEXT NOT NULL DEFAULT 'pre_event' CHECK (unit_basis IN ('pre_event', 'current')),
+    ADD COLUMN IF NOT EXISTS input_delta_quantity NUMERIC(18, 6),
+    ADD COLUMN IF NOT EXISTS unit_multiplier NUMERIC(24, 12) NOT NULL DEFAULT 1;
+UPDATE adjustment_requests SET input_delta_quantity = delta_quantity WHERE input_delta_quantity IS NULL;
+
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+DROP VIEW IF EXISTS historical_rewards;
+DROP VIEW IF EXISTS reward_lots;
+
+CREATE VIEW reward_lots AS
+SELECT
+    r.id AS reward_id,
+    r.user_id,
+    r.created_at::date AS reward_date,
+    r.stock_symbol AS original_symbol,
+    reward_current_symbol(r.stock_symbol, r.created_at::date) AS stock_symbol,
+    r.quantity + COALESCE(adj.delta_quantity, 0) AS base_quantity,
+    reward_unit_multiplier(r.stock_symbol, r.created_at::date) AS unit_multiplier,
+    COALESCE(adj.delta_amount, 0) AS total_adjustment_amount
+FROM rewards r
+LEFT JOIN (
+    SELECT reward_id, SUM(delta_quantity) AS delta_quantity, SUM(delta_amount) AS delta_amount
+    FROM adjustments
+    GROUP BY reward_id
+) adj ON adj.reward_id = r.id;
+
+CREATE VIEW user_portfolio AS
+SELECT
+    l.user_id,
+    l.stock_symbol,
+    SUM(l.base_quantity * l.unit_multiplier) AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    SUM(l.base_quantity * l.unit_multiplier) * COALESCE(sp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+GROUP BY l.user_id, l.stock_symbol, sp.price;
+
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.base_quantity * l.unit_multiplier * COALESCE(sp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE UPPER(e.stock_symbol) = UPPER(l.stock_symbol)
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+-- Historical value uses the price on the reward date, restated per current
+-- unit so that adjusted_quantity * price = inr_value.
+CREATE VIEW historical_rewards AS
+SELECT
+    l.user_id,
+    l.reward_date,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(hp.price, 0) / l.unit_multiplier AS price,
+    l.total_adjustment_amount,
+    l.base_quantity * COALESCE(hp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN LATERAL (
+    SELECT h.price
+    FROM stock_price_history h
+    WHERE UPPER(h.stock_symbol) = UPPER(l.original_symbol)
+      AND h.date <= l.reward_date
+    ORDER BY h.date DESC
+    LIMIT 1
+) hp ON TRUE;
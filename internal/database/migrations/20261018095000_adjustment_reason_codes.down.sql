@@ -0,0 +1,11 @@
+ALTER TABLE adjustment_requests
+    DROP COLUMN IF EXISTS ticket_ref,
+    DROP COLUMN IF EXISTS reason_code;
+
+DROP INDEX IF EXISTS idx_adjustments_reason_code;
+ALTER TABLE adjustments
+    DROP COLUMN IF EXISTS ticket_ref,
+    DROP COLUMN IF EXISTS initiated_by,
+    DROP COLUMN IF EXISTS reason_code;
+
+DROP TABLE IF EXISTS adjustment_reason_codes;
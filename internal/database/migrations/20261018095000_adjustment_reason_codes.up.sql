@@ -0,0 +1,56 @@
+-- Managed taxonomy of adjustment reason codes. Each code limits which
+-- adjustment types may use it and the sign of the quantity and INR deltas.
+CREATE TABLE IF NOT EXISTS adjustment_reason_codes (
+    code          TEXT PRIMARY KEY CHECK (code ~ '^[A-Z][A-Z0-9_]{1,39}$'),
+    description   TEXT NOT NULL,
+    allowed_types TEXT[] NOT NULL CHECK (cardinality(allowed_types) > 0),
+    quantity_sign TEXT NOT NULL DEFAULT 'any' CHECK (quantity_sign IN ('any', 'positive', 'negative', 'zero')),
+    amount_sign   TEXT NOT NULL DEFAULT 'any' CHECK (amount_sign IN ('any', 'positive', 'negative', 'zero')),
+    active        BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by    TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by    TEXT,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO adjustment_reason_codes (code, description, allowed_types, quantity_sign, amount_sign, active, created_by) VALUES
+    ('PRICING_ERROR', 'Reward granted with a wrong quantity or price', ARRAY['manual_correction'], 'any', 'any', TRUE, 'system'),
+    ('DUPLICATE_REWARD', 'Reward granted more than once', ARRAY['reward_reversal'], 'negative', 'any', TRUE, 'system'),
+    ('FRAUD_REVERSAL', 'Reward clawed back after a fraud review', ARRAY['reward_reversal'], 'negative', 'any', TRUE, 'system'),
+    ('FEE_OVERCHARGE', 'Brokerage, STT or GST charged in excess', ARRAY['fee_refund'], 'zero', 'positive', TRUE, 'system'),
+    ('CORPORATE_ACTION', 'Holding corrected after a split, bonus or merger', ARRAY['manual_correction'], 'any', 'zero', TRUE, 'system'),
+    ('REVERT', 'Compensating entry for a mistaken adjustment', ARRAY['reward_reversal', 'fee_refund', 'manual_correction'], 'any', 'any', TRUE, 'system'),
+    ('LEGACY', 'Recorded before reason codes were introduced', ARRAY['reward_reversal', 'fee_refund', 'manual_correction'], 'any', 'any', FALSE, 'system')
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE adjustments
+    ADD COLUMN IF NOT EXISTS reason_code TEXT REFERENCES adjustment_reason_codes(code),
+    ADD COLUMN IF NOT EXISTS initiated_by TEXT,
+    ADD COLUMN IF NOT EXISTS ticket_ref TEXT;
+
+UPDATE adjustments a
+SET reason_code  = COALESCE(a.reason_code, 'LEGACY'),
+    initiated_by = COALESCE(
+        a.initiated_by,
+        (SELECT ar.initiated_by FROM adjustment_requests ar WHERE ar.adjustment_id = a.id),
+        'unknown'),
+    ticket_ref   = COALESCE(a.ticket_ref, '');
+
+ALTER TABLE adjustments
+    ALTER COLUMN reason_code SET NOT NULL,
+    ALTER COLUMN initiated_by SET NOT NULL,
+    ALTER COLUMN ticket_ref SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_adjustments_reason_code ON adjustments(reason_code);
+
+ALTER TABLE adjustment_requests
+    ADD COLUMN IF NOT EXISTS reason_code TEXT REFERENCES adjustment_reason_codes(code),
+    ADD COLUMN IF NOT EXISTS ticket_ref TEXT;
+
+UPDATE adjustment_requests
+SET reason_code = COALESCE(reason_code, 'LEGACY'),
+    ticket_ref  = COALESCE(ticket_ref, '');
+
+ALTER TABLE adjustment_requests
+    ALTER COLUMN reason_code SET NOT NULL,
+    ALTER COLUMN ticket_ref SET NOT NULL;
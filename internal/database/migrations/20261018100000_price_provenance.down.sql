@@ -0,0 +1,46 @@
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+
+CREATE VIEW user_portfolio AS
+SELECT
+    l.user_id,
+    l.stock_symbol,
+    SUM(l.base_quantity * l.unit_multiplier) AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    SUM(l.base_quantity * l.unit_multiplier) * COALESCE(sp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+GROUP BY l.user_id, l.stock_symbol, sp.price;
+
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.base_quantity * l.unit_multiplier * COALESCE(sp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE UPPER(e.stock_symbol) = UPPER(l.stock_symbol)
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+ALTER TABLE stock_price_history
+    DROP CONSTRAINT IF EXISTS stock_price_history_price_source_check,
+    DROP COLUMN IF EXISTS fetch_attempts,
+    DROP COLUMN IF EXISTS provider_timestamp,
+    DROP COLUMN IF EXISTS provider,
+    DROP COLUMN IF EXISTS price_source;
+
+ALTER TABLE stock_prices
+    DROP CONSTRAINT IF EXISTS stock_prices_price_source_check,
+    DROP COLUMN IF EXISTS fetch_attempts,
+    DROP COLUMN IF EXISTS provider_timestamp,
+    DROP COLUMN IF EXISTS provider,
+    DROP COLUMN IF EXISTS price_source;
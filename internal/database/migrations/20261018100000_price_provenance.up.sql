@@ -0,0 +1,49 @@
+-- Where each stored price came from. Rows written before provenance was
+-- tracked are marked 'unknown'.
+ALTER TABLE stock_prices
+    ADD COLUMN price_source       TEXT NOT NULL DEFAULT 'unknown',
+    ADD COLUMN provider           TEXT,
+    ADD COLUMN provider_timestamp TIMESTAMPTZ,
+    ADD COLUMN fetch_attempts     INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT stock_prices_price_source_check
+        CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'unknown'));
+
+ALTER TABLE stock_price_history
+    ADD COLUMN price_source       TEXT NOT NULL DEFAULT 'unknown',
+    ADD COLUMN provider           TEXT,
+    ADD COLUMN provider_timestamp TIMESTAMPTZ,
+    ADD COLUMN fetch_attempts     INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT stock_price_history_price_source_check
+        CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'unknown'));
+
+CREATE OR REPLACE VIEW user_portfolio AS
+SELECT
+    l.user_id,
+    l.stock_symbol,
+    SUM(l.base_quantity * l.unit_multiplier) AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    SUM(l.base_quantity * l.unit_multiplier) * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+GROUP BY l.user_id, l.stock_symbol, sp.price, sp.price_source;
+
+CREATE OR REPLACE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.base_quantity * l.unit_multiplier * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE UPPER(e.stock_symbol) = UPPER(l.stock_symbol)
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
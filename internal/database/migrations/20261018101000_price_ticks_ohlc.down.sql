@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_price_daily_bars;
+DROP TABLE IF EXISTS stock_price_ticks;
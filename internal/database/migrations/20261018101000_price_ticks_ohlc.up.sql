@@ -0,0 +1,32 @@
+-- Every price the updater stores, with its provenance.
+CREATE TABLE IF NOT EXISTS stock_price_ticks (
+    id                 BIGSERIAL PRIMARY KEY,
+    stock_symbol       TEXT NOT NULL,
+    price              NUMERIC(18, 4) NOT NULL,
+    price_source       TEXT NOT NULL,
+    provider           TEXT,
+    provider_timestamp TIMESTAMPTZ,
+    fetched_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_price_ticks_symbol_time ON stock_price_ticks (UPPER(stock_symbol), fetched_at);
+
+-- Daily open/high/low/close built from the ticks. Carried-forward ticks are
+-- not new observations and are left out.
+CREATE TABLE IF NOT EXISTS stock_price_daily_bars (
+    stock_symbol TEXT NOT NULL,
+    date         DATE NOT NULL,
+    open         NUMERIC(18, 4) NOT NULL,
+    high         NUMERIC(18, 4) NOT NULL,
+    low          NUMERIC(18, 4) NOT NULL,
+    close        NUMERIC(18, 4) NOT NULL,
+    tick_count   INT NOT NULL DEFAULT 1,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (stock_symbol, date)
+);
+
+-- Days before ticks were kept only have their last price.
+INSERT INTO stock_price_daily_bars (stock_symbol, date, open, high, low, close, tick_count)
+SELECT stock_symbol, date, price, price, price, price, 1
+FROM stock_price_history
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,13 @@
+UPDATE stock_prices SET price_source = 'unknown' WHERE price_source = 'override';
+UPDATE stock_price_history SET price_source = 'unknown' WHERE price_source = 'override';
+
+ALTER TABLE stock_price_history DROP CONSTRAINT stock_price_history_price_source_check;
+ALTER TABLE stock_price_history ADD CONSTRAINT stock_price_history_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'unknown'));
+
+ALTER TABLE stock_prices DROP CONSTRAINT stock_prices_price_source_check;
+ALTER TABLE stock_prices ADD CONSTRAINT stock_prices_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'unknown'));
+
+DROP TABLE IF EXISTS stock_price_override_audit;
+DROP TABLE IF EXISTS stock_price_overrides;
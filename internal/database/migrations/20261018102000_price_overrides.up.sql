@@ -0,0 +1,40 @@
+-- Manually pinned prices. An override is active until it expires or is
+-- released; at most one unreleased override exists per symbol.
+CREATE TABLE IF NOT EXISTS stock_price_overrides (
+    id             SERIAL PRIMARY KEY,
+    stock_symbol   TEXT NOT NULL,
+    price          NUMERIC(18, 4) NOT NULL CHECK (price > 0),
+    reason         TEXT NOT NULL,
+    set_by         TEXT NOT NULL,
+    set_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    released_by    TEXT,
+    released_at    TIMESTAMPTZ,
+    release_reason TEXT,
+    CHECK (expires_at > set_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_price_overrides_open
+    ON stock_price_overrides (UPPER(stock_symbol))
+    WHERE released_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS stock_price_override_audit (
+    id           SERIAL PRIMARY KEY,
+    override_id  INT NOT NULL REFERENCES stock_price_overrides(id),
+    stock_symbol TEXT NOT NULL,
+    action       TEXT NOT NULL CHECK (action IN ('set', 'release', 'expire', 'supersede')),
+    price        NUMERIC(18, 4) NOT NULL,
+    actor        TEXT NOT NULL,
+    reason       TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_price_override_audit_symbol ON stock_price_override_audit (UPPER(stock_symbol), created_at);
+
+ALTER TABLE stock_prices DROP CONSTRAINT stock_prices_price_source_check;
+ALTER TABLE stock_prices ADD CONSTRAINT stock_prices_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'unknown'));
+
+ALTER TABLE stock_price_history DROP CONSTRAINT stock_price_history_price_source_check;
+ALTER TABLE stock_price_history ADD CONSTRAINT stock_price_history_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'unknown'));
@@ -0,0 +1,9 @@
+-- Closing price of each symbol per trading day, captured after the session.
+CREATE TABLE IF NOT EXISTS stock_closing_prices (
+    stock_symbol TEXT NOT NULL,
+    date         DATE NOT NULL,
+    price        NUMERIC(18, 4) NOT NULL,
+    price_source TEXT NOT NULL,
+    captured_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (stock_symbol, date)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_price_quarantine;
+DROP TABLE IF EXISTS stock_price_bands;
@@ -0,0 +1,34 @@
+-- Per-symbol sanity limits. A band_pct is the circuit limit around the
+-- previous close; max_move_pct caps the move between two refreshes. Symbols
+-- without a row use the configured defaults; 0 disables a check.
+CREATE TABLE IF NOT EXISTS stock_price_bands (
+    stock_symbol TEXT PRIMARY KEY,
+    band_pct     NUMERIC(6, 2) NOT NULL CHECK (band_pct >= 0),
+    max_move_pct NUMERIC(6, 2) NOT NULL CHECK (max_move_pct >= 0),
+    updated_by   TEXT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_price_bands_symbol ON stock_price_bands (UPPER(stock_symbol));
+
+-- Provider prices that failed a sanity check. They are held here for review
+-- instead of being applied.
+CREATE TABLE IF NOT EXISTS stock_price_quarantine (
+    id                 SERIAL PRIMARY KEY,
+    stock_symbol       TEXT NOT NULL,
+    price              NUMERIC(18, 4) NOT NULL,
+    previous_price     NUMERIC(18, 4) NOT NULL,
+    reference_price    NUMERIC(18, 4),
+    reason             TEXT NOT NULL CHECK (reason IN ('non_positive', 'circuit_band', 'max_move')),
+    detail             TEXT NOT NULL,
+    provider           TEXT,
+    provider_timestamp TIMESTAMPTZ,
+    status             TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected')),
+    reviewed_by        TEXT,
+    reviewed_at        TIMESTAMPTZ,
+    review_comment     TEXT,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_price_quarantine_status ON stock_price_quarantine (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_price_quarantine_symbol ON stock_price_quarantine (UPPER(stock_symbol), created_at);
@@ -0,0 +1,12 @@
+UPDATE stock_prices SET price_source = 'unknown' WHERE price_source = 'bhavcopy';
+UPDATE stock_price_history SET price_source = 'unknown' WHERE price_source = 'bhavcopy';
+
+ALTER TABLE stock_price_history DROP CONSTRAINT stock_price_history_price_source_check;
+ALTER TABLE stock_price_history ADD CONSTRAINT stock_price_history_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'unknown'));
+
+ALTER TABLE stock_prices DROP CONSTRAINT stock_prices_price_source_check;
+ALTER TABLE stock_prices ADD CONSTRAINT stock_prices_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'unknown'));
+
+DROP TABLE IF EXISTS price_import_files;
@@ -0,0 +1,24 @@
+-- Exchange bhavcopy files loaded into the price history. A file is
+-- identified by its content hash, so re-importing it is detected.
+CREATE TABLE IF NOT EXISTS price_import_files (
+    id            SERIAL PRIMARY KEY,
+    file_name     TEXT NOT NULL,
+    sha256        TEXT NOT NULL UNIQUE,
+    format        TEXT NOT NULL,
+    trade_date    DATE NOT NULL,
+    rows_total    INT NOT NULL,
+    rows_imported INT NOT NULL,
+    prices_seeded INT NOT NULL DEFAULT 0,
+    imported_by   TEXT NOT NULL,
+    imported_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_import_files_trade_date ON price_import_files (trade_date);
+
+ALTER TABLE stock_prices DROP CONSTRAINT stock_prices_price_source_check;
+ALTER TABLE stock_prices ADD CONSTRAINT stock_prices_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'bhavcopy', 'unknown'));
+
+ALTER TABLE stock_price_history DROP CONSTRAINT stock_price_history_price_source_check;
+ALTER TABLE stock_price_history ADD CONSTRAINT stock_price_history_price_source_check
+    CHECK (price_source IN ('provider', 'fallback_random', 'cache', 'carried_forward', 'override', 'bhavcopy', 'unknown'));
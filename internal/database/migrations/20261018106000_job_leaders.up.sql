@@ -0,0 +1,8 @@
+-- The instance currently holding a job leadership lock. The Postgres
+-- advisory lock decides who leads; this row only makes it visible.
+CREATE TABLE IF NOT EXISTS job_leaders (
+    name         TEXT PRIMARY KEY,
+    instance_id  TEXT NOT NULL,
+    acquired_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
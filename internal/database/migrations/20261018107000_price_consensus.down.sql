@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_stock_price_ticks_divergent;
+DROP INDEX IF EXISTS idx_stock_prices_divergent;
+
+ALTER TABLE stock_price_ticks DROP COLUMN IF EXISTS divergent, DROP COLUMN IF EXISTS consensus;
+ALTER TABLE stock_price_history DROP COLUMN IF EXISTS divergent, DROP COLUMN IF EXISTS consensus;
+ALTER TABLE stock_prices DROP COLUMN IF EXISTS divergent, DROP COLUMN IF EXISTS consensus;
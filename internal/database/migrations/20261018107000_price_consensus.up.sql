@@ -0,0 +1,16 @@
+-- How the price sources compared when several were queried: every quote, the
+-- method and source that won, and the spread. NULL when one source was used.
+ALTER TABLE stock_prices
+    ADD COLUMN IF NOT EXISTS consensus JSONB,
+    ADD COLUMN IF NOT EXISTS divergent BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE stock_price_history
+    ADD COLUMN IF NOT EXISTS consensus JSONB,
+    ADD COLUMN IF NOT EXISTS divergent BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE stock_price_ticks
+    ADD COLUMN IF NOT EXISTS consensus JSONB,
+    ADD COLUMN IF NOT EXISTS divergent BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_stock_prices_divergent ON stock_prices (stock_symbol) WHERE divergent;
+CREATE INDEX IF NOT EXISTS idx_stock_price_ticks_divergent ON stock_price_ticks (fetched_at) WHERE divergent;
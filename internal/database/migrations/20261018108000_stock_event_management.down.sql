@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_stock_events_symbol_date;
+DROP TABLE IF EXISTS stock_event_audit;
+
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS updated_by,
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS note;
@@ -0,0 +1,22 @@
+-- Stock events are managed through the API: who created or last changed an
+-- event, and an audit trail of every change. before/after hold the event as
+-- it was and as it became; a deleted event has no after.
+ALTER TABLE stock_events
+    ADD COLUMN IF NOT EXISTS note TEXT,
+    ADD COLUMN IF NOT EXISTS created_by TEXT,
+    ADD COLUMN IF NOT EXISTS updated_by TEXT,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS stock_event_audit (
+    id         SERIAL PRIMARY KEY,
+    event_id   INT NOT NULL,
+    action     TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    actor      TEXT NOT NULL,
+    forced     BOOLEAN NOT NULL DEFAULT FALSE,
+    before     JSONB,
+    after      JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_event_audit_event ON stock_event_audit (event_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_events_symbol_date ON stock_events (UPPER(stock_symbol), effective_date);
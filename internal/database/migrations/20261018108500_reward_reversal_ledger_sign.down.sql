@@ -0,0 +1,13 @@
+-- Remove the correcting rows, then flip reversal rows back to the sign the
+-- previous code posts.
+DELETE FROM ledger
+WHERE id IN (SELECT ledger_id FROM reward_reversal_ledger_corrections);
+
+DROP TABLE IF EXISTS reward_reversal_ledger_corrections;
+
+UPDATE ledger l
+SET quantity = -l.quantity
+FROM adjustments a
+WHERE l.adjustment_id = a.id
+  AND a.adjustment_type = 'reward_reversal'
+  AND l.entry_type = 'stock_units';
@@ -0,0 +1,35 @@
+-- Reward reversals posted their stock_units row as -delta_quantity, although
+-- delta_quantity is already negative for a reversal. The ledger therefore
+-- credited units that the adjustment took away, and its stock_units rows did
+-- not add up to the reward quantity plus adjustments that the reward views
+-- report. Flip the linked rows, then post one correcting row for any lot whose
+-- units still disagree with the reward and its adjustments (reversals recorded
+-- before rows were linked). The correcting rows are listed in
+-- reward_reversal_ledger_corrections so the down migration can remove them.
+CREATE TABLE IF NOT EXISTS reward_reversal_ledger_corrections (
+    ledger_id INT PRIMARY KEY REFERENCES ledger(id) ON DELETE CASCADE
+);
+
+UPDATE ledger l
+SET quantity = -l.quantity
+FROM adjustments a
+WHERE l.adjustment_id = a.id
+  AND a.adjustment_type = 'reward_reversal'
+  AND l.entry_type = 'stock_units';
+
+WITH corrections AS (
+    INSERT INTO ledger (reward_id, entry_type, stock_symbol, quantity, amount, created_at)
+    SELECT r.id, 'stock_units', r.stock_symbol,
+        r.quantity + COALESCE(adj.delta_quantity, 0) - COALESCE(lg.units, 0), 0, NOW()
+    FROM rewards r
+    LEFT JOIN (
+        SELECT reward_id, SUM(delta_quantity) AS delta_quantity FROM adjustments GROUP BY reward_id
+    ) adj ON adj.reward_id = r.id
+    LEFT JOIN (
+        SELECT reward_id, SUM(quantity) AS units FROM ledger WHERE entry_type = 'stock_units' GROUP BY reward_id
+    ) lg ON lg.reward_id = r.id
+    WHERE r.quantity + COALESCE(adj.delta_quantity, 0) <> COALESCE(lg.units, 0)
+    RETURNING id
+)
+INSERT INTO reward_reversal_ledger_corrections (ledger_id)
+SELECT id FROM corrections;
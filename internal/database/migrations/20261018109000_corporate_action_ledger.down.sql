@@ -0,0 +1,88 @@
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+DROP VIEW IF EXISTS historical_rewards;
+DROP VIEW IF EXISTS reward_lots;
+
+DROP FUNCTION IF EXISTS unapply_stock_event(INT);
+DROP FUNCTION IF EXISTS apply_stock_event(INT);
+DROP FUNCTION IF EXISTS reward_lot_symbol(INT);
+DROP FUNCTION IF EXISTS reward_lot_multiplier(INT);
+
+DELETE FROM ledger WHERE stock_event_id IS NOT NULL;
+
+DROP TABLE IF EXISTS stock_event_applications;
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS applied_lots,
+    DROP COLUMN IF EXISTS applied_at;
+DROP INDEX IF EXISTS idx_ledger_reward_units;
+DROP INDEX IF EXISTS idx_ledger_stock_event;
+ALTER TABLE ledger DROP COLUMN IF EXISTS stock_event_id;
+
+CREATE VIEW reward_lots AS
+SELECT
+    r.id AS reward_id,
+    r.user_id,
+    r.created_at::date AS reward_date,
+    r.stock_symbol AS original_symbol,
+    reward_current_symbol(r.stock_symbol, r.created_at::date) AS stock_symbol,
+    r.quantity + COALESCE(adj.delta_quantity, 0) AS base_quantity,
+    reward_unit_multiplier(r.stock_symbol, r.created_at::date) AS unit_multiplier,
+    COALESCE(adj.delta_amount, 0) AS total_adjustment_amount
+FROM rewards r
+LEFT JOIN (
+    SELECT reward_id, SUM(delta_quantity) AS delta_quantity, SUM(delta_amount) AS delta_amount
+    FROM adjustments
+    GROUP BY reward_id
+) adj ON adj.reward_id = r.id;
+
+CREATE VIEW user_portfolio AS
+SELECT
+    l.user_id,
+    l.stock_symbol,
+    SUM(l.base_quantity * l.unit_multiplier) AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    SUM(l.base_quantity * l.unit_multiplier) * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+GROUP BY l.user_id, l.stock_symbol, sp.price, sp.price_source;
+
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.base_quantity * l.unit_multiplier * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE UPPER(e.stock_symbol) = UPPER(l.stock_symbol)
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+CREATE VIEW historical_rewards AS
+SELECT
+    l.user_id,
+    l.reward_date,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.base_quantity * l.unit_multiplier AS adjusted_quantity,
+    COALESCE(hp.price, 0) / l.unit_multiplier AS price,
+    l.total_adjustment_amount,
+    l.base_quantity * COALESCE(hp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN LATERAL (
+    SELECT h.price
+    FROM stock_price_history h
+    WHERE UPPER(h.stock_symbol) = UPPER(l.original_symbol)
+      AND h.date <= l.reward_date
+    ORDER BY h.date DESC
+    LIMIT 1
+) hp ON TRUE;
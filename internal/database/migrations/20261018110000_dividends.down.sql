@@ -0,0 +1,76 @@
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS dividend_payouts;
+DROP TABLE IF EXISTS user_wallets;
+
+-- Enum values cannot be dropped; dividend events are removed instead.
+DELETE FROM stock_events WHERE event_type::text = 'dividend';
+
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS amount_per_share,
+    DROP COLUMN IF EXISTS record_date;
+
+-- apply_stock_event posts an event in effect to the lots holding its symbol
+-- and returns how many lots it changed. It does nothing for an event that is
+-- already applied or not yet in effect, so concurrent callers are safe. A
+-- delisting posts no rows; it only hides the symbol.
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev      stock_events%ROWTYPE;
+    v_ratio NUMERIC;
+    v_lots  INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, units_before, units_after, ratio, applied_at)
+        SELECT ev.id, h.reward_id, h.stock_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_symbol ELSE h.stock_symbol END,
+            h.units, ROUND(h.units * v_ratio, 6), v_ratio, NOW()
+        FROM (
+            SELECT lg.reward_id, MIN(lg.stock_symbol) AS stock_symbol, SUM(lg.quantity) AS units
+            FROM ledger lg
+            JOIN rewards r ON r.id = lg.reward_id
+            WHERE lg.entry_type = 'stock_units'
+              AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+              AND r.created_at::date < ev.effective_date
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.symbol_after, a.units_after - a.units_before WHERE a.symbol_after = a.symbol_before
+            UNION ALL
+            SELECT a.symbol_before, -a.units_before WHERE a.symbol_after <> a.symbol_before
+            UNION ALL
+            SELECT a.symbol_after, a.units_after WHERE a.symbol_after <> a.symbol_before
+        ) m(stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
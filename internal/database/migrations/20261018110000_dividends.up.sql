@@ -0,0 +1,114 @@
+-- Cash dividends. A dividend is a stock event with a record date and an
+-- amount per share; its effective_date is the payment date. Holders as of the
+-- record date are credited to an INR wallet, net of TDS.
+ALTER TYPE stock_event_type ADD VALUE IF NOT EXISTS 'dividend';
+
+ALTER TABLE stock_events
+    ADD COLUMN IF NOT EXISTS record_date DATE,
+    ADD COLUMN IF NOT EXISTS amount_per_share NUMERIC(18, 4);
+
+CREATE TABLE IF NOT EXISTS user_wallets (
+    user_id    INT PRIMARY KEY REFERENCES users(id),
+    balance    NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per holder and dividend. tds_pct is the rate applied, 0 when the
+-- holder's dividends for the financial year stayed under the threshold.
+CREATE TABLE IF NOT EXISTS dividend_payouts (
+    id               SERIAL PRIMARY KEY,
+    event_id         INT NOT NULL REFERENCES stock_events(id),
+    user_id          INT NOT NULL REFERENCES users(id),
+    stock_symbol     TEXT NOT NULL,
+    record_date      DATE NOT NULL,
+    payment_date     DATE NOT NULL,
+    units            NUMERIC(18, 6) NOT NULL,
+    amount_per_share NUMERIC(18, 4) NOT NULL,
+    gross_amount     NUMERIC(18, 4) NOT NULL,
+    tds_pct          NUMERIC(8, 4) NOT NULL,
+    tds_amount       NUMERIC(18, 4) NOT NULL,
+    net_amount       NUMERIC(18, 4) NOT NULL,
+    paid_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_dividend_payouts_user ON dividend_payouts (user_id, payment_date);
+
+-- Every change to a wallet balance. amount is signed; balance_after is the
+-- balance once the row is applied.
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id                 SERIAL PRIMARY KEY,
+    user_id            INT NOT NULL REFERENCES users(id),
+    txn_type           TEXT NOT NULL CHECK (txn_type IN ('dividend', 'tds')),
+    amount             NUMERIC(18, 4) NOT NULL,
+    balance_after      NUMERIC(18, 4) NOT NULL,
+    stock_event_id     INT,
+    dividend_payout_id INT REFERENCES dividend_payouts(id),
+    note               TEXT,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_user ON wallet_transactions (user_id, created_at);
+
+-- Dividends change no units and are paid by the corporate actions job in Go,
+-- so the ledger posting skips them.
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev      stock_events%ROWTYPE;
+    v_ratio NUMERIC;
+    v_lots  INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, units_before, units_after, ratio, applied_at)
+        SELECT ev.id, h.reward_id, h.stock_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_symbol ELSE h.stock_symbol END,
+            h.units, ROUND(h.units * v_ratio, 6), v_ratio, NOW()
+        FROM (
+            SELECT lg.reward_id, MIN(lg.stock_symbol) AS stock_symbol, SUM(lg.quantity) AS units
+            FROM ledger lg
+            JOIN rewards r ON r.id = lg.reward_id
+            WHERE lg.entry_type = 'stock_units'
+              AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+              AND r.created_at::date < ev.effective_date
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.symbol_after, a.units_after - a.units_before WHERE a.symbol_after = a.symbol_before
+            UNION ALL
+            SELECT a.symbol_before, -a.units_before WHERE a.symbol_after <> a.symbol_before
+            UNION ALL
+            SELECT a.symbol_after, a.units_after WHERE a.symbol_after <> a.symbol_before
+        ) m(stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,231 @@
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+DROP VIEW IF EXISTS historical_rewards;
+DROP VIEW IF EXISTS reward_lots;
+
+DROP TRIGGER IF EXISTS trg_rewards_instrument ON rewards;
+DROP TRIGGER IF EXISTS trg_ledger_instrument ON ledger;
+DROP TRIGGER IF EXISTS trg_stock_events_instrument ON stock_events;
+DROP TRIGGER IF EXISTS trg_stock_events_target_instrument ON stock_events;
+DROP TRIGGER IF EXISTS trg_dividend_payouts_instrument ON dividend_payouts;
+DROP TRIGGER IF EXISTS trg_stock_prices_instrument ON stock_prices;
+DROP TRIGGER IF EXISTS trg_stock_price_history_instrument ON stock_price_history;
+DROP TRIGGER IF EXISTS trg_stock_price_ticks_instrument ON stock_price_ticks;
+DROP TRIGGER IF EXISTS trg_stock_price_daily_bars_instrument ON stock_price_daily_bars;
+DROP TRIGGER IF EXISTS trg_stock_closing_prices_instrument ON stock_closing_prices;
+DROP TRIGGER IF EXISTS trg_stock_price_overrides_instrument ON stock_price_overrides;
+DROP TRIGGER IF EXISTS trg_stock_price_bands_instrument ON stock_price_bands;
+DROP TRIGGER IF EXISTS trg_stock_price_quarantine_instrument ON stock_price_quarantine;
+DROP FUNCTION IF EXISTS set_target_instrument_id();
+DROP FUNCTION IF EXISTS set_instrument_id();
+DROP FUNCTION IF EXISTS reward_lot_symbol(INT);
+DROP FUNCTION IF EXISTS reward_lot_instrument(INT);
+
+-- Rows posted after a rename carry the new symbol and are not matched to the
+-- old one by the restored functions.
+CREATE OR REPLACE FUNCTION reward_lot_symbol(p_reward_id INT)
+RETURNS TEXT AS $$
+    SELECT COALESCE((
+        SELECT symbol_after
+        FROM stock_event_applications
+        WHERE reward_id = p_reward_id AND reversed_at IS NULL
+        ORDER BY id DESC
+        LIMIT 1
+    ), (SELECT stock_symbol FROM rewards WHERE id = p_reward_id))
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev      stock_events%ROWTYPE;
+    v_ratio NUMERIC;
+    v_lots  INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, units_before, units_after, ratio, applied_at)
+        SELECT ev.id, h.reward_id, h.stock_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_symbol ELSE h.stock_symbol END,
+            h.units, ROUND(h.units * v_ratio, 6), v_ratio, NOW()
+        FROM (
+            SELECT lg.reward_id, MIN(lg.stock_symbol) AS stock_symbol, SUM(lg.quantity) AS units
+            FROM ledger lg
+            JOIN rewards r ON r.id = lg.reward_id
+            WHERE lg.entry_type = 'stock_units'
+              AND UPPER(lg.stock_symbol) = UPPER(ev.stock_symbol)
+              AND r.created_at::date < ev.effective_date
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.symbol_after, a.units_after - a.units_before WHERE a.symbol_after = a.symbol_before
+            UNION ALL
+            SELECT a.symbol_before, -a.units_before WHERE a.symbol_after <> a.symbol_before
+            UNION ALL
+            SELECT a.symbol_after, a.units_after WHERE a.symbol_after <> a.symbol_before
+        ) m(stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unapply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_lots INT := 0;
+BEGIN
+    INSERT INTO ledger (reward_id, stock_event_id, entry_type, stock_symbol, quantity, amount, created_at)
+    SELECT lg.reward_id, p_event_id, 'stock_units', MIN(lg.stock_symbol), -SUM(lg.quantity), 0, NOW()
+    FROM ledger lg
+    WHERE lg.stock_event_id = p_event_id AND lg.entry_type = 'stock_units'
+    GROUP BY lg.reward_id, UPPER(lg.stock_symbol)
+    HAVING SUM(lg.quantity) <> 0;
+
+    UPDATE stock_event_applications SET reversed_at = NOW()
+    WHERE event_id = p_event_id AND reversed_at IS NULL;
+    GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+    UPDATE stock_events SET applied_at = NULL, applied_lots = NULL WHERE id = p_event_id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE stock_price_quarantine DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_price_bands DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_price_overrides DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_closing_prices DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_price_daily_bars DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_price_ticks DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_price_history DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_prices DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE dividend_payouts DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE stock_event_applications
+    DROP COLUMN IF EXISTS instrument_after,
+    DROP COLUMN IF EXISTS instrument_before;
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS target_instrument_id,
+    DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE ledger DROP CONSTRAINT IF EXISTS ledger_stock_units_instrument;
+ALTER TABLE ledger DROP COLUMN IF EXISTS instrument_id;
+ALTER TABLE rewards DROP COLUMN IF EXISTS instrument_id;
+
+DROP FUNCTION IF EXISTS register_instrument(TEXT);
+DROP FUNCTION IF EXISTS resolve_instrument(TEXT);
+DROP TABLE IF EXISTS instrument_symbol_changes;
+DROP TABLE IF EXISTS instrument_aliases;
+DROP TABLE IF EXISTS instruments;
+
+-- base_quantity stays in pre-event units for adjustment validation; units is
+-- the lot's current holding from the ledger.
+CREATE VIEW reward_lots AS
+SELECT
+    r.id AS reward_id,
+    r.user_id,
+    r.created_at::date AS reward_date,
+    r.stock_symbol AS original_symbol,
+    reward_lot_symbol(r.id) AS stock_symbol,
+    r.quantity + COALESCE(adj.delta_quantity, 0) AS base_quantity,
+    reward_lot_multiplier(r.id) AS unit_multiplier,
+    COALESCE(adj.delta_amount, 0) AS total_adjustment_amount,
+    COALESCE(lg.units, 0) AS units
+FROM rewards r
+LEFT JOIN (
+    SELECT reward_id, SUM(delta_quantity) AS delta_quantity, SUM(delta_amount) AS delta_amount
+    FROM adjustments
+    GROUP BY reward_id
+) adj ON adj.reward_id = r.id
+LEFT JOIN (
+    SELECT reward_id, SUM(quantity) AS units
+    FROM ledger
+    WHERE entry_type = 'stock_units'
+    GROUP BY reward_id
+) lg ON lg.reward_id = r.id;
+
+-- Symbols a user no longer holds, e.g. merged away, are left out.
+CREATE VIEW user_portfolio AS
+SELECT
+    h.user_id,
+    COALESCE(sp.stock_symbol, h.stock_symbol) AS stock_symbol,
+    h.units AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    h.units * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM (
+    SELECT r.user_id, MIN(lg.stock_symbol) AS stock_symbol, SUM(lg.quantity) AS units
+    FROM ledger lg
+    JOIN rewards r ON r.id = lg.reward_id
+    WHERE lg.entry_type = 'stock_units'
+    GROUP BY r.user_id, UPPER(lg.stock_symbol)
+    HAVING SUM(lg.quantity) <> 0
+) h
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(h.stock_symbol);
+
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.units AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.units * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON UPPER(sp.stock_symbol) = UPPER(l.stock_symbol)
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE UPPER(e.stock_symbol) = UPPER(l.stock_symbol)
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+-- Historical value uses the price on the reward date, restated per current
+-- unit so that adjusted_quantity * price = inr_value.
+CREATE VIEW historical_rewards AS
+SELECT
+    l.user_id,
+    l.reward_date,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.units AS adjusted_quantity,
+    COALESCE(hp.price, 0) / l.unit_multiplier AS price,
+    l.total_adjustment_amount,
+    l.units / l.unit_multiplier * COALESCE(hp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN LATERAL (
+    SELECT h.price
+    FROM stock_price_history h
+    WHERE UPPER(h.stock_symbol) = UPPER(l.original_symbol)
+      AND h.date <= l.reward_date
+    ORDER BY h.date DESC
+    LIMIT 1
+) hp ON TRUE;
@@ -0,0 +1,457 @@
+-- Instrument master. Every stock is an instrument keyed by ISIN, with its
+-- exchange symbols, name, sector, face value and listing status. Rewards,
+-- ledger rows, prices and stock events reference the instrument; their
+-- stock_symbol columns keep the symbol as recorded at the time, so a rename
+-- leaves the history as it was while the instrument key ties it together.
+CREATE TABLE IF NOT EXISTS instruments (
+    id             SERIAL PRIMARY KEY,
+    isin           VARCHAR(12) UNIQUE CHECK (isin ~ '^[A-Z]{2}[A-Z0-9]{9}[0-9]$'),
+    symbol         TEXT NOT NULL UNIQUE CHECK (symbol <> '' AND symbol = UPPER(symbol)),
+    nse_symbol     TEXT UNIQUE CHECK (nse_symbol = UPPER(nse_symbol)),
+    bse_symbol     TEXT UNIQUE CHECK (bse_symbol = UPPER(bse_symbol)),
+    name           TEXT NOT NULL,
+    sector         TEXT,
+    face_value     NUMERIC(12, 4) CHECK (face_value > 0),
+    listing_status TEXT NOT NULL DEFAULT 'listed' CHECK (listing_status IN ('listed', 'suspended', 'delisted')),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by     TEXT,
+    updated_at     TIMESTAMPTZ
+);
+
+-- Other names an instrument is looked up by. A rename keeps the old symbol as
+-- a former_symbol alias. A ticker can be reused, so two instruments may share
+-- a former symbol.
+CREATE TABLE IF NOT EXISTS instrument_aliases (
+    id            SERIAL PRIMARY KEY,
+    instrument_id INT NOT NULL REFERENCES instruments(id),
+    alias         TEXT NOT NULL CHECK (alias <> '' AND alias = UPPER(alias)),
+    alias_type    TEXT NOT NULL CHECK (alias_type IN ('alias', 'former_symbol')),
+    created_by    TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (instrument_id, alias)
+);
+CREATE INDEX IF NOT EXISTS idx_instrument_aliases_alias ON instrument_aliases (alias, created_at);
+
+CREATE TABLE IF NOT EXISTS instrument_symbol_changes (
+    id            SERIAL PRIMARY KEY,
+    instrument_id INT NOT NULL REFERENCES instruments(id),
+    old_symbol    TEXT NOT NULL,
+    new_symbol    TEXT NOT NULL,
+    reason        TEXT NOT NULL,
+    changed_by    TEXT NOT NULL,
+    changed_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_instrument_symbol_changes_instrument ON instrument_symbol_changes (instrument_id, changed_at);
+
+-- resolve_instrument finds the instrument a symbol, exchange symbol, ISIN or
+-- alias names, case-insensitively. A current symbol wins over an alias, so a
+-- ticker taken over by another company resolves to its current holder, and
+-- of two instruments that once used a symbol the later one wins.
+CREATE OR REPLACE FUNCTION resolve_instrument(p_symbol TEXT)
+RETURNS INT AS $$
+    SELECT id FROM (
+        SELECT id, 1 AS rank, NULL::timestamptz AS since FROM instruments WHERE symbol = UPPER(TRIM(p_symbol))
+        UNION ALL
+        SELECT id, 2, NULL FROM instruments WHERE nse_symbol = UPPER(TRIM(p_symbol))
+        UNION ALL
+        SELECT id, 3, NULL FROM instruments WHERE bse_symbol = UPPER(TRIM(p_symbol))
+        UNION ALL
+        SELECT id, 4, NULL FROM instruments WHERE isin = UPPER(TRIM(p_symbol))
+        UNION ALL
+        SELECT instrument_id, 5, created_at FROM instrument_aliases WHERE alias = UPPER(TRIM(p_symbol))
+    ) m
+    ORDER BY rank, since DESC
+    LIMIT 1
+$$ LANGUAGE SQL STABLE;
+
+-- register_instrument resolves a symbol, creating a bare instrument (no ISIN
+-- yet) for one never seen before. Only price data registers instruments.
+CREATE OR REPLACE FUNCTION register_instrument(p_symbol TEXT)
+RETURNS INT AS $$
+DECLARE
+    v_id INT;
+BEGIN
+    v_id := resolve_instrument(p_symbol);
+    IF v_id IS NULL THEN
+        INSERT INTO instruments (symbol, nse_symbol, name)
+        VALUES (UPPER(TRIM(p_symbol)), UPPER(TRIM(p_symbol)), UPPER(TRIM(p_symbol)))
+        ON CONFLICT DO NOTHING
+        RETURNING id INTO v_id;
+        IF v_id IS NULL THEN
+            v_id := resolve_instrument(p_symbol);
+        END IF;
+    END IF;
+    RETURN v_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Every symbol recorded so far becomes an instrument under its upper-case
+-- spelling. Their ISINs are not known and are set through the API.
+INSERT INTO instruments (symbol, nse_symbol, name)
+SELECT s, s, s
+FROM (
+    SELECT UPPER(TRIM(stock_symbol)) AS s FROM stock_prices
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM rewards
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM ledger WHERE entry_type = 'stock_units'
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_events
+    UNION SELECT UPPER(TRIM(target_symbol)) FROM stock_events WHERE target_symbol IS NOT NULL
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_history
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_ticks
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_daily_bars
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_closing_prices
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_overrides
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_bands
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM stock_price_quarantine
+    UNION SELECT UPPER(TRIM(stock_symbol)) FROM dividend_payouts
+) symbols
+WHERE s IS NOT NULL AND s <> ''
+ON CONFLICT DO NOTHING;
+
+UPDATE instruments i
+SET listing_status = 'delisted'
+WHERE EXISTS (
+    SELECT 1 FROM stock_events e
+    WHERE UPPER(TRIM(e.stock_symbol)) = i.symbol
+      AND e.event_type = 'delist'
+      AND e.effective_date <= CURRENT_DATE
+);
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_events
+    ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id),
+    ADD COLUMN IF NOT EXISTS target_instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_event_applications
+    ADD COLUMN IF NOT EXISTS instrument_before INT REFERENCES instruments(id),
+    ADD COLUMN IF NOT EXISTS instrument_after INT REFERENCES instruments(id);
+ALTER TABLE dividend_payouts ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_prices ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_history ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_ticks ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_daily_bars ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_closing_prices ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_overrides ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_bands ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+ALTER TABLE stock_price_quarantine ADD COLUMN IF NOT EXISTS instrument_id INT REFERENCES instruments(id);
+
+UPDATE rewards t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE ledger t SET instrument_id = i.id FROM instruments i
+WHERE t.entry_type = 'stock_units' AND i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_events t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_events t SET target_instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.target_symbol));
+UPDATE stock_event_applications t SET instrument_before = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.symbol_before));
+UPDATE stock_event_applications t SET instrument_after = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.symbol_after));
+UPDATE dividend_payouts t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_prices t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_history t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_ticks t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_daily_bars t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_closing_prices t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_overrides t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_bands t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+UPDATE stock_price_quarantine t SET instrument_id = i.id FROM instruments i WHERE i.symbol = UPPER(TRIM(t.stock_symbol));
+
+ALTER TABLE rewards ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE ledger
+    DROP CONSTRAINT IF EXISTS ledger_stock_units_instrument,
+    ADD CONSTRAINT ledger_stock_units_instrument CHECK (entry_type <> 'stock_units' OR instrument_id IS NOT NULL);
+ALTER TABLE stock_events ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_event_applications
+    ALTER COLUMN instrument_before SET NOT NULL,
+    ALTER COLUMN instrument_after SET NOT NULL;
+ALTER TABLE dividend_payouts ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_prices ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_history ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_ticks ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_daily_bars ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_closing_prices ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_overrides ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_bands ALTER COLUMN instrument_id SET NOT NULL;
+ALTER TABLE stock_price_quarantine ALTER COLUMN instrument_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_rewards_instrument ON rewards (instrument_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_instrument_units ON ledger (instrument_id, reward_id) WHERE entry_type = 'stock_units';
+CREATE INDEX IF NOT EXISTS idx_stock_events_instrument ON stock_events (instrument_id, effective_date);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_prices_instrument ON stock_prices (instrument_id);
+CREATE INDEX IF NOT EXISTS idx_stock_price_history_instrument ON stock_price_history (instrument_id, date);
+CREATE INDEX IF NOT EXISTS idx_stock_price_ticks_instrument ON stock_price_ticks (instrument_id, fetched_at);
+CREATE INDEX IF NOT EXISTS idx_stock_price_daily_bars_instrument ON stock_price_daily_bars (instrument_id, date);
+CREATE INDEX IF NOT EXISTS idx_stock_closing_prices_instrument ON stock_closing_prices (instrument_id, date);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_price_bands_instrument ON stock_price_bands (instrument_id);
+
+-- Rows written by symbol get their instrument filled in. Price data registers
+-- symbols it has not seen; everything else must name a known instrument.
+CREATE OR REPLACE FUNCTION set_instrument_id()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NULLIF(TRIM(NEW.stock_symbol), '') IS NULL THEN
+        NEW.instrument_id := NULL;
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'INSERT' AND NEW.instrument_id IS NOT NULL THEN
+        RETURN NEW;
+    END IF;
+    IF TG_ARGV[0] = 'register' THEN
+        NEW.instrument_id := register_instrument(NEW.stock_symbol);
+    ELSE
+        NEW.instrument_id := resolve_instrument(NEW.stock_symbol);
+        IF NEW.instrument_id IS NULL THEN
+            RAISE EXCEPTION 'unknown instrument %', NEW.stock_symbol USING ERRCODE = 'foreign_key_violation';
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_target_instrument_id()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NULLIF(TRIM(NEW.target_symbol), '') IS NULL THEN
+        NEW.target_instrument_id := NULL;
+        RETURN NEW;
+    END IF;
+    NEW.target_instrument_id := resolve_instrument(NEW.target_symbol);
+    IF NEW.target_instrument_id IS NULL THEN
+        RAISE EXCEPTION 'unknown instrument %', NEW.target_symbol USING ERRCODE = 'foreign_key_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER trg_rewards_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON rewards
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_ledger_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON ledger
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_stock_events_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_events
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_stock_events_target_instrument BEFORE INSERT OR UPDATE OF target_symbol ON stock_events
+    FOR EACH ROW EXECUTE FUNCTION set_target_instrument_id();
+CREATE OR REPLACE TRIGGER trg_dividend_payouts_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON dividend_payouts
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_stock_prices_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_prices
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('register');
+CREATE OR REPLACE TRIGGER trg_stock_price_history_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_history
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('register');
+CREATE OR REPLACE TRIGGER trg_stock_price_ticks_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_ticks
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('register');
+CREATE OR REPLACE TRIGGER trg_stock_price_daily_bars_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_daily_bars
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('register');
+CREATE OR REPLACE TRIGGER trg_stock_closing_prices_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_closing_prices
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('register');
+CREATE OR REPLACE TRIGGER trg_stock_price_overrides_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_overrides
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_stock_price_bands_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_bands
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+CREATE OR REPLACE TRIGGER trg_stock_price_quarantine_instrument BEFORE INSERT OR UPDATE OF stock_symbol ON stock_price_quarantine
+    FOR EACH ROW EXECUTE FUNCTION set_instrument_id('require');
+
+-- A lot is held in the instrument of the last event applied to it, else the
+-- one it was granted in; its symbol is that instrument's current symbol.
+CREATE OR REPLACE FUNCTION reward_lot_instrument(p_reward_id INT)
+RETURNS INT AS $$
+    SELECT COALESCE((
+        SELECT instrument_after
+        FROM stock_event_applications
+        WHERE reward_id = p_reward_id AND reversed_at IS NULL
+        ORDER BY id DESC
+        LIMIT 1
+    ), (SELECT instrument_id FROM rewards WHERE id = p_reward_id))
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION reward_lot_symbol(p_reward_id INT)
+RETURNS TEXT AS $$
+    SELECT symbol FROM instruments WHERE id = reward_lot_instrument(p_reward_id)
+$$ LANGUAGE SQL STABLE;
+
+-- Events now match lots by instrument, so a lot renamed since it was granted
+-- is still found, and post their rows under the instrument's current symbol.
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, applied_at)
+        SELECT ev.id, h.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            h.units, ROUND(h.units * v_ratio, 6), v_ratio, NOW()
+        FROM (
+            SELECT lg.reward_id, SUM(lg.quantity) AS units
+            FROM ledger lg
+            JOIN rewards r ON r.id = lg.reward_id
+            WHERE lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+              AND r.created_at::date < ev.effective_date
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unapply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_lots INT := 0;
+BEGIN
+    INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+    SELECT lg.reward_id, p_event_id, 'stock_units', lg.instrument_id, i.symbol, -SUM(lg.quantity), 0, NOW()
+    FROM ledger lg
+    JOIN instruments i ON i.id = lg.instrument_id
+    WHERE lg.stock_event_id = p_event_id AND lg.entry_type = 'stock_units'
+    GROUP BY lg.reward_id, lg.instrument_id, i.symbol
+    HAVING SUM(lg.quantity) <> 0;
+
+    UPDATE stock_event_applications SET reversed_at = NOW()
+    WHERE event_id = p_event_id AND reversed_at IS NULL;
+    GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+    UPDATE stock_events SET applied_at = NULL, applied_lots = NULL WHERE id = p_event_id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP VIEW IF EXISTS user_portfolio;
+DROP VIEW IF EXISTS today_rewards;
+DROP VIEW IF EXISTS historical_rewards;
+DROP VIEW IF EXISTS reward_lots;
+
+-- The views group and price holdings by instrument and show its current
+-- symbol; original_symbol is the symbol as the reward was recorded.
+CREATE VIEW reward_lots AS
+SELECT
+    r.id AS reward_id,
+    r.user_id,
+    r.created_at::date AS reward_date,
+    r.stock_symbol AS original_symbol,
+    r.instrument_id AS original_instrument_id,
+    i.id AS instrument_id,
+    i.symbol AS stock_symbol,
+    r.quantity + COALESCE(adj.delta_quantity, 0) AS base_quantity,
+    reward_lot_multiplier(r.id) AS unit_multiplier,
+    COALESCE(adj.delta_amount, 0) AS total_adjustment_amount,
+    COALESCE(lg.units, 0) AS units
+FROM rewards r
+JOIN instruments i ON i.id = reward_lot_instrument(r.id)
+LEFT JOIN (
+    SELECT reward_id, SUM(delta_quantity) AS delta_quantity, SUM(delta_amount) AS delta_amount
+    FROM adjustments
+    GROUP BY reward_id
+) adj ON adj.reward_id = r.id
+LEFT JOIN (
+    SELECT reward_id, SUM(quantity) AS units
+    FROM ledger
+    WHERE entry_type = 'stock_units'
+    GROUP BY reward_id
+) lg ON lg.reward_id = r.id;
+
+CREATE VIEW user_portfolio AS
+SELECT
+    h.user_id,
+    i.symbol AS stock_symbol,
+    h.units AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    h.units * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source,
+    i.id AS instrument_id,
+    i.isin,
+    i.name AS instrument_name
+FROM (
+    SELECT r.user_id, lg.instrument_id, SUM(lg.quantity) AS units
+    FROM ledger lg
+    JOIN rewards r ON r.id = lg.reward_id
+    WHERE lg.entry_type = 'stock_units'
+    GROUP BY r.user_id, lg.instrument_id
+    HAVING SUM(lg.quantity) <> 0
+) h
+JOIN instruments i ON i.id = h.instrument_id
+LEFT JOIN stock_prices sp ON sp.instrument_id = h.instrument_id;
+
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.units AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.units * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON sp.instrument_id = l.instrument_id
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE e.instrument_id = l.instrument_id
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+CREATE VIEW historical_rewards AS
+SELECT
+    l.user_id,
+    l.reward_date,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.units AS adjusted_quantity,
+    COALESCE(hp.price, 0) / l.unit_multiplier AS price,
+    l.total_adjustment_amount,
+    l.units / l.unit_multiplier * COALESCE(hp.price, 0) AS inr_value
+FROM reward_lots l
+LEFT JOIN LATERAL (
+    SELECT h.price
+    FROM stock_price_history h
+    WHERE h.instrument_id = l.original_instrument_id
+      AND h.date <= l.reward_date
+    ORDER BY h.date DESC
+    LIMIT 1
+) hp ON TRUE;
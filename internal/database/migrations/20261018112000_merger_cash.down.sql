@@ -0,0 +1,102 @@
+-- Enum values cannot be dropped; the cash rows and wallet credits are
+-- removed instead, and the wallets debited by what they had been credited.
+DELETE FROM ledger WHERE entry_type::text IN ('merger_cash', 'cash_in_lieu');
+
+UPDATE user_wallets w
+SET balance = w.balance - t.amount, updated_at = NOW()
+FROM (
+    SELECT user_id, SUM(amount) AS amount
+    FROM wallet_transactions
+    WHERE txn_type IN ('merger_cash', 'cash_in_lieu')
+    GROUP BY user_id
+) t
+WHERE t.user_id = w.user_id;
+DELETE FROM wallet_transactions WHERE txn_type IN ('merger_cash', 'cash_in_lieu');
+
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_txn_type_check;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_txn_type_check
+    CHECK (txn_type IN ('dividend', 'tds'));
+
+ALTER TABLE stock_event_applications
+    DROP COLUMN IF EXISTS merger_cash,
+    DROP COLUMN IF EXISTS cash_in_lieu,
+    DROP COLUMN IF EXISTS fraction_price,
+    DROP COLUMN IF EXISTS fraction_units;
+
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS fraction_price,
+    DROP COLUMN IF EXISTS fraction_handling,
+    DROP COLUMN IF EXISTS cash_per_share;
+
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, applied_at)
+        SELECT ev.id, h.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            h.units, ROUND(h.units * v_ratio, 6), v_ratio, NOW()
+        FROM (
+            SELECT lg.reward_id, SUM(lg.quantity) AS units
+            FROM ledger lg
+            JOIN rewards r ON r.id = lg.reward_id
+            WHERE lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+              AND r.created_at::date < ev.effective_date
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
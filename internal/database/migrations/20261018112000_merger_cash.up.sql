@@ -0,0 +1,143 @@
+-- Merger schemes with a cash part and cash for fractional entitlements.
+-- cash_per_share is paid per share of the merging stock. fraction_handling
+-- 'cash' delivers whole target shares and pays for each holder's fraction at
+-- fraction_price, or the target's stored price when the event has none.
+ALTER TYPE ledger_entry_type ADD VALUE IF NOT EXISTS 'merger_cash';
+ALTER TYPE ledger_entry_type ADD VALUE IF NOT EXISTS 'cash_in_lieu';
+
+ALTER TABLE stock_events
+    ADD COLUMN IF NOT EXISTS cash_per_share NUMERIC(18, 4),
+    ADD COLUMN IF NOT EXISTS fraction_handling TEXT NOT NULL DEFAULT 'fractional'
+        CHECK (fraction_handling IN ('fractional', 'cash')),
+    ADD COLUMN IF NOT EXISTS fraction_price NUMERIC(18, 4);
+
+-- units_after is what the lot holds once any fraction is cashed out;
+-- fraction_units is the target units paid for instead, at fraction_price.
+ALTER TABLE stock_event_applications
+    ADD COLUMN IF NOT EXISTS fraction_units NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS fraction_price NUMERIC(18, 4),
+    ADD COLUMN IF NOT EXISTS cash_in_lieu NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS merger_cash NUMERIC(18, 4) NOT NULL DEFAULT 0;
+
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_txn_type_check;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_txn_type_check
+    CHECK (txn_type IN ('dividend', 'tds', 'merger_cash', 'cash_in_lieu'));
+
+-- A holder's fraction is taken from their newest lots first, so each lot
+-- keeps whole units where it can and the holder ends with a whole number of
+-- target shares. The cash is posted to the ledger per lot with a positive
+-- amount; the corporate actions job credits the wallets.
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_price  NUMERIC;
+    v_cash   BOOLEAN;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        v_cash := ev.event_type::text = 'merger' AND ev.fraction_handling = 'cash';
+        IF v_cash THEN
+            v_price := ev.fraction_price;
+            IF v_price IS NULL THEN
+                SELECT price INTO v_price FROM stock_prices WHERE instrument_id = ev.target_instrument_id;
+            END IF;
+            IF v_price IS NULL THEN
+                RAISE EXCEPTION 'no price for % to pay cash in lieu of fractions', v_target;
+            END IF;
+        END IF;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, fraction_units, fraction_price, cash_in_lieu, merger_cash, applied_at)
+        SELECT ev.id, e.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            e.units, e.entitled - e.taken, v_ratio, e.taken,
+            CASE WHEN v_cash THEN v_price END,
+            ROUND(e.taken * COALESCE(v_price, 0), 4),
+            CASE WHEN ev.event_type::text = 'merger' THEN ROUND(e.units * COALESCE(ev.cash_per_share, 0), 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT f.*,
+                CASE WHEN v_cash
+                     THEN LEAST(f.entitled, GREATEST(0, f.fraction - (f.newer_or_same - f.entitled)))
+                     ELSE 0 END AS taken
+            FROM (
+                SELECT h.*,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id)
+                        - FLOOR(SUM(h.entitled) OVER (PARTITION BY h.user_id)) AS fraction,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id ORDER BY h.reward_id DESC) AS newer_or_same
+                FROM (
+                    SELECT lg.reward_id, r.user_id, SUM(lg.quantity) AS units,
+                        ROUND(SUM(lg.quantity) * v_ratio, 6) AS entitled
+                    FROM ledger lg
+                    JOIN rewards r ON r.id = lg.reward_id
+                    WHERE lg.entry_type = 'stock_units'
+                      AND lg.instrument_id = ev.instrument_id
+                      AND r.created_at::date < ev.effective_date
+                    GROUP BY lg.reward_id, r.user_id
+                    HAVING SUM(lg.quantity) > 0
+                ) h
+            ) f
+        ) e;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, m.entry_type::ledger_entry_type, m.instrument_id, m.stock_symbol, m.quantity, m.amount, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT 'merger_cash', a.instrument_before, a.symbol_before, 0::NUMERIC, a.merger_cash
+            WHERE a.merger_cash > 0
+            UNION ALL
+            SELECT 'cash_in_lieu', a.instrument_after, a.symbol_after, a.fraction_units, a.cash_in_lieu
+            WHERE a.cash_in_lieu > 0
+        ) m(entry_type, instrument_id, stock_symbol, quantity, amount)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,190 @@
+DROP VIEW IF EXISTS user_unlisted_holdings;
+DROP VIEW IF EXISTS today_rewards;
+CREATE VIEW today_rewards AS
+SELECT
+    l.user_id,
+    l.reward_id AS reward_event_id,
+    l.stock_symbol,
+    l.units AS adjusted_quantity,
+    COALESCE(sp.price, 0) AS current_price,
+    l.total_adjustment_amount,
+    l.units * COALESCE(sp.price, 0) AS inr_value,
+    COALESCE(sp.price_source, 'unknown') AS price_source
+FROM reward_lots l
+LEFT JOIN stock_prices sp ON sp.instrument_id = l.instrument_id
+WHERE l.reward_date = CURRENT_DATE
+  AND NOT EXISTS (
+      SELECT 1 FROM stock_events e
+      WHERE e.instrument_id = l.instrument_id
+        AND e.event_type = 'delist'
+        AND e.effective_date <= CURRENT_DATE
+  );
+
+-- Enum values cannot be dropped. Delistings go back to hiding holdings: their
+-- ledger rows and applications are removed, exit offer credits are taken
+-- back from the wallets, and the delistings are marked applied again.
+DELETE FROM ledger
+WHERE stock_event_id IN (SELECT id FROM stock_events WHERE event_type::text = 'delist');
+DELETE FROM stock_event_applications
+WHERE event_id IN (SELECT id FROM stock_events WHERE event_type::text = 'delist');
+
+UPDATE user_wallets w
+SET balance = w.balance - t.amount, updated_at = NOW()
+FROM (
+    SELECT user_id, SUM(amount) AS amount
+    FROM wallet_transactions
+    WHERE txn_type = 'exit_offer'
+    GROUP BY user_id
+) t
+WHERE t.user_id = w.user_id;
+DELETE FROM wallet_transactions WHERE txn_type = 'exit_offer';
+
+UPDATE stock_events SET applied_at = COALESCE(applied_at, NOW()), applied_lots = 0
+WHERE event_type::text = 'delist' AND effective_date <= CURRENT_DATE;
+
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_txn_type_check;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_txn_type_check
+    CHECK (txn_type IN ('dividend', 'tds', 'merger_cash', 'cash_in_lieu'));
+
+ALTER TABLE stock_event_applications DROP COLUMN IF EXISTS exit_payout;
+
+ALTER TABLE stock_events
+    DROP COLUMN IF EXISTS exit_price,
+    DROP COLUMN IF EXISTS delist_outcome;
+
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_price  NUMERIC;
+    v_cash   BOOLEAN;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        v_cash := ev.event_type::text = 'merger' AND ev.fraction_handling = 'cash';
+        IF v_cash THEN
+            v_price := ev.fraction_price;
+            IF v_price IS NULL THEN
+                SELECT price INTO v_price FROM stock_prices WHERE instrument_id = ev.target_instrument_id;
+            END IF;
+            IF v_price IS NULL THEN
+                RAISE EXCEPTION 'no price for % to pay cash in lieu of fractions', v_target;
+            END IF;
+        END IF;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, fraction_units, fraction_price, cash_in_lieu, merger_cash, applied_at)
+        SELECT ev.id, e.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            e.units, e.entitled - e.taken, v_ratio, e.taken,
+            CASE WHEN v_cash THEN v_price END,
+            ROUND(e.taken * COALESCE(v_price, 0), 4),
+            CASE WHEN ev.event_type::text = 'merger' THEN ROUND(e.units * COALESCE(ev.cash_per_share, 0), 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT f.*,
+                CASE WHEN v_cash
+                     THEN LEAST(f.entitled, GREATEST(0, f.fraction - (f.newer_or_same - f.entitled)))
+                     ELSE 0 END AS taken
+            FROM (
+                SELECT h.*,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id)
+                        - FLOOR(SUM(h.entitled) OVER (PARTITION BY h.user_id)) AS fraction,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id ORDER BY h.reward_id DESC) AS newer_or_same
+                FROM (
+                    SELECT lg.reward_id, r.user_id, SUM(lg.quantity) AS units,
+                        ROUND(SUM(lg.quantity) * v_ratio, 6) AS entitled
+                    FROM ledger lg
+                    JOIN rewards r ON r.id = lg.reward_id
+                    WHERE lg.entry_type = 'stock_units'
+                      AND lg.instrument_id = ev.instrument_id
+                      AND r.created_at::date < ev.effective_date
+                    GROUP BY lg.reward_id, r.user_id
+                    HAVING SUM(lg.quantity) > 0
+                ) h
+            ) f
+        ) e;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, m.entry_type::ledger_entry_type, m.instrument_id, m.stock_symbol, m.quantity, m.amount, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT 'merger_cash', a.instrument_before, a.symbol_before, 0::NUMERIC, a.merger_cash
+            WHERE a.merger_cash > 0
+            UNION ALL
+            SELECT 'cash_in_lieu', a.instrument_after, a.symbol_after, a.fraction_units, a.cash_in_lieu
+            WHERE a.cash_in_lieu > 0
+        ) m(entry_type, instrument_id, stock_symbol, quantity, amount)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unapply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    v_lots INT := 0;
+BEGIN
+    INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+    SELECT lg.reward_id, p_event_id, 'stock_units', lg.instrument_id, i.symbol, -SUM(lg.quantity), 0, NOW()
+    FROM ledger lg
+    JOIN instruments i ON i.id = lg.instrument_id
+    WHERE lg.stock_event_id = p_event_id AND lg.entry_type = 'stock_units'
+    GROUP BY lg.reward_id, lg.instrument_id, i.symbol
+    HAVING SUM(lg.quantity) <> 0;
+
+    UPDATE stock_event_applications SET reversed_at = NOW()
+    WHERE event_id = p_event_id AND reversed_at IS NULL;
+    GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+    UPDATE stock_events SET applied_at = NULL, applied_lots = NULL WHERE id = p_event_id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
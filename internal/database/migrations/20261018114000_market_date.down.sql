@@ -0,0 +1,160 @@
+DROP FUNCTION IF EXISTS apply_stock_event(INT, DATE);
+
+CREATE FUNCTION apply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_price  NUMERIC;
+    v_cash   BOOLEAN;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > CURRENT_DATE
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        v_cash := ev.event_type::text = 'merger' AND ev.fraction_handling = 'cash';
+        IF v_cash THEN
+            v_price := ev.fraction_price;
+            IF v_price IS NULL THEN
+                SELECT price INTO v_price FROM stock_prices WHERE instrument_id = ev.target_instrument_id;
+            END IF;
+            IF v_price IS NULL THEN
+                RAISE EXCEPTION 'no price for % to pay cash in lieu of fractions', v_target;
+            END IF;
+        END IF;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, fraction_units, fraction_price, cash_in_lieu, merger_cash, applied_at)
+        SELECT ev.id, e.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            e.units, e.entitled - e.taken, v_ratio, e.taken,
+            CASE WHEN v_cash THEN v_price END,
+            ROUND(e.taken * COALESCE(v_price, 0), 4),
+            CASE WHEN ev.event_type::text = 'merger' THEN ROUND(e.units * COALESCE(ev.cash_per_share, 0), 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT f.*,
+                CASE WHEN v_cash
+                     THEN LEAST(f.entitled, GREATEST(0, f.fraction - (f.newer_or_same - f.entitled)))
+                     ELSE 0 END AS taken
+            FROM (
+                SELECT h.*,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id)
+                        - FLOOR(SUM(h.entitled) OVER (PARTITION BY h.user_id)) AS fraction,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id ORDER BY h.reward_id DESC) AS newer_or_same
+                FROM (
+                    SELECT lg.reward_id, r.user_id, SUM(lg.quantity) AS units,
+                        ROUND(SUM(lg.quantity) * v_ratio, 6) AS entitled
+                    FROM ledger lg
+                    JOIN rewards r ON r.id = lg.reward_id
+                    WHERE lg.entry_type = 'stock_units'
+                      AND lg.instrument_id = ev.instrument_id
+                      AND r.created_at::date < ev.effective_date
+                    GROUP BY lg.reward_id, r.user_id
+                    HAVING SUM(lg.quantity) > 0
+                ) h
+            ) f
+        ) e;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+    ELSIF ev.event_type::text = 'delist' THEN
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+
+        PERFORM 1 FROM rewards r
+        WHERE EXISTS (
+            SELECT 1 FROM ledger lg
+            WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+        )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, exit_payout, applied_at)
+        SELECT ev.id, h.reward_id, v_symbol, v_symbol, ev.instrument_id, ev.instrument_id,
+            h.units, 0, 1,
+            CASE WHEN ev.delist_outcome = 'exit_offer' THEN ROUND(h.units * ev.exit_price, 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT lg.reward_id, SUM(lg.quantity) AS units
+            FROM ledger lg
+            WHERE lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id,
+            CASE ev.delist_outcome
+                WHEN 'exit_offer' THEN 'exit_offer'
+                WHEN 'write_off' THEN 'write_off'
+                ELSE 'unlisted_units'
+            END::ledger_entry_type,
+            a.instrument_before, a.symbol_before, a.units_before, a.exit_payout, NOW()
+        FROM stock_event_applications a
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+
+        UPDATE instruments SET listing_status = 'delisted', updated_at = NOW()
+        WHERE id = ev.instrument_id AND listing_status <> 'delisted';
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger', 'delist') THEN
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, created_at)
+        SELECT a.reward_id, ev.id, m.entry_type::ledger_entry_type, m.instrument_id, m.stock_symbol, m.quantity, m.amount, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT 'merger_cash', a.instrument_before, a.symbol_before, 0::NUMERIC, a.merger_cash
+            WHERE a.merger_cash > 0
+            UNION ALL
+            SELECT 'cash_in_lieu', a.instrument_after, a.symbol_after, a.fraction_units, a.cash_in_lieu
+            WHERE a.cash_in_lieu > 0
+        ) m(entry_type, instrument_id, stock_symbol, quantity, amount)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
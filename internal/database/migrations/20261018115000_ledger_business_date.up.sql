@@ -0,0 +1,257 @@
+-- Ledger rows carry the business date they take effect on, which need not be
+-- the day they were inserted: a corporate action posted late, by a forced
+-- retroactive event or a catch-up run, takes effect on its effective date,
+-- and cancelling it takes effect on that same date. Holdings on a date, such
+-- as a dividend's record date, are summed by business date.
+--
+-- Rewards take effect on their grant date. An adjustment corrects its lot as
+-- of the grant, or of the last corporate action posted to the lot if that is
+-- later, since its units are that action's units; see reward_lot_as_of.
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS business_date DATE;
+
+UPDATE ledger l
+SET business_date = e.effective_date
+FROM stock_events e
+WHERE e.id = l.stock_event_id;
+
+-- Rows of events deleted since were cancelled; date both sides alike.
+UPDATE ledger l
+SET business_date = o.posted
+FROM (
+    SELECT stock_event_id, MIN(created_at)::date AS posted
+    FROM ledger
+    WHERE stock_event_id IS NOT NULL
+    GROUP BY stock_event_id
+) o
+WHERE l.business_date IS NULL AND l.stock_event_id = o.stock_event_id;
+
+UPDATE ledger l
+SET business_date = GREATEST(r.created_at::date, (
+    SELECT MAX(e.effective_date)
+    FROM stock_event_applications a
+    JOIN stock_events e ON e.id = a.event_id
+    WHERE a.reward_id = l.reward_id
+      AND a.applied_at <= l.created_at
+      AND (a.reversed_at IS NULL OR a.reversed_at > l.created_at)
+))
+FROM rewards r
+WHERE l.business_date IS NULL AND l.adjustment_id IS NOT NULL AND r.id = l.reward_id;
+
+UPDATE ledger l
+SET business_date = r.created_at::date
+FROM rewards r
+WHERE l.business_date IS NULL AND r.id = l.reward_id;
+
+ALTER TABLE ledger
+    ALTER COLUMN business_date SET DEFAULT CURRENT_DATE,
+    ALTER COLUMN business_date SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ledger_instrument_business_date
+    ON ledger (instrument_id, business_date) WHERE entry_type = 'stock_units';
+
+CREATE OR REPLACE FUNCTION reward_lot_as_of(p_reward_id INT)
+RETURNS DATE AS $$
+    SELECT GREATEST(
+        COALESCE(
+            (SELECT MIN(lg.business_date) FROM ledger lg
+             WHERE lg.reward_id = p_reward_id AND lg.adjustment_id IS NULL AND lg.stock_event_id IS NULL),
+            (SELECT created_at::date FROM rewards WHERE id = p_reward_id)
+        ),
+        (SELECT MAX(e.effective_date) FROM stock_event_applications a
+         JOIN stock_events e ON e.id = a.event_id
+         WHERE a.reward_id = p_reward_id AND a.reversed_at IS NULL)
+    )
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION apply_stock_event(p_event_id INT, p_today DATE)
+RETURNS INT AS $$
+DECLARE
+    ev       stock_events%ROWTYPE;
+    v_ratio  NUMERIC;
+    v_symbol TEXT;
+    v_target TEXT;
+    v_price  NUMERIC;
+    v_cash   BOOLEAN;
+    v_lots   INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id FOR UPDATE;
+    IF NOT FOUND OR ev.applied_at IS NOT NULL OR ev.effective_date > p_today
+        OR ev.event_type::text = 'dividend' THEN
+        RETURN 0;
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger') THEN
+        v_ratio := ev.ratio_num::NUMERIC / ev.ratio_den;
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+        SELECT symbol INTO v_target FROM instruments WHERE id = ev.target_instrument_id;
+
+        v_cash := ev.event_type::text = 'merger' AND ev.fraction_handling = 'cash';
+        IF v_cash THEN
+            v_price := ev.fraction_price;
+            IF v_price IS NULL THEN
+                SELECT price INTO v_price FROM stock_prices WHERE instrument_id = ev.target_instrument_id;
+            END IF;
+            IF v_price IS NULL THEN
+                RAISE EXCEPTION 'no price for % to pay cash in lieu of fractions', v_target;
+            END IF;
+        END IF;
+
+        -- Adjustments lock the reward row too, so none lands between
+        -- reading a lot's units and posting the event.
+        PERFORM 1 FROM rewards r
+        WHERE r.created_at::date < ev.effective_date
+          AND EXISTS (
+              SELECT 1 FROM ledger lg
+              WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+                AND lg.instrument_id = ev.instrument_id
+          )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, fraction_units, fraction_price, cash_in_lieu, merger_cash, applied_at)
+        SELECT ev.id, e.reward_id, v_symbol,
+            CASE WHEN ev.event_type::text = 'merger' THEN v_target ELSE v_symbol END,
+            ev.instrument_id,
+            CASE WHEN ev.event_type::text = 'merger' THEN ev.target_instrument_id ELSE ev.instrument_id END,
+            e.units, e.entitled - e.taken, v_ratio, e.taken,
+            CASE WHEN v_cash THEN v_price END,
+            ROUND(e.taken * COALESCE(v_price, 0), 4),
+            CASE WHEN ev.event_type::text = 'merger' THEN ROUND(e.units * COALESCE(ev.cash_per_share, 0), 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT f.*,
+                CASE WHEN v_cash
+                     THEN LEAST(f.entitled, GREATEST(0, f.fraction - (f.newer_or_same - f.entitled)))
+                     ELSE 0 END AS taken
+            FROM (
+                SELECT h.*,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id)
+                        - FLOOR(SUM(h.entitled) OVER (PARTITION BY h.user_id)) AS fraction,
+                    SUM(h.entitled) OVER (PARTITION BY h.user_id ORDER BY h.reward_id DESC) AS newer_or_same
+                FROM (
+                    SELECT lg.reward_id, r.user_id, SUM(lg.quantity) AS units,
+                        ROUND(SUM(lg.quantity) * v_ratio, 6) AS entitled
+                    FROM ledger lg
+                    JOIN rewards r ON r.id = lg.reward_id
+                    WHERE lg.entry_type = 'stock_units'
+                      AND lg.instrument_id = ev.instrument_id
+                      AND r.created_at::date < ev.effective_date
+                    GROUP BY lg.reward_id, r.user_id
+                    HAVING SUM(lg.quantity) > 0
+                ) h
+            ) f
+        ) e;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+    ELSIF ev.event_type::text = 'delist' THEN
+        SELECT symbol INTO v_symbol FROM instruments WHERE id = ev.instrument_id;
+
+        PERFORM 1 FROM rewards r
+        WHERE EXISTS (
+            SELECT 1 FROM ledger lg
+            WHERE lg.reward_id = r.id AND lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+        )
+        ORDER BY r.id
+        FOR UPDATE;
+
+        INSERT INTO stock_event_applications
+            (event_id, reward_id, symbol_before, symbol_after, instrument_before, instrument_after,
+             units_before, units_after, ratio, exit_payout, applied_at)
+        SELECT ev.id, h.reward_id, v_symbol, v_symbol, ev.instrument_id, ev.instrument_id,
+            h.units, 0, 1,
+            CASE WHEN ev.delist_outcome = 'exit_offer' THEN ROUND(h.units * ev.exit_price, 4) ELSE 0 END,
+            NOW()
+        FROM (
+            SELECT lg.reward_id, SUM(lg.quantity) AS units
+            FROM ledger lg
+            WHERE lg.entry_type = 'stock_units'
+              AND lg.instrument_id = ev.instrument_id
+            GROUP BY lg.reward_id
+            HAVING SUM(lg.quantity) > 0
+        ) h;
+        GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, business_date, created_at)
+        SELECT a.reward_id, ev.id,
+            CASE ev.delist_outcome
+                WHEN 'exit_offer' THEN 'exit_offer'
+                WHEN 'write_off' THEN 'write_off'
+                ELSE 'unlisted_units'
+            END::ledger_entry_type,
+            a.instrument_before, a.symbol_before, a.units_before, a.exit_payout, ev.effective_date, NOW()
+        FROM stock_event_applications a
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+
+        UPDATE instruments SET listing_status = 'delisted', updated_at = NOW()
+        WHERE id = ev.instrument_id AND listing_status <> 'delisted';
+    END IF;
+
+    IF ev.event_type::text IN ('split', 'bonus', 'merger', 'delist') THEN
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, business_date, created_at)
+        SELECT a.reward_id, ev.id, 'stock_units', m.instrument_id, m.stock_symbol, m.quantity, 0, ev.effective_date, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT a.instrument_after, a.symbol_after, a.units_after - a.units_before
+            WHERE a.instrument_after = a.instrument_before
+            UNION ALL
+            SELECT a.instrument_before, a.symbol_before, -a.units_before
+            WHERE a.instrument_after <> a.instrument_before
+            UNION ALL
+            SELECT a.instrument_after, a.symbol_after, a.units_after
+            WHERE a.instrument_after <> a.instrument_before
+        ) m(instrument_id, stock_symbol, quantity)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL AND m.quantity <> 0;
+
+        INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, business_date, created_at)
+        SELECT a.reward_id, ev.id, m.entry_type::ledger_entry_type, m.instrument_id, m.stock_symbol, m.quantity, m.amount, ev.effective_date, NOW()
+        FROM stock_event_applications a
+        CROSS JOIN LATERAL (
+            SELECT 'merger_cash', a.instrument_before, a.symbol_before, 0::NUMERIC, a.merger_cash
+            WHERE a.merger_cash > 0
+            UNION ALL
+            SELECT 'cash_in_lieu', a.instrument_after, a.symbol_after, a.fraction_units, a.cash_in_lieu
+            WHERE a.cash_in_lieu > 0
+        ) m(entry_type, instrument_id, stock_symbol, quantity, amount)
+        WHERE a.event_id = ev.id AND a.reversed_at IS NULL;
+    END IF;
+
+    UPDATE stock_events SET applied_at = NOW(), applied_lots = v_lots WHERE id = ev.id;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Cancelling rows take effect on the date of the rows they cancel.
+CREATE OR REPLACE FUNCTION unapply_stock_event(p_event_id INT)
+RETURNS INT AS $$
+DECLARE
+    ev     stock_events%ROWTYPE;
+    v_lots INT := 0;
+BEGIN
+    SELECT * INTO ev FROM stock_events WHERE id = p_event_id;
+
+    INSERT INTO ledger (reward_id, stock_event_id, entry_type, instrument_id, stock_symbol, quantity, amount, business_date, created_at)
+    SELECT lg.reward_id, p_event_id, lg.entry_type, lg.instrument_id, i.symbol, -SUM(lg.quantity), -SUM(lg.amount), lg.business_date, NOW()
+    FROM ledger lg
+    JOIN instruments i ON i.id = lg.instrument_id
+    WHERE lg.stock_event_id = p_event_id
+    GROUP BY lg.reward_id, lg.entry_type, lg.instrument_id, i.symbol, lg.business_date
+    HAVING SUM(lg.quantity) <> 0 OR SUM(lg.amount) <> 0;
+
+    UPDATE stock_event_applications SET reversed_at = NOW()
+    WHERE event_id = p_event_id AND reversed_at IS NULL;
+    GET DIAGNOSTICS v_lots = ROW_COUNT;
+
+    UPDATE stock_events SET applied_at = NULL, applied_lots = NULL WHERE id = p_event_id;
+
+    IF ev.event_type::text = 'delist' AND NOT EXISTS (
+        SELECT 1 FROM stock_events e
+        WHERE e.instrument_id = ev.instrument_id AND e.event_type::text = 'delist'
+          AND e.applied_at IS NOT NULL
+    ) THEN
+        UPDATE instruments SET listing_status = 'listed', updated_at = NOW()
+        WHERE id = ev.instrument_id AND listing_status = 'delisted';
+    END IF;
+    RETURN v_lots;
+END;
+$$ LANGUAGE plpgsql;
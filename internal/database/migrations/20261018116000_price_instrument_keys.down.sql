@@ -0,0 +1,10 @@
+-- Key history and daily bars by symbol again. Duplicate rows removed or merged
+-- by the up migration are not restored.
+ALTER TABLE stock_price_daily_bars DROP CONSTRAINT IF EXISTS stock_price_daily_bars_pkey;
+ALTER TABLE stock_price_daily_bars ADD CONSTRAINT stock_price_daily_bars_pkey PRIMARY KEY (stock_symbol, date);
+CREATE INDEX IF NOT EXISTS idx_stock_price_daily_bars_instrument ON stock_price_daily_bars (instrument_id, date);
+
+DROP INDEX IF EXISTS uq_stock_price_history_instrument_date;
+CREATE INDEX IF NOT EXISTS idx_stock_price_history_instrument ON stock_price_history (instrument_id, date);
+ALTER TABLE stock_price_history DROP CONSTRAINT IF EXISTS stock_price_history_stock_symbol_date_key;
+ALTER TABLE stock_price_history ADD CONSTRAINT stock_price_history_stock_symbol_date_key UNIQUE (stock_symbol, date);
@@ -0,0 +1,96 @@
+-- Price history and daily bars are keyed by instrument rather than symbol, so
+-- a renamed stock keeps one row per day across the rename, and a symbol taken
+-- over by another company does not collide with its former holder's rows.
+
+-- Symbols that resolve to the same instrument may have left several rows for
+-- one day. Keep the row under the instrument's current symbol.
+DELETE FROM stock_price_history
+WHERE ctid IN (
+    SELECT row_id FROM (
+        SELECT h.ctid AS row_id,
+               ROW_NUMBER() OVER (
+                   PARTITION BY h.instrument_id, h.date
+                   ORDER BY (UPPER(TRIM(h.stock_symbol)) = i.symbol) DESC, h.stock_symbol
+               ) AS n
+        FROM stock_price_history h
+        JOIN instruments i ON i.id = h.instrument_id
+    ) ranked
+    WHERE n > 1
+);
+
+-- Bars for the same day are merged into the kept row, chosen the same way:
+-- its open and close stay, the high, low and tick count cover all of them.
+UPDATE stock_price_daily_bars b
+SET high = m.high, low = m.low, tick_count = m.tick_count, updated_at = m.updated_at
+FROM (
+    SELECT instrument_id, date, MAX(high) AS high, MIN(low) AS low,
+           SUM(tick_count) AS tick_count, MAX(updated_at) AS updated_at
+    FROM stock_price_daily_bars
+    GROUP BY instrument_id, date
+    HAVING COUNT(*) > 1
+) m
+WHERE m.instrument_id = b.instrument_id AND m.date = b.date
+  AND b.ctid IN (
+    SELECT row_id FROM (
+        SELECT d.ctid AS row_id,
+               ROW_NUMBER() OVER (
+                   PARTITION BY d.instrument_id, d.date
+                   ORDER BY (UPPER(TRIM(d.stock_symbol)) = i.symbol) DESC, d.stock_symbol
+               ) AS n
+        FROM stock_price_daily_bars d
+        JOIN instruments i ON i.id = d.instrument_id
+    ) ranked
+    WHERE n = 1
+);
+
+DELETE FROM stock_price_daily_bars
+WHERE ctid IN (
+    SELECT row_id FROM (
+        SELECT d.ctid AS row_id,
+               ROW_NUMBER() OVER (
+                   PARTITION BY d.instrument_id, d.date
+                   ORDER BY (UPPER(TRIM(d.stock_symbol)) = i.symbol) DESC, d.stock_symbol
+               ) AS n
+        FROM stock_price_daily_bars d
+        JOIN instruments i ON i.id = d.instrument_id
+    ) ranked
+    WHERE n > 1
+);
+
+-- The symbol key of stock_price_history predates the migrations, so its
+-- constraint or index is found by its columns rather than its name.
+DO $$
+DECLARE
+    v_key int2[];
+    v_name TEXT;
+BEGIN
+    SELECT ARRAY_AGG(attnum ORDER BY attnum) INTO v_key
+    FROM pg_attribute
+    WHERE attrelid = 'stock_price_history'::regclass AND attname IN ('stock_symbol', 'date');
+
+    FOR v_name IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = 'stock_price_history'::regclass AND contype = 'u'
+          AND conkey @> v_key AND CARDINALITY(conkey) = 2
+    LOOP
+        EXECUTE FORMAT('ALTER TABLE stock_price_history DROP CONSTRAINT %I', v_name);
+    END LOOP;
+
+    FOR v_name IN
+        SELECT c.relname FROM pg_index x
+        JOIN pg_class c ON c.oid = x.indexrelid
+        WHERE x.indrelid = 'stock_price_history'::regclass AND x.indisunique AND NOT x.indisprimary
+          AND x.indexprs IS NULL
+          AND x.indkey::int2[] @> v_key AND CARDINALITY(x.indkey::int2[]) = 2
+    LOOP
+        EXECUTE FORMAT('DROP INDEX %I', v_name);
+    END LOOP;
+END $$;
+
+DROP INDEX IF EXISTS idx_stock_price_history_instrument;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_price_history_instrument_date
+    ON stock_price_history (instrument_id, date);
+
+ALTER TABLE stock_price_daily_bars DROP CONSTRAINT IF EXISTS stock_price_daily_bars_pkey;
+ALTER TABLE stock_price_daily_bars ADD CONSTRAINT stock_price_daily_bars_pkey PRIMARY KEY (instrument_id, date);
+DROP INDEX IF EXISTS idx_stock_price_daily_bars_instrument;
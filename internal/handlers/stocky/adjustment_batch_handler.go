@@ -0,0 +1,290 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const adjustmentBatchColumns = `
+	id, file_name, row_count, total_inr_value, required_approvals, status, initiated_by, created_at, decided_at`
+
+func scanAdjustmentBatch(row rowScanner) (models.AdjustmentBatch, error) {
+	var b models.AdjustmentBatch
+	err := row.Scan(
+		&b.ID,
+		&b.FileName,
+		&b.RowCount,
+		&b.TotalINRValue,
+		&b.RequiredApprovals,
+		&b.Status,
+		&b.InitiatedBy,
+		&b.CreatedAt,
+		&b.DecidedAt,
+	)
+	return b, err
+}
+
+// loadBatchRequests fills in the member requests of a batch and the distinct
+// operators who approved it. Batch decisions are recorded against every member
+// request, so approvers are read back from any of them.
+func loadBatchRequests(ctx context.Context, q querier, batch *models.AdjustmentBatch) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+adjustmentRequestColumns+`
+		FROM adjustment_requests
+		WHERE batch_id = $1
+		ORDER BY id
+	`, batch.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch.Requests = []models.AdjustmentRequest{}
+	var ids []int
+	for rows.Next() {
+		r, err := scanAdjustmentRequest(rows)
+		if err != nil {
+			return err
+		}
+		batch.Requests = append(batch.Requests, r)
+		ids = append(ids, r.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	approvals, err := loadAdjustmentApprovals(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	batch.Approvers = []string{}
+	seen := make(map[string]bool)
+	for i := range batch.Requests {
+		batch.Requests[i].Approvals = approvalsOrEmpty(approvals[batch.Requests[i].ID])
+		for _, a := range batch.Requests[i].Approvals {
+			if a.Decision == models.DecisionApprove && !seen[a.Approver] {
+				seen[a.Approver] = true
+				batch.Approvers = append(batch.Approvers, a.Approver)
+			}
+		}
+	}
+	return nil
+}
+
+func getAdjustmentBatch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "adjustment batch ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"batch_id":   id,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	batch, err := scanAdjustmentBatch(db.QueryRowContext(ctx, `
+		SELECT `+adjustmentBatchColumns+` FROM adjustment_batches WHERE id = $1
+	`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("adjustment batch not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch adjustment batch")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := loadBatchRequests(ctx, db, &batch); err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment batch requests")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"data": batch,
+	})
+}
+
+func approveAdjustmentBatch(c *gin.Context) {
+	decideAdjustmentBatch(c, models.DecisionApprove)
+}
+
+func rejectAdjustmentBatch(c *gin.Context) {
+	decideAdjustmentBatch(c, models.DecisionReject)
+}
+
+// decideAdjustmentBatch works like decideAdjustmentRequest for a whole batch.
+// Once the batch has enough approvals every row is applied in one transaction;
+// if any row fails (for example a quantity would go negative) none are applied.
+func decideAdjustmentBatch(c *gin.Context, decision string) {
+	id, ok := parseIDParam(c, "id", "adjustment batch ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"batch_id":   id,
+		"decision":   decision,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var body models.AdjustmentDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logger.WithError(err).Warn("Invalid decision payload")
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	rolledBack := false
+	defer func() {
+		if !rolledBack {
+			_ = tx.Rollback()
+		}
+	}()
+
+	batch, err := scanAdjustmentBatch(tx.QueryRowContext(ctx, `
+		SELECT `+adjustmentBatchColumns+` FROM adjustment_batches WHERE id = $1 FOR UPDATE
+	`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("adjustment batch not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch adjustment batch")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	if batch.Status != models.AdjustmentPending {
+		response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("adjustment batch is already "+batch.Status))
+		return
+	}
+	if batch.InitiatedBy == actor {
+		response.WriteJson(c.Writer, http.StatusForbidden, response.ErrorResponse("initiator cannot approve or reject their own adjustment batch"))
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO adjustment_approvals (request_id, approver, decision, comment, created_at)
+		SELECT id, $2, $3, $4, NOW() FROM adjustment_requests WHERE batch_id = $1
+	`, id, actor, decision, body.Comment); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("approver has already decided on this batch"))
+			return
+		}
+		logger.WithError(err).Error("Failed to record decision")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	if err := loadBatchRequests(ctx, tx, &batch); err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment batch requests")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	switch decision {
+	case models.DecisionReject:
+		batch.Status = models.AdjustmentRejected
+	case models.DecisionApprove:
+		if len(batch.Approvers) >= batch.RequiredApprovals {
+			for i := range batch.Requests {
+				req := &batch.Requests[i]
+				adj, err := applyAdjustment(ctx, tx, *req)
+				if err != nil {
+					writeError(c, logger.WithField("adjustment_request_id", req.ID), wrapBatchRowError(err, req), "Failed to apply batch adjustment")
+					return
+				}
+				req.Status = models.AdjustmentApproved
+				req.AdjustmentID = &adj.ID
+			}
+			batch.Status = models.AdjustmentApproved
+		}
+	}
+
+	if batch.Status != models.AdjustmentPending {
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE adjustment_batches SET status = $1, decided_at = NOW() WHERE id = $2
+			RETURNING decided_at
+		`, batch.Status, id).Scan(&batch.DecidedAt); err != nil {
+			logger.WithError(err).Error("Failed to update adjustment batch")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		for i := range batch.Requests {
+			req := &batch.Requests[i]
+			req.Status = batch.Status
+			if err := tx.QueryRowContext(ctx, `
+				UPDATE adjustment_requests SET status = $1, adjustment_id = $2, decided_at = NOW() WHERE id = $3
+				RETURNING decided_at
+			`, req.Status, req.AdjustmentID, req.ID).Scan(&req.DecidedAt); err != nil {
+				logger.WithError(err).Error("Failed to update adjustment request")
+				response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	rolledBack = true
+
+	logger.WithFields(logrus.Fields{
+		"actor":  actor,
+		"status": batch.Status,
+	}).Info("Adjustment batch decision recorded")
+
+	message := "Approval recorded; awaiting further approval"
+	switch batch.Status {
+	case models.AdjustmentApproved:
+		message = fmt.Sprintf("Adjustment batch approved and %d adjustments applied", len(batch.Requests))
+	case models.AdjustmentRejected:
+		message = "Adjustment batch rejected"
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": message,
+		"data":    batch,
+	})
+}
+
+// wrapBatchRowError prefixes client-facing errors with the failing request so
+// the operator can find the offending CSV row.
+func wrapBatchRowError(err error, req *models.AdjustmentRequest) error {
+	if apiErr, ok := err.(*apiError); ok {
+		return &apiError{
+			status: apiErr.status,
+			msg:    fmt.Sprintf("adjustment request %d (reward %d): %s; no adjustments in the batch were applied", req.ID, req.RewardID, apiErr.msg),
+		}
+	}
+	return err
+}
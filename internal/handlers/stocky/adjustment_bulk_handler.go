@@ -0,0 +1,432 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxBulkAdjustmentRows = 5000
+
+	bulkModeValidate = "validate"
+	bulkModeCommit   = "commit"
+)
+
+var bulkAdjustmentColumns = []string{"reward_id", "type", "unit_basis", "delta_quantity", "delta_amount", "reason", "reason_code", "ticket_ref"}
+
+type bulkAdjustmentRow struct {
+	Line     int
+	Req      models.AdjustmentRequest
+	INRValue float64
+	Priced   bool
+}
+
+// parseBulkAdjustmentCSV reads an adjustment CSV. Problems with individual
+// rows are collected and returned alongside the rows that parsed; an error is
+// only returned when the file as a whole is unusable.
+func parseBulkAdjustmentCSV(r io.Reader) ([]bulkAdjustmentRow, []models.BulkAdjustmentRowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, badRequest("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, badRequest("invalid CSV: " + err.Error())
+	}
+
+	index := make(map[string]int, len(header))
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+	for _, col := range bulkAdjustmentColumns {
+		if _, ok := index[col]; !ok {
+			return nil, nil, badRequest("CSV header must contain columns: " + strings.Join(bulkAdjustmentColumns, ", "))
+		}
+	}
+
+	var rows []bulkAdjustmentRow
+	var rowErrors []models.BulkAdjustmentRowError
+	line := 1
+	// dataRows counts the rows that are either parsed or reported as errors;
+	// blank lines do not count towards the limit.
+	dataRows := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err == nil && strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		dataRows++
+		if dataRows > maxBulkAdjustmentRows {
+			return nil, nil, badRequest(fmt.Sprintf("CSV exceeds the maximum of %d rows", maxBulkAdjustmentRows))
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: line, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+
+		field := func(name string) string {
+			i := index[name]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		rowError := func(name, msg string) {
+			rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: line, Field: name, Error: msg})
+		}
+
+		ok := true
+		rewardID, err := strconv.Atoi(field("reward_id"))
+		if err != nil || rewardID <= 0 {
+			rowError("reward_id", "must be a positive integer")
+			ok = false
+		}
+		deltaQty, err := parseOptionalFloat(field("delta_quantity"))
+		if err != nil {
+			rowError("delta_quantity", "must be a number")
+			ok = false
+		}
+		deltaAmount, err := parseOptionalFloat(field("delta_amount"))
+		if err != nil {
+			rowError("delta_amount", "must be a number")
+			ok = false
+		}
+		if !ok {
+			continue
+		}
+
+		req := models.AdjustmentRequest{
+			RewardID:           rewardID,
+			AdjustmentType:     field("type"),
+			UnitBasis:          field("unit_basis"),
+			InputDeltaQuantity: utils.RoundQuantity(deltaQty),
+			DeltaAmount:        utils.RoundAmount(deltaAmount),
+			Reason:             field("reason"),
+			ReasonCode:         strings.ToUpper(field("reason_code")),
+			TicketRef:          field("ticket_ref"),
+		}
+		if err := validateAdjustmentInput(req.AdjustmentType, req.UnitBasis, req.InputDeltaQuantity, req.DeltaAmount); err != nil {
+			rowError("", err.Error())
+			continue
+		}
+		if req.ReasonCode == "" {
+			rowError("reason_code", "reason_code is required")
+			continue
+		}
+		if err := validateTicketRef(req.TicketRef); err != nil {
+			rowError("ticket_ref", err.Error())
+			continue
+		}
+		rows = append(rows, bulkAdjustmentRow{Line: line, Req: req})
+	}
+
+	if len(rows) == 0 && len(rowErrors) == 0 {
+		return nil, nil, badRequest("CSV file has no data rows")
+	}
+	return rows, rowErrors, nil
+}
+
+// parseOptionalFloat parses a numeric CSV field, treating an empty field as
+// zero. NaN and infinities are rejected, since strconv accepts them.
+func parseOptionalFloat(v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", v)
+	}
+	return f, nil
+}
+
+// bulkHolding is a reward lot as the bulk checks see it, with the current
+// price of the lot's instrument when one is known.
+type bulkHolding struct {
+	rewardHolding
+	price sql.NullFloat64
+}
+
+// validateBulkAdjustments loads the reason codes and holdings the rows refer
+// to and checks the rows against them with checkBulkAdjustments.
+func validateBulkAdjustments(ctx context.Context, rows []bulkAdjustmentRow) ([]models.BulkAdjustmentRowError, error) {
+	ids := make([]int, 0, len(rows))
+	codes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.Req.RewardID)
+		codes = append(codes, row.Req.ReasonCode)
+	}
+
+	reasonCodes, err := loadReasonCodes(ctx, db, codes)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := loadBulkHoldings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return checkBulkAdjustments(rows, reasonCodes, holdings), nil
+}
+
+func loadBulkHoldings(ctx context.Context, rewardIDs []int) (map[int]*bulkHolding, error) {
+	dbRows, err := db.QueryContext(ctx, `
+		SELECT l.reward_id, l.base_quantity, l.unit_multiplier, l.stock_symbol, sp.price
+		FROM reward_lots l
+		LEFT JOIN stock_prices sp ON sp.instrument_id = l.instrument_id
+		WHERE l.reward_id = ANY($1)
+	`, pq.Array(rewardIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer dbRows.Close()
+
+	holdings := make(map[int]*bulkHolding)
+	for dbRows.Next() {
+		var id int
+		h := &bulkHolding{}
+		if err := dbRows.Scan(&id, &h.Quantity, &h.Multiplier, &h.StockSymbol, &h.price); err != nil {
+			return nil, err
+		}
+		holdings[id] = h
+	}
+	return holdings, dbRows.Err()
+}
+
+// checkBulkAdjustments applies the rows in file order to holdings, so several
+// corrections to the same reward are judged on their combined effect. It
+// converts each row to pre-event units the same way as single adjustments and
+// fills in its INR value. holdings is updated as rows are applied.
+func checkBulkAdjustments(rows []bulkAdjustmentRow, reasonCodes map[string]models.AdjustmentReasonCode, holdings map[int]*bulkHolding) []models.BulkAdjustmentRowError {
+	var rowErrors []models.BulkAdjustmentRowError
+	for i := range rows {
+		row := &rows[i]
+		rc, ok := reasonCodes[row.Req.ReasonCode]
+		if err := checkReasonCode(rc, ok, row.Req.AdjustmentType, row.Req.InputDeltaQuantity, row.Req.DeltaAmount); err != nil {
+			rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: row.Line, Field: "reason_code", Error: err.Error()})
+			continue
+		}
+		h, ok := holdings[row.Req.RewardID]
+		if !ok {
+			rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: row.Line, Field: "reward_id", Error: "reward not found"})
+			continue
+		}
+		if h.Multiplier <= 0 {
+			rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: row.Line, Field: "reward_id", Error: "reward has a non-positive corporate action multiplier"})
+			continue
+		}
+		row.Req.UnitMultiplier = h.Multiplier
+		row.Req.DeltaQuantity = h.toPreEventUnits(row.Req.UnitBasis, row.Req.InputDeltaQuantity)
+		if err := h.checkNonNegative(row.Req.DeltaQuantity); err != nil {
+			rowErrors = append(rowErrors, models.BulkAdjustmentRowError{Row: row.Line, Field: "delta_quantity", Error: err.Error()})
+			continue
+		}
+		h.Quantity += row.Req.DeltaQuantity
+
+		row.INRValue = math.Abs(row.Req.DeltaAmount)
+		row.Priced = row.Req.DeltaQuantity == 0 || h.price.Valid
+		if row.Req.DeltaQuantity != 0 && h.price.Valid {
+			row.INRValue += math.Abs(row.Req.DeltaQuantity*h.Multiplier) * h.price.Float64
+		}
+		row.INRValue = utils.RoundAmount(row.INRValue)
+	}
+	return rowErrors
+}
+
+// bulkAdjustmentHandler accepts a multipart CSV upload in the "file" field.
+// With mode=validate (the default) it only reports per-row errors. With
+// mode=commit it stores every row as one pending batch, or nothing at all if
+// any row is invalid; the batch is applied atomically once approved.
+func bulkAdjustmentHandler(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	mode := c.DefaultQuery("mode", bulkModeValidate)
+	if mode != bulkModeValidate && mode != bulkModeCommit {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid mode. must be one of: validate, commit"))
+		return
+	}
+
+	var actor string
+	if mode == bulkModeCommit {
+		var ok bool
+		if actor, ok = requireActor(c); !ok {
+			return
+		}
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("multipart field \"file\" with the CSV is required"))
+		return
+	}
+	defer file.Close()
+
+	rows, rowErrors, err := parseBulkAdjustmentCSV(file)
+	if err != nil {
+		writeError(c, logger, err, "Failed to read adjustment CSV")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if len(rows) > 0 {
+		dbErrors, err := validateBulkAdjustments(ctx, rows)
+		if err != nil {
+			logger.WithError(err).Error("Failed to validate bulk adjustments")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		rowErrors = append(rowErrors, dbErrors...)
+	}
+
+	totalINR := 0.0
+	priced := true
+	for _, row := range rows {
+		totalINR += row.INRValue
+		priced = priced && row.Priced
+	}
+	totalINR = utils.RoundAmount(totalINR)
+	requiredApprovals := requiredApprovalsFor(totalINR, priced)
+
+	summary := map[string]interface{}{
+		"mode":               mode,
+		"file_name":          header.Filename,
+		"row_count":          len(rows) + countErrorRows(rowErrors, rows),
+		"valid":              len(rowErrors) == 0,
+		"errors":             utils.OrEmpty(rowErrors),
+		"total_inr_value":    totalINR,
+		"required_approvals": requiredApprovals,
+	}
+
+	if mode == bulkModeValidate {
+		response.WriteJson(c.Writer, http.StatusOK, summary)
+		return
+	}
+	if len(rowErrors) > 0 {
+		summary["message"] = "CSV has invalid rows; nothing was committed"
+		response.WriteJson(c.Writer, http.StatusUnprocessableEntity, summary)
+		return
+	}
+
+	batch, err := createAdjustmentBatch(ctx, header.Filename, rows, totalINR, requiredApprovals, actor)
+	if err != nil {
+		writeError(c, logger, err, "Failed to create adjustment batch")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"batch_id":     batch.ID,
+		"row_count":    batch.RowCount,
+		"initiated_by": actor,
+	}).Info("Adjustment batch created")
+
+	response.WriteJson(c.Writer, http.StatusAccepted, map[string]interface{}{
+		"message": "Adjustment batch created and awaiting approval",
+		"data":    batch,
+	})
+}
+
+// countErrorRows counts rows that failed parsing and therefore are not in rows.
+func countErrorRows(rowErrors []models.BulkAdjustmentRowError, rows []bulkAdjustmentRow) int {
+	parsed := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		parsed[row.Line] = true
+	}
+	seen := make(map[int]bool)
+	for _, e := range rowErrors {
+		if !parsed[e.Row] {
+			seen[e.Row] = true
+		}
+	}
+	return len(seen)
+}
+
+func createAdjustmentBatch(ctx context.Context, fileName string, rows []bulkAdjustmentRow, totalINR float64, requiredApprovals int, actor string) (models.AdjustmentBatch, error) {
+	var batch models.AdjustmentBatch
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return batch, err
+	}
+	rolledBack := false
+	defer func() {
+		if !rolledBack {
+			_ = tx.Rollback()
+		}
+	}()
+
+	batch, err = scanAdjustmentBatch(tx.QueryRowContext(ctx, `
+		INSERT INTO adjustment_batches (file_name, row_count, total_inr_value, required_approvals, status, initiated_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING `+adjustmentBatchColumns,
+		fileName, len(rows), totalINR, requiredApprovals, models.AdjustmentPending, actor))
+	if err != nil {
+		return batch, err
+	}
+
+	for _, row := range rows {
+		req, err := scanAdjustmentRequest(tx.QueryRowContext(ctx, `
+			INSERT INTO adjustment_requests
+				(reward_id, adjustment_type, unit_basis, input_delta_quantity, unit_multiplier, delta_quantity, delta_amount,
+				 reason, reason_code, ticket_ref, inr_value, required_approvals, status, initiated_by, batch_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+			RETURNING `+adjustmentRequestColumns,
+			row.Req.RewardID,
+			row.Req.AdjustmentType,
+			row.Req.UnitBasis,
+			row.Req.InputDeltaQuantity,
+			row.Req.UnitMultiplier,
+			row.Req.DeltaQuantity,
+			row.Req.DeltaAmount,
+			row.Req.Reason,
+			row.Req.ReasonCode,
+			row.Req.TicketRef,
+			row.INRValue,
+			requiredApprovals,
+			models.AdjustmentPending,
+			actor,
+			batch.ID))
+		if err != nil {
+			return batch, err
+		}
+		req.Approvals = []models.AdjustmentApproval{}
+		batch.Requests = append(batch.Requests, req)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return batch, err
+	}
+	rolledBack = true
+
+	batch.Approvers = []string{}
+	return batch, nil
+}
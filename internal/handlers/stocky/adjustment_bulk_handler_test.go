@@ -0,0 +1,350 @@
+package stocky
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+)
+
+// validBulkRow is a CSV row that passes parsing; cases replace fields in it.
+var validBulkRow = map[string]string{
+	"reward_id":      "1",
+	"type":           models.Manual_Correction,
+	"unit_basis":     models.UnitsCurrent,
+	"delta_quantity": "2",
+	"delta_amount":   "",
+	"reason":         "typo in grant",
+	"reason_code":    "DATA_FIX",
+	"ticket_ref":     "OPS-1",
+}
+
+// bulkLine formats row as a CSV line, taking fields it lacks from validBulkRow.
+func bulkLine(row map[string]string) string {
+	fields := make([]string, len(bulkAdjustmentColumns))
+	for i, col := range bulkAdjustmentColumns {
+		v, ok := row[col]
+		if !ok {
+			v = validBulkRow[col]
+		}
+		fields[i] = v
+	}
+	return strings.Join(fields, ",") + "\n"
+}
+
+// bulkCSV builds a file with the standard header and one line per row.
+func bulkCSV(rows ...map[string]string) string {
+	s := strings.Join(bulkAdjustmentColumns, ",") + "\n"
+	for _, row := range rows {
+		s += bulkLine(row)
+	}
+	return s
+}
+
+func TestParseBulkAdjustmentCSV(t *testing.T) {
+	type rowError struct {
+		Row   int
+		Field string
+	}
+	tests := []struct {
+		name       string
+		file       string
+		wantLines  []int
+		wantErrors []rowError
+	}{
+		{
+			name:      "valid rows",
+			file:      bulkCSV(validBulkRow, map[string]string{"type": models.Fee_Refund, "delta_quantity": "", "delta_amount": "150"}),
+			wantLines: []int{2, 3},
+		},
+		{
+			name: "header in any case and order, with a BOM and extra columns",
+			file: "\ufeffReason , DELTA_AMOUNT,Type,extra,reward_id,Unit_Basis,delta_quantity,Ticket_Ref,REASON_CODE\n" +
+				"typo,,manual_correction,x,1,current,2,OPS-1,data_fix\n",
+			wantLines: []int{2},
+		},
+		{
+			name:      "blank rows are skipped but keep line numbers",
+			file:      bulkCSV(validBulkRow) + ",,,,,,,\n" + bulkLine(validBulkRow),
+			wantLines: []int{2, 4},
+		},
+		{
+			name:      "short record leaves the missing fields empty",
+			file:      "reward_id,type,unit_basis,delta_quantity,reason_code,ticket_ref,delta_amount,reason\n1,manual_correction,current,2,DATA_FIX,OPS-1\n",
+			wantLines: []int{2},
+		},
+		{
+			name:       "short record without deltas",
+			file:       "reward_id,type,unit_basis,delta_quantity,delta_amount,reason,reason_code,ticket_ref\n1,manual_correction,current\n",
+			wantErrors: []rowError{{2, ""}},
+		},
+		{
+			name:       "every unparsable field is reported",
+			file:       bulkCSV(map[string]string{"reward_id": "x", "delta_quantity": "abc", "delta_amount": "1e"}),
+			wantErrors: []rowError{{2, "reward_id"}, {2, "delta_quantity"}, {2, "delta_amount"}},
+		},
+		{
+			name:       "NaN and infinite deltas",
+			file:       bulkCSV(map[string]string{"delta_quantity": "NaN", "delta_amount": "-Inf"}, map[string]string{"delta_quantity": "+Infinity"}),
+			wantErrors: []rowError{{2, "delta_quantity"}, {2, "delta_amount"}, {3, "delta_quantity"}},
+		},
+		{
+			name:       "non-positive reward id",
+			file:       bulkCSV(map[string]string{"reward_id": "0"}),
+			wantErrors: []rowError{{2, "reward_id"}},
+		},
+		{
+			name:       "invalid type",
+			file:       bulkCSV(map[string]string{"type": "gift"}),
+			wantErrors: []rowError{{2, ""}},
+		},
+		{
+			name:       "deltas that round to zero",
+			file:       bulkCSV(map[string]string{"delta_quantity": "0.0000001", "delta_amount": "0.00001"}),
+			wantErrors: []rowError{{2, ""}},
+		},
+		{
+			name:       "malformed line does not stop the file",
+			file:       bulkCSV(validBulkRow) + bulkLine(map[string]string{"delta_quantity": `1 "x"`}) + bulkLine(validBulkRow),
+			wantLines:  []int{2, 4},
+			wantErrors: []rowError{{3, ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := parseBulkAdjustmentCSV(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var lines []int
+			for _, row := range rows {
+				lines = append(lines, row.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("parsed lines = %v, want %v", lines, tt.wantLines)
+			}
+			var errs []rowError
+			for _, e := range rowErrors {
+				errs = append(errs, rowError{e.Row, e.Field})
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrors) {
+				t.Errorf("row errors = %+v, want %+v", rowErrors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestParseBulkAdjustmentCSVValues(t *testing.T) {
+	rows, _, err := parseBulkAdjustmentCSV(strings.NewReader(bulkCSV(map[string]string{
+		"reward_id": " 42 ", "delta_quantity": "1.23456789", "delta_amount": "-10.123456", "reason": " late grant ",
+		"reason_code": "data_fix",
+	})))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("got %d rows, err %v", len(rows), err)
+	}
+	req := rows[0].Req
+	if req.RewardID != 42 || req.AdjustmentType != models.Manual_Correction || req.UnitBasis != models.UnitsCurrent ||
+		req.Reason != "late grant" || req.ReasonCode != "DATA_FIX" || req.TicketRef != "OPS-1" {
+		t.Errorf("request = %+v", req)
+	}
+	if req.InputDeltaQuantity != 1.234568 || req.DeltaAmount != -10.1235 {
+		t.Errorf("deltas = %v, %v, want them rounded to 1.234568, -10.1235", req.InputDeltaQuantity, req.DeltaAmount)
+	}
+}
+
+func TestParseBulkAdjustmentCSVRejectsFile(t *testing.T) {
+	tooMany := bulkCSV() + strings.Repeat(bulkLine(validBulkRow), maxBulkAdjustmentRows+1)
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "empty", file: "", wantErr: "empty"},
+		{name: "missing column", file: "reward_id,type,delta_quantity,delta_amount,reason,reason_code,ticket_ref\n1,manual_correction,1,,typo,DATA_FIX,OPS-1\n", wantErr: "must contain columns"},
+		{name: "header only", file: bulkCSV(), wantErr: "no data rows"},
+		{name: "only blank lines", file: bulkCSV() + "\n,,,,\n", wantErr: "no data rows"},
+		{name: "too many rows", file: tooMany, wantErr: "exceeds the maximum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseBulkAdjustmentCSV(strings.NewReader(tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBulkAdjustmentCSVRowLimit(t *testing.T) {
+	// Blank lines are not rows; a full file padded with them is accepted.
+	full := bulkCSV() + strings.Repeat(bulkLine(validBulkRow)+",,,,,,,\n", maxBulkAdjustmentRows)
+	rows, _, err := parseBulkAdjustmentCSV(strings.NewReader(full))
+	if err != nil || len(rows) != maxBulkAdjustmentRows {
+		t.Fatalf("got %d rows, err %v; want %d rows", len(rows), err, maxBulkAdjustmentRows)
+	}
+
+	// Rows that fail to parse still count.
+	over := bulkCSV() + strings.Repeat(bulkLine(validBulkRow), maxBulkAdjustmentRows) + bulkLine(map[string]string{"reward_id": "x"})
+	if _, _, err := parseBulkAdjustmentCSV(strings.NewReader(over)); err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Fatalf("err = %v, want the row limit error", err)
+	}
+}
+
+func TestCheckBulkAdjustments(t *testing.T) {
+	reasonCodes := map[string]models.AdjustmentReasonCode{
+		"DATA_FIX": {
+			Code:         "DATA_FIX",
+			AllowedTypes: []string{models.Manual_Correction, models.Fee_Refund},
+			QuantitySign: models.SignAny,
+			AmountSign:   models.SignAny,
+			Active:       true,
+		},
+	}
+	// Reward 1 holds 5 pre-event units after a 2:1 split, priced at 100 a
+	// unit; reward 2 has a broken multiplier and reward 3 has no price.
+	newHoldings := func() map[int]*bulkHolding {
+		return map[int]*bulkHolding{
+			1: {rewardHolding: rewardHolding{Quantity: 5, Multiplier: 2, StockSymbol: "TCS"}, price: sql.NullFloat64{Float64: 100, Valid: true}},
+			2: {rewardHolding: rewardHolding{Quantity: 1, Multiplier: 0, StockSymbol: "INFY"}},
+			3: {rewardHolding: rewardHolding{Quantity: 3, Multiplier: 1, StockSymbol: "WIPRO"}},
+		}
+	}
+	row := func(line, rewardID int, unitBasis string, qty float64) bulkAdjustmentRow {
+		return bulkAdjustmentRow{Line: line, Req: models.AdjustmentRequest{
+			RewardID:           rewardID,
+			AdjustmentType:     models.Manual_Correction,
+			UnitBasis:          unitBasis,
+			InputDeltaQuantity: qty,
+			ReasonCode:         "DATA_FIX",
+		}}
+	}
+	unknownCode := row(2, 1, models.UnitsPreEvent, 1)
+	unknownCode.Req.ReasonCode = "NOPE"
+
+	type rowError struct {
+		Row   int
+		Field string
+	}
+	tests := []struct {
+		name       string
+		rows       []bulkAdjustmentRow
+		wantErrors []rowError
+	}{
+		{
+			name: "rows on one reward that together empty the lot",
+			rows: []bulkAdjustmentRow{row(2, 1, models.UnitsPreEvent, -4), row(3, 1, models.UnitsCurrent, -2)},
+		},
+		{
+			name:       "rows on one reward that together go negative",
+			rows:       []bulkAdjustmentRow{row(2, 1, models.UnitsPreEvent, -4), row(3, 1, models.UnitsCurrent, -4)},
+			wantErrors: []rowError{{3, "delta_quantity"}},
+		},
+		{
+			name:       "a rejected row does not count towards the combined effect",
+			rows:       []bulkAdjustmentRow{row(2, 1, models.UnitsPreEvent, -6), row(3, 1, models.UnitsPreEvent, -5)},
+			wantErrors: []rowError{{2, "delta_quantity"}},
+		},
+		{
+			name:       "rows on different rewards are checked separately",
+			rows:       []bulkAdjustmentRow{row(2, 1, models.UnitsPreEvent, -5), row(3, 3, models.UnitsPreEvent, -3), row(4, 3, models.UnitsPreEvent, -1)},
+			wantErrors: []rowError{{4, "delta_quantity"}},
+		},
+		{
+			name:       "unknown reason code",
+			rows:       []bulkAdjustmentRow{unknownCode},
+			wantErrors: []rowError{{2, "reason_code"}},
+		},
+		{
+			name:       "unknown reward",
+			rows:       []bulkAdjustmentRow{row(2, 9, models.UnitsPreEvent, 1)},
+			wantErrors: []rowError{{2, "reward_id"}},
+		},
+		{
+			name:       "non-positive multiplier",
+			rows:       []bulkAdjustmentRow{row(2, 2, models.UnitsPreEvent, 1)},
+			wantErrors: []rowError{{2, "reward_id"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []rowError
+			for _, e := range checkBulkAdjustments(tt.rows, reasonCodes, newHoldings()) {
+				errs = append(errs, rowError{e.Row, e.Field})
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrors) {
+				t.Errorf("row errors = %+v, want %+v", errs, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestCheckBulkAdjustmentsValues(t *testing.T) {
+	reasonCodes := map[string]models.AdjustmentReasonCode{
+		"DATA_FIX": {Code: "DATA_FIX", AllowedTypes: []string{models.Manual_Correction}, QuantitySign: models.SignAny, AmountSign: models.SignAny, Active: true},
+	}
+	holdings := map[int]*bulkHolding{
+		1: {rewardHolding: rewardHolding{Quantity: 5, Multiplier: 2}, price: sql.NullFloat64{Float64: 100, Valid: true}},
+		3: {rewardHolding: rewardHolding{Quantity: 3, Multiplier: 1}},
+	}
+	rows := []bulkAdjustmentRow{
+		{Line: 2, Req: models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Manual_Correction, UnitBasis: models.UnitsCurrent, InputDeltaQuantity: -3, DeltaAmount: 10, ReasonCode: "DATA_FIX"}},
+		{Line: 3, Req: models.AdjustmentRequest{RewardID: 3, AdjustmentType: models.Manual_Correction, UnitBasis: models.UnitsPreEvent, InputDeltaQuantity: 1, ReasonCode: "DATA_FIX"}},
+		{Line: 4, Req: models.AdjustmentRequest{RewardID: 3, AdjustmentType: models.Manual_Correction, UnitBasis: models.UnitsPreEvent, DeltaAmount: -25, ReasonCode: "DATA_FIX"}},
+	}
+	if errs := checkBulkAdjustments(rows, reasonCodes, holdings); len(errs) != 0 {
+		t.Fatalf("unexpected row errors %+v", errs)
+	}
+
+	if got := rows[0].Req; got.UnitMultiplier != 2 || got.DeltaQuantity != -1.5 {
+		t.Errorf("row 2: multiplier %v, pre-event delta %v; want 2, -1.5", got.UnitMultiplier, got.DeltaQuantity)
+	}
+	if rows[0].INRValue != 310 || !rows[0].Priced {
+		t.Errorf("row 2: INR value %v, priced %v; want 310, true", rows[0].INRValue, rows[0].Priced)
+	}
+	if rows[1].Priced {
+		t.Error("row 3: units without a price should not be priced")
+	}
+	if rows[2].INRValue != 25 || !rows[2].Priced {
+		t.Errorf("row 4: INR value %v, priced %v; want 25, true", rows[2].INRValue, rows[2].Priced)
+	}
+	if holdings[1].Quantity != 3.5 || holdings[3].Quantity != 4 {
+		t.Errorf("holdings after the rows = %v, %v; want 3.5, 4", holdings[1].Quantity, holdings[3].Quantity)
+	}
+}
+
+func TestCountErrorRows(t *testing.T) {
+	rows := []bulkAdjustmentRow{{Line: 2}, {Line: 4}}
+	rowErrors := []models.BulkAdjustmentRowError{
+		{Row: 3, Field: "reward_id"},
+		{Row: 3, Field: "delta_quantity"},
+		{Row: 4, Field: "reward_id"}, // parsed, failed against the database
+		{Row: 5},
+	}
+	if got := countErrorRows(rowErrors, rows); got != 2 {
+		t.Errorf("countErrorRows = %d, want 2", got)
+	}
+}
+
+func TestRequiredApprovalsFor(t *testing.T) {
+	saved := appCfg.Adjustments.DualApprovalThresholdINR
+	appCfg.Adjustments.DualApprovalThresholdINR = 1000
+	defer func() { appCfg.Adjustments.DualApprovalThresholdINR = saved }()
+
+	tests := []struct {
+		value  float64
+		priced bool
+		want   int
+	}{
+		{value: 0, priced: true, want: 1},
+		{value: 1000, priced: true, want: 1},
+		{value: 1000.01, priced: true, want: 2},
+		{value: 0, priced: false, want: 2},
+	}
+	for _, tt := range tests {
+		if got := requiredApprovalsFor(tt.value, tt.priced); got != tt.want {
+			t.Errorf("requiredApprovalsFor(%v, %v) = %d, want %d", tt.value, tt.priced, got, tt.want)
+		}
+	}
+}
@@ -3,17 +3,24 @@ package stocky
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/LoganX64/stocky-api/internal/storage/models"
 	"github.com/LoganX64/stocky-api/internal/utils"
 	"github.com/LoganX64/stocky-api/internal/utils/response"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+// adjustmentHandler records an adjustment as a pending request. Nothing is
+// written to the adjustments table or the ledger until the request has been
+// approved by someone other than the initiator (see adjustment_request_handler.go).
 func adjustmentHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	rewardID, err := strconv.Atoi(idParam)
@@ -27,6 +34,11 @@ func adjustmentHandler(c *gin.Context) {
 		"reward_id":  rewardID,
 	})
 
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
 	var req models.Adjustment
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.WithError(err).Warn("Invalid adjustment payload")
@@ -34,28 +46,165 @@ func adjustmentHandler(c *gin.Context) {
 		return
 	}
 
-	validTypes := map[string]bool{
-		models.Reward_Reversal:   true,
-		models.Fee_Refund:        true,
-		models.Manual_Correction: true,
+	req.DeltaQuantity = utils.RoundQuantity(req.DeltaQuantity)
+	req.DeltaAmount = utils.RoundAmount(req.DeltaAmount)
+
+	req.ReasonCode = strings.ToUpper(strings.TrimSpace(req.ReasonCode))
+	req.TicketRef = strings.TrimSpace(req.TicketRef)
+
+	if err := validateAdjustmentInput(req.AdjustmentType, req.UnitBasis, req.DeltaQuantity, req.DeltaAmount); err != nil {
+		writeError(c, logger, err, "Invalid adjustment")
+		return
 	}
-	if _, ok := validTypes[req.AdjustmentType]; !ok {
-		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid adjustment type. must be one of: reward_reversal, fee_refund, manual_correction"))
+	if err := validateTicketRef(req.TicketRef); err != nil {
+		writeError(c, logger, err, "Invalid adjustment")
 		return
 	}
 
-	req.DeltaQuantity = utils.RoundQuantity(req.DeltaQuantity)
-	req.DeltaAmount = utils.RoundAmount(req.DeltaAmount)
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	pending, err := createAdjustmentRequest(ctx, models.AdjustmentRequest{
+		RewardID:           rewardID,
+		AdjustmentType:     req.AdjustmentType,
+		UnitBasis:          req.UnitBasis,
+		InputDeltaQuantity: req.DeltaQuantity,
+		DeltaAmount:        req.DeltaAmount,
+		Reason:             req.Reason,
+		ReasonCode:         req.ReasonCode,
+		TicketRef:          req.TicketRef,
+		InitiatedBy:        actor,
+	})
 	if err != nil {
-		logger.WithError(err).Error("Failed to begin transaction")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		writeError(c, logger, err, "Failed to create adjustment request")
 		return
 	}
+
+	logger.WithFields(logrus.Fields{
+		"adjustment_request_id": pending.ID,
+		"initiated_by":          actor,
+		"required_approvals":    pending.RequiredApprovals,
+	}).Info("Adjustment request created")
+
+	response.WriteJson(c.Writer, http.StatusAccepted, map[string]interface{}{
+		"message":  "Adjustment request created and awaiting approval",
+		"rewardId": rewardID,
+		"data":     pending,
+	})
+}
+
+var validAdjustmentTypes = map[string]bool{
+	models.Reward_Reversal:   true,
+	models.Fee_Refund:        true,
+	models.Manual_Correction: true,
+}
+
+// validateAdjustmentInput checks the parts of an adjustment that do not need
+// the database. Deltas are expected to be rounded already.
+func validateAdjustmentInput(adjustmentType, unitBasis string, deltaQty, deltaAmount float64) error {
+	if _, ok := validAdjustmentTypes[adjustmentType]; !ok {
+		return badRequest("invalid adjustment type. must be one of: reward_reversal, fee_refund, manual_correction")
+	}
+	if unitBasis != models.UnitsPreEvent && unitBasis != models.UnitsCurrent {
+		return badRequest("unit_basis is required. must be one of: pre_event, current")
+	}
+	if math.IsNaN(deltaQty) || math.IsInf(deltaQty, 0) || math.IsNaN(deltaAmount) || math.IsInf(deltaAmount, 0) {
+		return badRequest("delta_quantity and delta_amount must be finite numbers")
+	}
+	if deltaQty == 0 && deltaAmount == 0 {
+		return badRequest("delta_quantity or delta_amount must be non-zero")
+	}
+	return nil
+}
+
+// rewardHolding is a reward lot as user_portfolio sees it: Quantity is the
+// reward quantity plus applied adjustments in pre-event units, Multiplier the
+// corporate-action multiplier to current units, and StockSymbol the symbol the
+// lot is held in after any merger.
+type rewardHolding struct {
+	Quantity    float64
+	Multiplier  float64
+	StockSymbol string
+	// AsOf is the business date the lot's adjustments take effect on: its
+	// grant date, or the effective date of the last corporate action posted
+	// to it, whose units they are in.
+	AsOf string
+}
+
+func (h rewardHolding) CurrentQuantity() float64 {
+	return utils.RoundQuantity(h.Quantity * h.Multiplier)
+}
+
+// toPreEventUnits converts an entered quantity to the pre-event units that
+// adjustments are stored in.
+func (h rewardHolding) toPreEventUnits(unitBasis string, qty float64) float64 {
+	if unitBasis == models.UnitsCurrent {
+		return utils.RoundQuantity(qty / h.Multiplier)
+	}
+	return qty
+}
+
+// loadRewardHolding reads a reward lot with the multiplier and symbol of the
+// corporate actions posted to it. With lock set the reward row is locked for
+// the rest of the transaction, which also keeps a corporate action from being
+// posted to the lot in between.
+func loadRewardHolding(ctx context.Context, q querier, rewardID int, lock bool) (rewardHolding, error) {
+	var h rewardHolding
+	var quantity float64
+
+	query := `
+		SELECT quantity,
+			reward_lot_multiplier(id),
+			reward_lot_symbol(id),
+			reward_lot_as_of(id)::text
+		FROM rewards WHERE id=$1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	err := q.QueryRowContext(ctx, query, rewardID).Scan(&quantity, &h.Multiplier, &h.StockSymbol, &h.AsOf)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return h, badRequest("reward not found")
+		}
+		return h, err
+	}
+	if h.Multiplier <= 0 {
+		return h, badRequest("reward has a non-positive corporate action multiplier; check its stock events")
+	}
+
+	var totalDeltaQty float64
+	err = q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(delta_quantity),0) FROM adjustments WHERE reward_id=$1
+	`, rewardID).Scan(&totalDeltaQty)
+	if err != nil {
+		return h, err
+	}
+	h.Quantity = quantity + totalDeltaQty
+	return h, nil
+}
+
+// checkNonNegative rejects a pre-event delta that would take the lot below
+// zero, reporting the holding in both unit bases.
+func (h rewardHolding) checkNonNegative(deltaQty float64) error {
+	if h.Quantity+deltaQty < 0 {
+		return badRequest(fmt.Sprintf("adjustment would make quantity negative (holding %.6f pre-event units, %.6f current units)",
+			h.Quantity, h.CurrentQuantity()))
+	}
+	return nil
+}
+
+// createAdjustmentRequest validates req against the reward's current holdings
+// and stores it as a pending request, deciding how many approvals it needs.
+// req.InputDeltaQuantity is interpreted in req.UnitBasis units. For a revert
+// the original adjustment is locked and checked in the same transaction as the
+// insert, so two concurrent revert requests cannot both pass the check.
+func createAdjustmentRequest(ctx context.Context, req models.AdjustmentRequest) (models.AdjustmentRequest, error) {
+	var pending models.AdjustmentRequest
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return pending, err
+	}
 	rolledBack := false
 	defer func() {
 		if !rolledBack {
@@ -63,68 +212,240 @@ func adjustmentHandler(c *gin.Context) {
 		}
 	}()
 
-	var currentQty float64
-	err = tx.QueryRowContext(ctx, `
-		SELECT quantity FROM rewards WHERE id=$1
-	`, rewardID).Scan(&currentQty)
+	if req.RevertsAdjustmentID != nil {
+		if err := checkRevertable(ctx, tx, *req.RevertsAdjustmentID); err != nil {
+			return pending, err
+		}
+	}
+
+	if err := validateReasonCode(ctx, tx, req.ReasonCode, req.AdjustmentType, req.InputDeltaQuantity, req.DeltaAmount); err != nil {
+		return pending, err
+	}
+
+	holding, err := loadRewardHolding(ctx, tx, req.RewardID, false)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("reward not found"))
-			return
+		return pending, err
+	}
+	req.UnitMultiplier = holding.Multiplier
+	req.DeltaQuantity = holding.toPreEventUnits(req.UnitBasis, req.InputDeltaQuantity)
+
+	// Checked again at approval time, since other adjustments or corporate
+	// actions may land in between.
+	if err := holding.checkNonNegative(req.DeltaQuantity); err != nil {
+		return pending, err
+	}
+
+	currentUnits := req.DeltaQuantity * holding.Multiplier
+	inrValue, priced, err := adjustmentINRValue(ctx, holding.StockSymbol, currentUnits, req.DeltaAmount)
+	if err != nil {
+		return pending, err
+	}
+
+	requiredApprovals := requiredApprovalsFor(inrValue, priced)
+
+	pending, err = scanAdjustmentRequest(tx.QueryRowContext(ctx, `
+		INSERT INTO adjustment_requests
+			(reward_id, adjustment_type, unit_basis, input_delta_quantity, unit_multiplier, delta_quantity, delta_amount,
+			 reason, reason_code, ticket_ref, inr_value, required_approvals, status, initiated_by, reverts_adjustment_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+		RETURNING `+adjustmentRequestColumns,
+		req.RewardID,
+		req.AdjustmentType,
+		req.UnitBasis,
+		req.InputDeltaQuantity,
+		req.UnitMultiplier,
+		req.DeltaQuantity,
+		req.DeltaAmount,
+		req.Reason,
+		req.ReasonCode,
+		req.TicketRef,
+		inrValue,
+		requiredApprovals,
+		models.AdjustmentPending,
+		req.InitiatedBy,
+		req.RevertsAdjustmentID))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return pending, conflict("a revert request for this adjustment is already pending")
 		}
-		logger.WithError(err).Error("Failed to fetch reward")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
-		return
+		return pending, err
+	}
+	if err := tx.Commit(); err != nil {
+		return pending, err
 	}
+	rolledBack = true
+	pending.Approvals = []models.AdjustmentApproval{}
+	return pending, nil
+}
 
-	var totalDeltaQty float64
-	err = tx.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(delta_quantity),0) FROM adjustments WHERE reward_id=$1
-	`, rewardID).Scan(&totalDeltaQty)
+// checkRevertable locks the adjustment being reverted and rejects the revert
+// if it is itself a revert, has already been reverted, or already has a
+// revert request awaiting approval.
+func checkRevertable(ctx context.Context, tx *sql.Tx, adjustmentID int) error {
+	var revertsID sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT reverts_adjustment_id FROM adjustments WHERE id = $1 FOR UPDATE
+	`, adjustmentID).Scan(&revertsID)
+	if err == sql.ErrNoRows {
+		return badRequest("adjustment not found")
+	}
 	if err != nil {
-		logger.WithError(err).Error("Failed to fetch adjustment sum")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
-		return
+		return err
+	}
+	if revertsID.Valid {
+		return conflict(fmt.Sprintf("adjustment is a revert of adjustment %d and cannot itself be reverted", revertsID.Int64))
 	}
 
-	if currentQty+totalDeltaQty+req.DeltaQuantity < 0 {
-		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("adjustment would make quantity negative"))
-		return
+	var reverted, pending bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM adjustments WHERE reverts_adjustment_id = $1),
+			EXISTS(SELECT 1 FROM adjustment_requests WHERE reverts_adjustment_id = $1 AND status = $2)
+	`, adjustmentID, models.AdjustmentPending).Scan(&reverted, &pending); err != nil {
+		return err
+	}
+	if reverted {
+		return conflict("adjustment has already been reverted")
+	}
+	if pending {
+		return conflict("a revert request for this adjustment is already pending")
+	}
+	return nil
+}
+
+// requiredApprovalsFor returns how many distinct approvers an adjustment of
+// the given INR value needs.
+func requiredApprovalsFor(inrValue float64, priced bool) int {
+	if !priced || inrValue > appCfg.Adjustments.DualApprovalThresholdINR {
+		return 2
+	}
+	return 1
+}
+
+// adjustmentINRValue estimates the INR impact of an adjustment, used to decide
+// how many approvals it needs. Units (current units of stockSymbol) are valued
+// at the current stock price. priced is false when units are involved but no
+// price is known, in which case callers should treat the adjustment as
+// exceeding the threshold.
+func adjustmentINRValue(ctx context.Context, stockSymbol string, deltaQty, deltaAmount float64) (value float64, priced bool, err error) {
+	value = math.Abs(deltaAmount)
+	if deltaQty == 0 {
+		return utils.RoundAmount(value), true, nil
+	}
+
+	var price float64
+	err = db.QueryRowContext(ctx, `SELECT price FROM stock_prices WHERE UPPER(stock_symbol) = UPPER($1)`, stockSymbol).Scan(&price)
+	if err == sql.ErrNoRows {
+		return utils.RoundAmount(value), false, nil
 	}
+	if err != nil {
+		return 0, false, err
+	}
+	return utils.RoundAmount(value + math.Abs(deltaQty)*price), true, nil
+}
 
+// applyAdjustment writes an approved adjustment and its ledger entries inside
+// tx. The reward row is locked so concurrent approvals on the same reward see
+// each other's deltas when enforcing the non-negative quantity rule. Deltas
+// entered in current units are converted again with the multiplier in force at
+// approval time, so a corporate action between request and approval is honoured.
+func applyAdjustment(ctx context.Context, tx *sql.Tx, req models.AdjustmentRequest) (models.Adjustment, error) {
 	var inserted models.Adjustment
+	rewardID := req.RewardID
+
+	holding, err := loadRewardHolding(ctx, tx, rewardID, true)
+	if err != nil {
+		return inserted, err
+	}
+	req.UnitMultiplier = holding.Multiplier
+	req.DeltaQuantity = holding.toPreEventUnits(req.UnitBasis, req.InputDeltaQuantity)
+
+	if err := holding.checkNonNegative(req.DeltaQuantity); err != nil {
+		return inserted, err
+	}
+
 	err = tx.QueryRowContext(ctx, `
-		INSERT INTO adjustments (reward_id, adjustment_type, delta_quantity, delta_amount, reason, created_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
-		RETURNING id, reward_id, adjustment_type, delta_quantity, delta_amount, reason, created_at
+		INSERT INTO adjustments
+			(reward_id, adjustment_type, unit_basis, input_delta_quantity, unit_multiplier, delta_quantity, delta_amount,
+			 reason, reason_code, ticket_ref, initiated_by, reverts_adjustment_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		RETURNING id, reward_id, adjustment_type, unit_basis, input_delta_quantity, unit_multiplier, delta_quantity,
+			delta_amount, reason, reason_code, ticket_ref, initiated_by, reverts_adjustment_id, created_at
 	`,
 		rewardID,
 		req.AdjustmentType,
+		req.UnitBasis,
+		req.InputDeltaQuantity,
+		req.UnitMultiplier,
 		req.DeltaQuantity,
 		req.DeltaAmount,
-		req.Reason).Scan(
+		req.Reason,
+		req.ReasonCode,
+		req.TicketRef,
+		req.InitiatedBy,
+		req.RevertsAdjustmentID).Scan(
 		&inserted.ID,
 		&inserted.RewardID,
 		&inserted.AdjustmentType,
+		&inserted.UnitBasis,
+		&inserted.InputDeltaQuantity,
+		&inserted.UnitMultiplier,
 		&inserted.DeltaQuantity,
 		&inserted.DeltaAmount,
 		&inserted.Reason,
+		&inserted.ReasonCode,
+		&inserted.TicketRef,
+		&inserted.InitiatedBy,
+		&inserted.RevertsAdjustmentID,
 		&inserted.CreatedAt,
 	)
 	if err != nil {
-		logger.WithError(err).Error("Failed to insert adjustment")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
-		return
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return inserted, conflict("adjustment has already been reverted")
+		}
+		return inserted, err
 	}
 
-	var stockSymbol string
-	err = tx.QueryRowContext(ctx, `SELECT stock_symbol FROM rewards WHERE id=$1`, rewardID).Scan(&stockSymbol)
-	if err != nil {
-		logger.WithError(err).Error("Failed to fetch reward stock symbol")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
-		return
+	var ledgerEntries []models.Ledger
+	if req.RevertsAdjustmentID != nil {
+		ledgerEntries, err = revertLedgerEntries(ctx, tx, *req.RevertsAdjustmentID, req, holding)
+		if err != nil {
+			return inserted, err
+		}
+	}
+	if len(ledgerEntries) == 0 {
+		ledgerEntries = adjustmentLedgerEntries(req, holding)
 	}
 
+	for _, entry := range ledgerEntries {
+		if entry.BusinessDate == "" {
+			entry.BusinessDate = holding.AsOf
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO ledger (reward_id, adjustment_id, entry_type, stock_symbol, quantity, amount, business_date, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())
+		`,
+			entry.Reward_ID,
+			inserted.ID,
+			entry.Entry_Type,
+			entry.Stock_Symbol,
+			utils.RoundQuantity(entry.Quantity),
+			utils.RoundAmount(entry.Amount),
+			entry.BusinessDate); err != nil {
+			return inserted, err
+		}
+	}
+
+	return inserted, nil
+}
+
+// adjustmentLedgerEntries derives the ledger rows for an adjustment from its
+// type and deltas. Units are posted in the lot's current units and symbol, so
+// that stock_units rows always add up to the holding.
+func adjustmentLedgerEntries(req models.AdjustmentRequest, holding rewardHolding) []models.Ledger {
+	rewardID := req.RewardID
+	stockSymbol := holding.StockSymbol
+	units := utils.RoundQuantity(req.DeltaQuantity * holding.Multiplier)
 	ledgerEntries := []models.Ledger{}
 
 	switch req.AdjustmentType {
@@ -134,7 +455,7 @@ func adjustmentHandler(c *gin.Context) {
 				Reward_ID:    rewardID,
 				Entry_Type:   models.StockUnits,
 				Stock_Symbol: stockSymbol,
-				Quantity:     -req.DeltaQuantity,
+				Quantity:     units,
 				Amount:       0,
 			})
 		}
@@ -159,7 +480,7 @@ func adjustmentHandler(c *gin.Context) {
 				Reward_ID:    rewardID,
 				Entry_Type:   models.StockUnits,
 				Stock_Symbol: stockSymbol,
-				Quantity:     req.DeltaQuantity,
+				Quantity:     units,
 				Amount:       0,
 			})
 		}
@@ -172,36 +493,86 @@ func adjustmentHandler(c *gin.Context) {
 		}
 	}
 
-	for _, entry := range ledgerEntries {
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO ledger (reward_id, entry_type, stock_symbol, quantity, amount, created_at)
-			VALUES ($1,$2,$3,$4,$5,NOW())
-		`,
-			entry.Reward_ID,
-			entry.Entry_Type,
-			entry.Stock_Symbol,
-			utils.RoundQuantity(entry.Quantity),
-			utils.RoundAmount(entry.Amount)); err != nil {
-			logger.WithError(err).Error("Failed to insert ledger entry")
-			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("failed to update ledger"))
-			return
-		}
+	return ledgerEntries
+}
+
+// revertLedgerEntries negates the ledger rows written by the original
+// adjustment, on the business dates of those rows, with
+// compensatingLedgerEntries. It returns nothing for adjustments recorded
+// before ledger rows were linked to them; callers then fall back to
+// adjustmentLedgerEntries with the negated deltas.
+func revertLedgerEntries(ctx context.Context, tx *sql.Tx, originalID int, req models.AdjustmentRequest, holding rewardHolding) ([]models.Ledger, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT reward_id, entry_type, COALESCE(stock_symbol, ''), quantity, amount, business_date::text
+		FROM ledger
+		WHERE adjustment_id = $1
+		ORDER BY id
+	`, originalID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if err := tx.Commit(); err != nil {
-		logger.WithError(err).Error("Failed to commit transaction")
-		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
-		return
+	var original []models.Ledger
+	for rows.Next() {
+		var l models.Ledger
+		if err := rows.Scan(&l.Reward_ID, &l.Entry_Type, &l.Stock_Symbol, &l.Quantity, &l.Amount, &l.BusinessDate); err != nil {
+			return nil, err
+		}
+		original = append(original, l)
 	}
-	rolledBack = true
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return compensatingLedgerEntries(original, req, holding), nil
+}
 
-	logger.WithFields(logrus.Fields{
-		"adjustment_id": inserted.ID,
-	}).Info("Adjustment applied successfully")
+// compensatingLedgerEntries negates the original adjustment's ledger rows.
+// Corporate actions posted since may have turned the original units into more
+// units or another symbol; those are taken back with extra stock_units rows,
+// so the lot ends up where the reverted deltas in req put it in its current
+// units. It returns nothing when there are no original rows.
+func compensatingLedgerEntries(original []models.Ledger, req models.AdjustmentRequest, holding rewardHolding) []models.Ledger {
+	if len(original) == 0 {
+		return nil
+	}
+	entries := make([]models.Ledger, 0, len(original))
+	for _, l := range original {
+		l.Quantity = -l.Quantity
+		l.Amount = -l.Amount
+		entries = append(entries, l)
+	}
 
-	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
-		"message":  "Adjustment applied successfully",
-		"rewardId": rewardID,
-		"data":     inserted,
-	})
+	// What the negated rows leave over, per symbol, compared with the
+	// reverted deltas in the lot's current units and symbol.
+	carry := map[string]float64{holding.StockSymbol: req.DeltaQuantity * holding.Multiplier}
+	symbols := []string{holding.StockSymbol}
+	for _, l := range entries {
+		if l.Entry_Type != models.StockUnits {
+			continue
+		}
+		symbol := l.Stock_Symbol
+		for _, s := range symbols {
+			if strings.EqualFold(s, symbol) {
+				symbol = s
+				break
+			}
+		}
+		if _, ok := carry[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+		carry[symbol] -= l.Quantity
+	}
+	for _, symbol := range symbols {
+		if units := utils.RoundQuantity(carry[symbol]); units != 0 {
+			entries = append(entries, models.Ledger{
+				Reward_ID:    req.RewardID,
+				Entry_Type:   models.StockUnits,
+				Stock_Symbol: symbol,
+				Quantity:     units,
+				Amount:       0,
+			})
+		}
+	}
+	return entries
 }
@@ -0,0 +1,281 @@
+package stocky
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logrus.SetOutput(io.Discard)
+}
+
+// serve runs one request through a router with handler mounted at route.
+// Only requests rejected before the database is reached can be served, since
+// the handlers' db is nil in tests.
+func serve(t *testing.T, method, route, path string, handler gin.HandlerFunc, actor, body string) (int, string) {
+	t.Helper()
+	r := gin.New()
+	r.Use(RequestIDLogger())
+	r.Handle(method, route, handler)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if actor != "" {
+		req.Header.Set(actorHeader, actor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Error
+}
+
+func TestAdjustmentHandlerRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		actor      string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "invalid reward id", path: "/adjustments/abc", actor: "ops", body: `{}`, wantStatus: http.StatusBadRequest, wantError: "invalid reward ID"},
+		{name: "no actor", path: "/adjustments/1", body: `{"adjustment_type": "fee_refund", "delta_amount": 10}`, wantStatus: http.StatusUnauthorized, wantError: actorHeader},
+		{name: "malformed payload", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": `, wantStatus: http.StatusBadRequest, wantError: "Invalid request payload"},
+		{name: "unknown type", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": "gift", "delta_amount": 10}`, wantStatus: http.StatusBadRequest, wantError: "invalid adjustment type"},
+		{name: "no unit basis", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": "manual_correction", "delta_quantity": 1}`, wantStatus: http.StatusBadRequest, wantError: "unit_basis is required"},
+		{name: "no ticket ref", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": "fee_refund", "unit_basis": "current", "delta_amount": 10, "reason_code": "FEE_WAIVER"}`, wantStatus: http.StatusBadRequest, wantError: "ticket_ref is required"},
+		{name: "no deltas", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": "fee_refund", "unit_basis": "current"}`, wantStatus: http.StatusBadRequest, wantError: "must be non-zero"},
+		{name: "deltas round to zero", path: "/adjustments/1", actor: "ops", body: `{"adjustment_type": "manual_correction", "unit_basis": "pre_event", "delta_quantity": 0.0000001, "delta_amount": 0.00001}`, wantStatus: http.StatusBadRequest, wantError: "must be non-zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := serve(t, http.MethodPost, "/adjustments/:id", tt.path, adjustmentHandler, tt.actor, tt.body)
+			if status != tt.wantStatus || !strings.Contains(msg, tt.wantError) {
+				t.Errorf("got %d %q, want %d containing %q", status, msg, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateAdjustmentInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		deltaQty    float64
+		deltaAmount float64
+		wantErr     string
+	}{
+		{name: "quantity only", deltaQty: 1},
+		{name: "amount only", deltaAmount: -10},
+		{name: "no deltas", wantErr: "must be non-zero"},
+		{name: "NaN quantity", deltaQty: math.NaN(), wantErr: "must be finite"},
+		{name: "infinite quantity", deltaQty: math.Inf(-1), wantErr: "must be finite"},
+		{name: "NaN amount", deltaQty: 1, deltaAmount: math.NaN(), wantErr: "must be finite"},
+		{name: "infinite amount", deltaAmount: math.Inf(1), wantErr: "must be finite"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAdjustmentInput(models.Manual_Correction, models.UnitsCurrent, tt.deltaQty, tt.deltaAmount)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecideAdjustmentRequestRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		actor      string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "invalid id", path: "/adjustment-requests/0/approve", actor: "checker", wantStatus: http.StatusBadRequest, wantError: "invalid adjustment request ID"},
+		{name: "no actor", path: "/adjustment-requests/1/approve", wantStatus: http.StatusUnauthorized, wantError: actorHeader},
+		{name: "malformed payload", path: "/adjustment-requests/1/approve", actor: "checker", body: `{"comment": `, wantStatus: http.StatusBadRequest, wantError: "Invalid request payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := serve(t, http.MethodPost, "/adjustment-requests/:id/approve", tt.path, approveAdjustmentRequest, tt.actor, tt.body)
+			if status != tt.wantStatus || !strings.Contains(msg, tt.wantError) {
+				t.Errorf("got %d %q, want %d containing %q", status, msg, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAdjustmentLedgerEntries(t *testing.T) {
+	tcs := rewardHolding{Multiplier: 1, StockSymbol: "TCS"}
+	tests := []struct {
+		name    string
+		req     models.AdjustmentRequest
+		holding rewardHolding
+		want    []models.Ledger
+	}{
+		{
+			name: "reward reversal",
+			req:  models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Reward_Reversal, DeltaQuantity: -2, DeltaAmount: -100},
+			want: []models.Ledger{
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: -2},
+				{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: 100},
+			},
+		},
+		{
+			name: "fee refund posts only the amount",
+			req:  models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Fee_Refund, DeltaQuantity: 3, DeltaAmount: 50},
+			want: []models.Ledger{{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: 50}},
+		},
+		{
+			name: "manual correction of units",
+			req:  models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Manual_Correction, DeltaQuantity: 1.5},
+			want: []models.Ledger{{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: 1.5}},
+		},
+		{
+			name: "manual correction of the amount",
+			req:  models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Manual_Correction, DeltaAmount: -20},
+			want: []models.Ledger{{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: -20}},
+		},
+		{
+			name:    "units are posted in the lot's current units and symbol",
+			req:     models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Manual_Correction, DeltaQuantity: 1.5},
+			holding: rewardHolding{Multiplier: 2.5, StockSymbol: "TCSNEW"},
+			want:    []models.Ledger{{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCSNEW", Quantity: 3.75}},
+		},
+		{
+			name:    "reversal units are rounded after conversion",
+			req:     models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Reward_Reversal, DeltaQuantity: -1},
+			holding: rewardHolding{Multiplier: 1.0 / 3, StockSymbol: "TCS"},
+			want:    []models.Ledger{{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: -0.333333}},
+		},
+		{
+			name: "unknown type posts nothing",
+			req:  models.AdjustmentRequest{RewardID: 1, AdjustmentType: "gift", DeltaQuantity: 1, DeltaAmount: 1},
+			want: []models.Ledger{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holding := tt.holding
+			if holding == (rewardHolding{}) {
+				holding = tcs
+			}
+			if got := adjustmentLedgerEntries(tt.req, holding); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewardHoldingToPreEventUnits(t *testing.T) {
+	h := rewardHolding{Quantity: 10, Multiplier: 3}
+	tests := []struct {
+		unitBasis string
+		qty       float64
+		want      float64
+	}{
+		{unitBasis: models.UnitsPreEvent, qty: 2, want: 2},
+		{unitBasis: models.UnitsCurrent, qty: 6, want: 2},
+		{unitBasis: models.UnitsCurrent, qty: -1, want: -0.333333},
+	}
+	for _, tt := range tests {
+		if got := h.toPreEventUnits(tt.unitBasis, tt.qty); got != tt.want {
+			t.Errorf("toPreEventUnits(%q, %v) = %v, want %v", tt.unitBasis, tt.qty, got, tt.want)
+		}
+	}
+}
+
+func TestRewardHoldingCheckNonNegative(t *testing.T) {
+	h := rewardHolding{Quantity: 2.5, Multiplier: 2}
+	if err := h.checkNonNegative(-2.5); err != nil {
+		t.Errorf("taking the lot to zero: unexpected error %v", err)
+	}
+	err := h.checkNonNegative(-2.500001)
+	if err == nil {
+		t.Fatal("taking the lot below zero: expected an error")
+	}
+	if want := "holding 2.500000 pre-event units, 5.000000 current units"; !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestCompensatingLedgerEntries(t *testing.T) {
+	original := []models.Ledger{
+		{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: 2},
+		{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: 100},
+	}
+	// The revert carries the original's negated deltas, in pre-event units.
+	revert := models.AdjustmentRequest{RewardID: 1, AdjustmentType: models.Manual_Correction, DeltaQuantity: -2, DeltaAmount: -100}
+
+	tests := []struct {
+		name     string
+		original []models.Ledger
+		holding  rewardHolding
+		want     []models.Ledger
+	}{
+		{
+			name:     "no corporate action since",
+			original: original,
+			holding:  rewardHolding{Multiplier: 1, StockSymbol: "TCS"},
+			want: []models.Ledger{
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: -2},
+				{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: -100},
+			},
+		},
+		{
+			name:     "split since takes back the extra units",
+			original: original,
+			holding:  rewardHolding{Multiplier: 2, StockSymbol: "tcs"},
+			want: []models.Ledger{
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: -2},
+				{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: -100},
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "tcs", Quantity: -2},
+			},
+		},
+		{
+			name:     "merger since moves the units to the new symbol",
+			original: original,
+			holding:  rewardHolding{Multiplier: 1.5, StockSymbol: "INFY"},
+			want: []models.Ledger{
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: -2},
+				{Reward_ID: 1, Entry_Type: models.INROutflow, Amount: -100},
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "INFY", Quantity: -3},
+				{Reward_ID: 1, Entry_Type: models.StockUnits, Stock_Symbol: "TCS", Quantity: 2},
+			},
+		},
+		{
+			name:    "no original rows",
+			holding: rewardHolding{Multiplier: 1, StockSymbol: "TCS"},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compensatingLedgerEntries(tt.original, revert, tt.holding)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// adjustmentFilter narrows the adjustment history. Zero values mean "any".
+type adjustmentFilter struct {
+	RewardID   int
+	UserID     int
+	Type       string
+	ReasonCode string
+	From       time.Time
+	To         time.Time
+	Query      string
+	Limit      int
+	Offset     int
+}
+
+// parseAdjustmentFilter reads the shared query parameters of the history
+// endpoints: type, reason_code, from/to (YYYY-MM-DD, inclusive), user_id, q,
+// limit, offset.
+func parseAdjustmentFilter(c *gin.Context) (adjustmentFilter, error) {
+	f := adjustmentFilter{
+		Type:       c.Query("type"),
+		ReasonCode: strings.ToUpper(strings.TrimSpace(c.Query("reason_code"))),
+		Query:      strings.TrimSpace(c.Query("q")),
+		Limit:      defaultHistoryLimit,
+		Offset:     0,
+	}
+
+	if f.Type != "" {
+		switch f.Type {
+		case models.Reward_Reversal, models.Fee_Refund, models.Manual_Correction:
+		default:
+			return f, badRequest("invalid type. must be one of: reward_reversal, fee_refund, manual_correction")
+		}
+	}
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return f, badRequest("invalid from date – expected YYYY-MM-DD")
+		}
+		f.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return f, badRequest("invalid to date – expected YYYY-MM-DD")
+		}
+		f.To = t
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return f, badRequest("to date must not be before from date")
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return f, badRequest("invalid user_id – must be a positive integer")
+		}
+		f.UserID = id
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, badRequest("invalid limit – must be a positive integer")
+		}
+		if n > maxHistoryLimit {
+			n = maxHistoryLimit
+		}
+		f.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, badRequest("invalid offset – must be a non-negative integer")
+		}
+		f.Offset = n
+	}
+
+	return f, nil
+}
+
+// conditions returns the SQL predicates for f over adjustments a joined to
+// rewards r, registering each value through arg.
+func (f adjustmentFilter) conditions(arg func(interface{}) string) []string {
+	var where []string
+	if f.RewardID != 0 {
+		where = append(where, "a.reward_id = "+arg(f.RewardID))
+	}
+	if f.UserID != 0 {
+		where = append(where, "r.user_id = "+arg(f.UserID))
+	}
+	if f.Type != "" {
+		where = append(where, "a.adjustment_type = "+arg(f.Type))
+	}
+	if f.ReasonCode != "" {
+		where = append(where, "a.reason_code = "+arg(f.ReasonCode))
+	}
+	if !f.From.IsZero() {
+		where = append(where, "a.created_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "a.created_at < "+arg(f.To.AddDate(0, 0, 1)))
+	}
+	if f.Query != "" {
+		where = append(where, "a.reason ILIKE '%' || "+arg(f.Query)+" || '%'")
+	}
+	return where
+}
+
+// queryAdjustmentHistory returns the adjustments matching f, newest first,
+// each with the ledger rows it produced.
+func queryAdjustmentHistory(ctx context.Context, f adjustmentFilter) ([]models.AdjustmentHistoryItem, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := f.conditions(arg)
+
+	query := `
+		SELECT a.id, a.reward_id, a.adjustment_type, a.unit_basis, COALESCE(a.input_delta_quantity, a.delta_quantity),
+			a.unit_multiplier, a.delta_quantity, a.delta_amount,
+			COALESCE(a.reason, ''), a.reason_code, a.ticket_ref, a.initiated_by, a.reverts_adjustment_id,
+			a.created_at, r.user_id, r.stock_symbol, ar.id, rv.id
+		FROM adjustments a
+		JOIN rewards r ON r.id = a.reward_id
+		LEFT JOIN adjustment_requests ar ON ar.adjustment_id = a.id
+		LEFT JOIN adjustments rv ON rv.reverts_adjustment_id = a.id`
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += "\n\t\tORDER BY a.created_at DESC, a.id DESC LIMIT " + arg(f.Limit) + " OFFSET " + arg(f.Offset)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.AdjustmentHistoryItem
+	var adjustmentIDs, requestIDs []int
+	for rows.Next() {
+		var item models.AdjustmentHistoryItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.RewardID,
+			&item.AdjustmentType,
+			&item.UnitBasis,
+			&item.InputDeltaQuantity,
+			&item.UnitMultiplier,
+			&item.DeltaQuantity,
+			&item.DeltaAmount,
+			&item.Reason,
+			&item.ReasonCode,
+			&item.TicketRef,
+			&item.InitiatedBy,
+			&item.RevertsAdjustmentID,
+			&item.CreatedAt,
+			&item.UserID,
+			&item.StockSymbol,
+			&item.AdjustmentRequestID,
+			&item.RevertedBy,
+		); err != nil {
+			return nil, err
+		}
+		item.DeltaQuantity = utils.RoundQuantity(item.DeltaQuantity)
+		item.InputDeltaQuantity = utils.RoundQuantity(item.InputDeltaQuantity)
+		item.DeltaAmount = utils.RoundAmount(item.DeltaAmount)
+		item.ApprovedBy = []string{}
+		item.Ledger = []models.Ledger{}
+		items = append(items, item)
+		adjustmentIDs = append(adjustmentIDs, item.ID)
+		if item.AdjustmentRequestID != nil {
+			requestIDs = append(requestIDs, *item.AdjustmentRequestID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	ledgerByAdjustment, err := loadAdjustmentLedger(ctx, adjustmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := loadAdjustmentApprovals(ctx, db, requestIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if entries, ok := ledgerByAdjustment[items[i].ID]; ok {
+			items[i].Ledger = entries
+		}
+		if items[i].AdjustmentRequestID == nil {
+			continue
+		}
+		for _, a := range approvals[*items[i].AdjustmentRequestID] {
+			if a.Decision == models.DecisionApprove {
+				items[i].ApprovedBy = append(items[i].ApprovedBy, a.Approver)
+			}
+		}
+	}
+	return items, nil
+}
+
+func loadAdjustmentLedger(ctx context.Context, adjustmentIDs []int) (map[int][]models.Ledger, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, reward_id, adjustment_id, entry_type, COALESCE(stock_symbol, ''), quantity, amount,
+			business_date::text, created_at
+		FROM ledger
+		WHERE adjustment_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(adjustmentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int][]models.Ledger)
+	for rows.Next() {
+		var l models.Ledger
+		if err := rows.Scan(&l.ID, &l.Reward_ID, &l.Adjustment_ID, &l.Entry_Type, &l.Stock_Symbol,
+			&l.Quantity, &l.Amount, &l.BusinessDate, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		l.Quantity = utils.RoundQuantity(l.Quantity)
+		l.Amount = utils.RoundAmount(l.Amount)
+		out[*l.Adjustment_ID] = append(out[*l.Adjustment_ID], l)
+	}
+	return out, rows.Err()
+}
+
+func listRewardAdjustments(c *gin.Context) {
+	rewardID, ok := parseIDParam(c, "id", "reward ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"reward_id":  rewardID,
+	})
+
+	filter, err := parseAdjustmentFilter(c)
+	if err != nil {
+		writeError(c, logger, err, "Invalid adjustment filter")
+		return
+	}
+	filter.RewardID = rewardID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rewardExists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM rewards WHERE id=$1)`, rewardID).Scan(&rewardExists); err != nil {
+		logger.WithError(err).Error("Reward existence check failed")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if !rewardExists {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("reward not found"))
+		return
+	}
+
+	items, err := queryAdjustmentHistory(ctx, filter)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch reward adjustments")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"rewardId":    rewardID,
+		"adjustments": utils.OrEmpty(items),
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+	})
+}
+
+func listAdjustments(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	filter, err := parseAdjustmentFilter(c)
+	if err != nil {
+		writeError(c, logger, err, "Invalid adjustment filter")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	items, err := queryAdjustmentHistory(ctx, filter)
+	if err != nil {
+		logger.WithError(err).Error("Failed to search adjustments")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"adjustments": utils.OrEmpty(items),
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+	})
+}
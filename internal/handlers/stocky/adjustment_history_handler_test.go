@@ -0,0 +1,107 @@
+package stocky
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseAdjustmentFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    adjustmentFilter
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  adjustmentFilter{Limit: defaultHistoryLimit},
+		},
+		{
+			name:  "every filter",
+			query: "type=fee_refund&reason_code=+fee_waiver&from=2026-10-01&to=2026-10-18&user_id=7&q=+typo+&limit=20&offset=40",
+			want: adjustmentFilter{
+				UserID:     7,
+				Type:       "fee_refund",
+				ReasonCode: "FEE_WAIVER",
+				From:       time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+				To:         time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+				Query:      "typo",
+				Limit:      20,
+				Offset:     40,
+			},
+		},
+		{name: "same from and to", query: "from=2026-10-18&to=2026-10-18", want: adjustmentFilter{
+			From:  time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			To:    time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			Limit: defaultHistoryLimit,
+		}},
+		{name: "limit is capped", query: "limit=1000", want: adjustmentFilter{Limit: maxHistoryLimit}},
+		{name: "unknown type", query: "type=gift", wantErr: "invalid type"},
+		{name: "bad from", query: "from=18-10-2026", wantErr: "invalid from date"},
+		{name: "bad to", query: "to=yesterday", wantErr: "invalid to date"},
+		{name: "to before from", query: "from=2026-10-18&to=2026-10-17", wantErr: "must not be before"},
+		{name: "zero user id", query: "user_id=0", wantErr: "invalid user_id"},
+		{name: "zero limit", query: "limit=0", wantErr: "invalid limit"},
+		{name: "negative offset", query: "offset=-1", wantErr: "invalid offset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/adjustments?"+tt.query, nil)
+
+			got, err := parseAdjustmentFilter(c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdjustmentFilterConditions(t *testing.T) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	f := adjustmentFilter{
+		RewardID:   3,
+		ReasonCode: "FEE_WAIVER",
+		To:         time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		Query:      "typo",
+	}
+
+	where := f.conditions(arg)
+	wantWhere := []string{
+		"a.reward_id = $1",
+		"a.reason_code = $2",
+		"a.created_at < $3",
+		"a.reason ILIKE '%' || $4 || '%'",
+	}
+	if !reflect.DeepEqual(where, wantWhere) {
+		t.Errorf("conditions = %q, want %q", where, wantWhere)
+	}
+	wantArgs := []interface{}{3, "FEE_WAIVER", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), "typo"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v (to is exclusive of the next day)", args, wantArgs)
+	}
+
+	if where := (adjustmentFilter{}).conditions(arg); len(where) != 0 {
+		t.Errorf("empty filter conditions = %q, want none", where)
+	}
+}
@@ -0,0 +1,80 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// adjustmentsByReasonCode totals applied adjustments per reason code. It
+// accepts the same filters as the adjustment history (type, reason_code,
+// from, to, user_id, q); limit and offset are ignored.
+func adjustmentsByReasonCode(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	filter, err := parseAdjustmentFilter(c)
+	if err != nil {
+		writeError(c, logger, err, "Invalid adjustment filter")
+		return
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := filter.conditions(arg)
+
+	query := `
+		SELECT a.reason_code, COALESCE(rc.description, ''), COUNT(*),
+			SUM(a.delta_quantity), SUM(a.delta_amount), MIN(a.created_at), MAX(a.created_at)
+		FROM adjustments a
+		JOIN rewards r ON r.id = a.reward_id
+		LEFT JOIN adjustment_reason_codes rc ON rc.code = a.reason_code`
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += "\n\t\tGROUP BY a.reason_code, rc.description ORDER BY COUNT(*) DESC, a.reason_code"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.WithError(err).Error("Failed to summarise adjustments by reason code")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	var summaries []models.AdjustmentReasonSummary
+	for rows.Next() {
+		var s models.AdjustmentReasonSummary
+		if err := rows.Scan(&s.ReasonCode, &s.Description, &s.AdjustmentCount,
+			&s.TotalDeltaQuantity, &s.TotalDeltaAmount, &s.FirstAt, &s.LastAt); err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		s.TotalDeltaQuantity = utils.RoundQuantity(s.TotalDeltaQuantity)
+		s.TotalDeltaAmount = utils.RoundAmount(s.TotalDeltaAmount)
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("row iteration error")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"byReasonCode": utils.OrEmpty(summaries),
+	})
+}
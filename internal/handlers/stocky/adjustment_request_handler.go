@@ -0,0 +1,350 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const adjustmentRequestColumns = `
+	id, reward_id, adjustment_type, unit_basis, COALESCE(input_delta_quantity, delta_quantity), unit_multiplier,
+	delta_quantity, delta_amount, reason, reason_code, ticket_ref, inr_value,
+	required_approvals, status, initiated_by, reverts_adjustment_id, batch_id, adjustment_id, created_at, decided_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAdjustmentRequest(row rowScanner) (models.AdjustmentRequest, error) {
+	var r models.AdjustmentRequest
+	err := row.Scan(
+		&r.ID,
+		&r.RewardID,
+		&r.AdjustmentType,
+		&r.UnitBasis,
+		&r.InputDeltaQuantity,
+		&r.UnitMultiplier,
+		&r.DeltaQuantity,
+		&r.DeltaAmount,
+		&r.Reason,
+		&r.ReasonCode,
+		&r.TicketRef,
+		&r.INRValue,
+		&r.RequiredApprovals,
+		&r.Status,
+		&r.InitiatedBy,
+		&r.RevertsAdjustmentID,
+		&r.BatchID,
+		&r.AdjustmentID,
+		&r.CreatedAt,
+		&r.DecidedAt,
+	)
+	return r, err
+}
+
+func loadAdjustmentApprovals(ctx context.Context, q querier, requestIDs []int) (map[int][]models.AdjustmentApproval, error) {
+	out := make(map[int][]models.AdjustmentApproval, len(requestIDs))
+	if len(requestIDs) == 0 {
+		return out, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, request_id, approver, decision, comment, created_at
+		FROM adjustment_approvals
+		WHERE request_id = ANY($1)
+		ORDER BY created_at, id
+	`, pq.Array(requestIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.AdjustmentApproval
+		if err := rows.Scan(&a.ID, &a.RequestID, &a.Approver, &a.Decision, &a.Comment, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out[a.RequestID] = append(out[a.RequestID], a)
+	}
+	return out, rows.Err()
+}
+
+func approvalsOrEmpty(a []models.AdjustmentApproval) []models.AdjustmentApproval {
+	if a == nil {
+		return []models.AdjustmentApproval{}
+	}
+	return a
+}
+
+func listAdjustmentRequests(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	status := c.DefaultQuery("status", models.AdjustmentPending)
+	switch status {
+	case models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected:
+	default:
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid status. must be one of: pending, approved, rejected"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+adjustmentRequestColumns+`
+		FROM adjustment_requests
+		WHERE status = $1
+		ORDER BY created_at, id
+	`, status)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment requests")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	var requests []models.AdjustmentRequest
+	var ids []int
+	for rows.Next() {
+		r, err := scanAdjustmentRequest(rows)
+		if err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		requests = append(requests, r)
+		ids = append(ids, r.ID)
+	}
+
+	approvals, err := loadAdjustmentApprovals(ctx, db, ids)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment approvals")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	for i := range requests {
+		requests[i].Approvals = approvalsOrEmpty(approvals[requests[i].ID])
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"status":   status,
+		"requests": utils.OrEmpty(requests),
+	})
+}
+
+func getAdjustmentRequest(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "adjustment request ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":            requestID(c),
+		"adjustment_request_id": id,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := scanAdjustmentRequest(db.QueryRowContext(ctx, `
+		SELECT `+adjustmentRequestColumns+` FROM adjustment_requests WHERE id = $1
+	`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("adjustment request not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch adjustment request")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	approvals, err := loadAdjustmentApprovals(ctx, db, []int{id})
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment approvals")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	req.Approvals = approvalsOrEmpty(approvals[id])
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"data": req,
+	})
+}
+
+func approveAdjustmentRequest(c *gin.Context) {
+	decideAdjustmentRequest(c, models.DecisionApprove)
+}
+
+func rejectAdjustmentRequest(c *gin.Context) {
+	decideAdjustmentRequest(c, models.DecisionReject)
+}
+
+// decideAdjustmentRequest records an approver's decision. A single rejection
+// closes the request; it is applied once it has collected the required number
+// of approvals from people other than the initiator.
+func decideAdjustmentRequest(c *gin.Context, decision string) {
+	id, ok := parseIDParam(c, "id", "adjustment request ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":            requestID(c),
+		"adjustment_request_id": id,
+		"decision":              decision,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var body models.AdjustmentDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			logger.WithError(err).Warn("Invalid decision payload")
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	rolledBack := false
+	defer func() {
+		if !rolledBack {
+			_ = tx.Rollback()
+		}
+	}()
+
+	req, err := scanAdjustmentRequest(tx.QueryRowContext(ctx, `
+		SELECT `+adjustmentRequestColumns+` FROM adjustment_requests WHERE id = $1 FOR UPDATE
+	`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("adjustment request not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch adjustment request")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	if req.Status != models.AdjustmentPending {
+		response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("adjustment request is already "+req.Status))
+		return
+	}
+	if req.BatchID != nil {
+		response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse(fmt.Sprintf("adjustment request belongs to batch %d; approve or reject the batch instead", *req.BatchID)))
+		return
+	}
+	if req.InitiatedBy == actor {
+		response.WriteJson(c.Writer, http.StatusForbidden, response.ErrorResponse("initiator cannot approve or reject their own adjustment request"))
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO adjustment_approvals (request_id, approver, decision, comment, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, id, actor, decision, body.Comment); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("approver has already decided on this request"))
+			return
+		}
+		logger.WithError(err).Error("Failed to record decision")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	var applied *models.Adjustment
+	switch decision {
+	case models.DecisionReject:
+		req.Status = models.AdjustmentRejected
+	case models.DecisionApprove:
+		var approvals int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM adjustment_approvals WHERE request_id = $1 AND decision = $2
+		`, id, models.DecisionApprove).Scan(&approvals); err != nil {
+			logger.WithError(err).Error("Failed to count approvals")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		if approvals >= req.RequiredApprovals {
+			adj, err := applyAdjustment(ctx, tx, req)
+			if err != nil {
+				writeError(c, logger, err, "Failed to apply adjustment")
+				return
+			}
+			applied = &adj
+			req.Status = models.AdjustmentApproved
+			req.AdjustmentID = &adj.ID
+		}
+	}
+
+	if req.Status != models.AdjustmentPending {
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE adjustment_requests
+			SET status = $1, adjustment_id = $2, decided_at = NOW()
+			WHERE id = $3
+			RETURNING decided_at
+		`, req.Status, req.AdjustmentID, id).Scan(&req.DecidedAt); err != nil {
+			logger.WithError(err).Error("Failed to update adjustment request")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+	}
+
+	approvals, err := loadAdjustmentApprovals(ctx, tx, []int{id})
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch adjustment approvals")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	req.Approvals = approvalsOrEmpty(approvals[id])
+
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	rolledBack = true
+
+	logger.WithFields(logrus.Fields{
+		"actor":  actor,
+		"status": req.Status,
+	}).Info("Adjustment request decision recorded")
+
+	message := "Approval recorded; awaiting further approval"
+	switch req.Status {
+	case models.AdjustmentApproved:
+		message = "Adjustment approved and applied successfully"
+	case models.AdjustmentRejected:
+		message = "Adjustment request rejected"
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message":    message,
+		"data":       req,
+		"adjustment": applied,
+	})
+}
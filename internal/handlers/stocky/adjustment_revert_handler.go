@@ -0,0 +1,113 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// revertAdjustmentHandler requests a compensating adjustment that exactly
+// negates an applied one. Like any other adjustment it goes through approval;
+// on approval the original's ledger rows are posted again with opposite sign.
+// The negated delta is taken from the stored pre-event quantity, so the revert
+// is exact whatever unit basis the original was entered in. A revert cannot
+// itself be reverted, and an adjustment can have only one revert, applied or
+// pending. A ticket_ref is required; reason_code defaults to REVERT.
+func revertAdjustmentHandler(c *gin.Context) {
+	adjustmentID, ok := parseIDParam(c, "id", "adjustment ID")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"adjustment_id": adjustmentID,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var body models.RevertAdjustmentRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		logger.WithError(err).Warn("Invalid revert payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var original models.Adjustment
+	err := db.QueryRowContext(ctx, `
+		SELECT id, reward_id, adjustment_type, delta_quantity, delta_amount, COALESCE(reason, '')
+		FROM adjustments WHERE id = $1
+	`, adjustmentID).Scan(
+		&original.ID,
+		&original.RewardID,
+		&original.AdjustmentType,
+		&original.DeltaQuantity,
+		&original.DeltaAmount,
+		&original.Reason,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("adjustment not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch adjustment")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	body.TicketRef = strings.TrimSpace(body.TicketRef)
+	if err := validateTicketRef(body.TicketRef); err != nil {
+		writeError(c, logger, err, "Invalid revert request")
+		return
+	}
+	body.ReasonCode = strings.ToUpper(strings.TrimSpace(body.ReasonCode))
+	if body.ReasonCode == "" {
+		body.ReasonCode = revertReasonCode
+	}
+
+	reason := fmt.Sprintf("revert of adjustment %d", adjustmentID)
+	if body.Reason != "" {
+		reason += ": " + body.Reason
+	}
+
+	pending, err := createAdjustmentRequest(ctx, models.AdjustmentRequest{
+		RewardID:            original.RewardID,
+		AdjustmentType:      original.AdjustmentType,
+		UnitBasis:           models.UnitsPreEvent,
+		InputDeltaQuantity:  -original.DeltaQuantity,
+		DeltaAmount:         -original.DeltaAmount,
+		Reason:              reason,
+		ReasonCode:          body.ReasonCode,
+		TicketRef:           body.TicketRef,
+		InitiatedBy:         actor,
+		RevertsAdjustmentID: &original.ID,
+	})
+	if err != nil {
+		writeError(c, logger, err, "Failed to create revert request")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"adjustment_request_id": pending.ID,
+		"initiated_by":          actor,
+	}).Info("Adjustment revert requested")
+
+	response.WriteJson(c.Writer, http.StatusAccepted, map[string]interface{}{
+		"message":  "Revert request created and awaiting approval",
+		"rewardId": original.RewardID,
+		"data":     pending,
+	})
+}
@@ -0,0 +1,30 @@
+package stocky
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRevertAdjustmentHandlerRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		actor      string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "invalid id", path: "/adjustments/x/revert", actor: "ops", wantStatus: http.StatusBadRequest, wantError: "invalid adjustment ID"},
+		{name: "no actor", path: "/adjustments/1/revert", wantStatus: http.StatusUnauthorized, wantError: actorHeader},
+		{name: "malformed payload", path: "/adjustments/1/revert", actor: "ops", body: `{"reason": `, wantStatus: http.StatusBadRequest, wantError: "Invalid request payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := serve(t, http.MethodPost, "/adjustments/:id/revert", tt.path, revertAdjustmentHandler, tt.actor, tt.body)
+			if status != tt.wantStatus || !strings.Contains(msg, tt.wantError) {
+				t.Errorf("got %d %q, want %d containing %q", status, msg, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package stocky
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/bhavcopy"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBhavcopySize comfortably fits a full-market daily file.
+const maxBhavcopySize = 32 << 20
+
+// importBhavcopy loads an uploaded bhavcopy (multipart field "file", CSV or
+// zip) into the price history, like cmd/bhavcopy-import. Query flags:
+// seed_prices, all_symbols, force and series (comma-separated).
+func importBhavcopy(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBhavcopySize)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("multipart field \"file\" with the bhavcopy is required (max 32 MB)"))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("failed to read uploaded file"))
+		return
+	}
+
+	opts := bhavcopy.Options{
+		SeedPrices: c.Query("seed_prices") == "true",
+		AllSymbols: c.Query("all_symbols") == "true",
+		Force:      c.Query("force") == "true",
+		Actor:      actor,
+	}
+	if s := c.Query("series"); s != "" {
+		opts.Series = strings.Split(s, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	res, err := bhavcopy.Import(ctx, db, header.Filename, data, opts)
+	if err != nil {
+		if errors.Is(err, bhavcopy.ErrAlreadyImported) {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse(
+				"this file was already imported on "+res.ImportedAt.Format(time.RFC3339)+"; pass force=true to import it again"))
+			return
+		}
+		if errors.Is(err, bhavcopy.ErrInvalidFile) {
+			logger.WithError(err).Warn("Invalid bhavcopy upload")
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse(err.Error()))
+			return
+		}
+		logger.WithError(err).Error("Failed to import bhavcopy")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"file":       res.FileName,
+		"format":     res.Format,
+		"trade_date": res.TradeDate,
+		"imported":   res.Imported,
+		"seeded":     res.Seeded,
+		"actor":      actor,
+	}).Info("Bhavcopy imported")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Bhavcopy imported successfully",
+		"data":    res,
+	})
+}
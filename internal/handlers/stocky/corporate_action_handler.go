@@ -0,0 +1,72 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// runCorporateActions posts the stock events in effect that the schedule has
+// not reached yet, and reports what it applied.
+func runCorporateActions(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	if corporateActions == nil {
+		response.WriteJson(c.Writer, http.StatusServiceUnavailable, response.ErrorResponse("corporate actions job is not running"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	run := corporateActions.Run(ctx, jobs.TriggerManual)
+	logger.WithFields(logrus.Fields{
+		"actor":   actor,
+		"applied": run.Applied,
+		"failed":  run.Failed,
+	}).Info("Manual corporate actions run")
+
+	status := http.StatusOK
+	if run.Error != "" || run.Failed > 0 {
+		status = http.StatusInternalServerError
+	}
+	response.WriteJson(c.Writer, status, map[string]interface{}{
+		"data": run,
+	})
+}
+
+// corporateActionsStatus reports the job's schedule and last run, and the
+// events in effect still waiting to be posted.
+func corporateActionsStatus(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	if corporateActions == nil {
+		response.WriteJson(c.Writer, http.StatusServiceUnavailable, response.ErrorResponse("corporate actions job is not running"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var pending int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM stock_events WHERE applied_at IS NULL AND effective_date <= $1::date
+	`, corporateActions.Today()).Scan(&pending); err != nil {
+		logger.WithError(err).Error("Failed to count pending stock events")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"job":     corporateActions.Status(),
+		"pending": pending,
+	})
+}
@@ -0,0 +1,75 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// listUserDelistings returns what each delisting did with the user's
+// holdings, newest first: the units settled, the outcome and any exit offer
+// payout.
+func listUserDelistings(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"user_id":    userID,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id, i.symbol, i.isin, i.name, e.effective_date::text, e.delist_outcome,
+			COUNT(*), SUM(a.units_before), e.exit_price, SUM(a.exit_payout), MIN(a.applied_at)
+		FROM stock_event_applications a
+		JOIN stock_events e ON e.id = a.event_id
+		JOIN rewards r ON r.id = a.reward_id
+		JOIN instruments i ON i.id = a.instrument_before
+		WHERE r.user_id = $1 AND e.event_type = 'delist' AND a.reversed_at IS NULL
+		GROUP BY e.id, i.symbol, i.isin, i.name, e.effective_date, e.delist_outcome, e.exit_price
+		ORDER BY e.effective_date DESC, e.id DESC
+	`, userID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch delistings")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	settlements := []models.DelistingSettlement{}
+	var payout float64
+	for rows.Next() {
+		var s models.DelistingSettlement
+		if err := rows.Scan(&s.EventID, &s.StockSymbol, &s.ISIN, &s.Name, &s.EffectiveDate, &s.Outcome,
+			&s.Lots, &s.Units, &s.ExitPrice, &s.Payout, &s.SettledAt); err != nil {
+			logger.WithError(err).Error("Failed to scan delisting")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		s.Units = utils.RoundQuantity(s.Units)
+		s.Payout = utils.RoundAmount(s.Payout)
+		payout += s.Payout
+		settlements = append(settlements, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Failed to read delistings")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"userId":      userID,
+		"totalPayout": utils.RoundAmount(payout),
+		"delistings":  settlements,
+	})
+}
@@ -0,0 +1,712 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const instrumentColumns = `
+	id, isin, symbol, nse_symbol, bse_symbol, name, sector, face_value, listing_status,
+	created_at, updated_by, updated_at`
+
+var (
+	symbolPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9&._-]{0,19}$`)
+	isinPattern   = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`)
+)
+
+const maxInstrumentReasonLength = 500
+
+func scanInstrument(row rowScanner) (models.Instrument, error) {
+	var i models.Instrument
+	err := row.Scan(
+		&i.ID,
+		&i.ISIN,
+		&i.Symbol,
+		&i.NSESymbol,
+		&i.BSESymbol,
+		&i.Name,
+		&i.Sector,
+		&i.FaceValue,
+		&i.ListingStatus,
+		&i.CreatedAt,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+// findInstrument looks an instrument up by symbol, exchange symbol, ISIN or
+// alias, the way resolve_instrument does in the database.
+func findInstrument(ctx context.Context, q querier, symbol string) (models.Instrument, bool, error) {
+	inst, err := scanInstrument(q.QueryRowContext(ctx, `
+		SELECT `+instrumentColumns+` FROM instruments WHERE id = resolve_instrument($1)
+	`, symbol))
+	if err == sql.ErrNoRows {
+		return inst, false, nil
+	}
+	return inst, err == nil, err
+}
+
+// validISIN checks the format and the check digit: letters become two-digit
+// numbers (A=10) and the digits must pass the Luhn check.
+func validISIN(isin string) bool {
+	if !isinPattern.MatchString(isin) {
+		return false
+	}
+	var digits []int
+	for _, r := range isin {
+		if r >= 'A' && r <= 'Z' {
+			n := int(r-'A') + 10
+			digits = append(digits, n/10, n%10)
+		} else {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func normaliseSymbol(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// validateInstrument normalises req and checks it. create requires an ISIN
+// and a symbol; an update may leave the ISIN of an instrument created from
+// price data unset.
+func validateInstrument(req *models.InstrumentRequest, create bool) error {
+	req.ISIN = normaliseSymbol(req.ISIN)
+	req.Symbol = normaliseSymbol(req.Symbol)
+	req.NSESymbol = normaliseSymbol(req.NSESymbol)
+	req.BSESymbol = normaliseSymbol(req.BSESymbol)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Sector = strings.TrimSpace(req.Sector)
+	req.ListingStatus = strings.ToLower(strings.TrimSpace(req.ListingStatus))
+
+	if req.ISIN == "" && create {
+		return badRequest("isin is required")
+	}
+	if req.ISIN != "" && !validISIN(req.ISIN) {
+		return badRequest("isin must be a valid 12-character ISIN")
+	}
+	if create {
+		if req.Symbol == "" {
+			req.Symbol = req.NSESymbol
+		}
+		if req.Symbol == "" {
+			req.Symbol = req.BSESymbol
+		}
+		if req.Symbol == "" {
+			return badRequest("symbol, nse_symbol or bse_symbol is required")
+		}
+		if !symbolPattern.MatchString(req.Symbol) {
+			return badRequest("symbol must be 1-20 letters, digits or & . _ -")
+		}
+	}
+	for _, s := range []string{req.NSESymbol, req.BSESymbol} {
+		if s != "" && !symbolPattern.MatchString(s) {
+			return badRequest("nse_symbol and bse_symbol must be 1-20 letters, digits or & . _ -")
+		}
+	}
+	if req.Name == "" {
+		return badRequest("name is required")
+	}
+	if req.FaceValue != nil && *req.FaceValue <= 0 {
+		return badRequest("face_value must be positive")
+	}
+	switch req.ListingStatus {
+	case "":
+		req.ListingStatus = models.ListingListed
+	case models.ListingListed, models.ListingSuspended, models.ListingDelisted:
+	default:
+		return badRequest("invalid listing_status. must be one of: listed, suspended, delisted")
+	}
+	return nil
+}
+
+// checkIdentifiers rejects values already used as another instrument's
+// symbol, exchange symbol or ISIN. Aliases are not checked: a current symbol
+// takes precedence over an alias, so a reused ticker may shadow one.
+func checkIdentifiers(ctx context.Context, q querier, excludeID int, values ...string) error {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		var id int
+		var symbol string
+		err := q.QueryRowContext(ctx, `
+			SELECT id, symbol FROM instruments
+			WHERE $1 IN (symbol, nse_symbol, bse_symbol, isin) AND id <> $2
+			LIMIT 1
+		`, v, excludeID).Scan(&id, &symbol)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return conflict(v + " already identifies instrument " + symbol)
+	}
+	return nil
+}
+
+// loadInstrumentHistory fills in the aliases and past renames.
+func loadInstrumentHistory(ctx context.Context, q querier, inst *models.Instrument) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT alias, alias_type, created_by, created_at
+		FROM instrument_aliases WHERE instrument_id = $1
+		ORDER BY created_at, id
+	`, inst.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	inst.Aliases = []models.InstrumentAlias{}
+	for rows.Next() {
+		var a models.InstrumentAlias
+		if err := rows.Scan(&a.Alias, &a.AliasType, &a.CreatedBy, &a.CreatedAt); err != nil {
+			return err
+		}
+		inst.Aliases = append(inst.Aliases, a)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	renames, err := q.QueryContext(ctx, `
+		SELECT old_symbol, new_symbol, reason, changed_by, changed_at
+		FROM instrument_symbol_changes WHERE instrument_id = $1
+		ORDER BY changed_at, id
+	`, inst.ID)
+	if err != nil {
+		return err
+	}
+	defer renames.Close()
+	inst.SymbolChanges = []models.InstrumentRename{}
+	for renames.Next() {
+		var r models.InstrumentRename
+		if err := renames.Scan(&r.OldSymbol, &r.NewSymbol, &r.Reason, &r.ChangedBy, &r.ChangedAt); err != nil {
+			return err
+		}
+		inst.SymbolChanges = append(inst.SymbolChanges, r)
+	}
+	return renames.Err()
+}
+
+func bindInstrument(c *gin.Context, logger *logrus.Entry, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		logger.WithError(err).Warn("Invalid instrument payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return false
+	}
+	return true
+}
+
+// lockInstrument loads an instrument for update, reporting 404 if it does
+// not exist.
+func lockInstrument(ctx context.Context, tx *sql.Tx, id int) (models.Instrument, error) {
+	inst, err := scanInstrument(tx.QueryRowContext(ctx, `
+		SELECT `+instrumentColumns+` FROM instruments WHERE id = $1 FOR UPDATE
+	`, id))
+	if err == sql.ErrNoRows {
+		return inst, &apiError{status: http.StatusNotFound, msg: "instrument not found"}
+	}
+	return inst, err
+}
+
+// listInstruments lists the instrument master, optionally filtered by
+// listing status and a search over symbols, ISIN, name and aliases (q).
+func listInstruments(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	status := strings.ToLower(strings.TrimSpace(c.Query("status")))
+	switch status {
+	case "", models.ListingListed, models.ListingSuspended, models.ListingDelisted:
+	default:
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid status. must be one of: listed, suspended, delisted"))
+		return
+	}
+	search := strings.TrimSpace(c.Query("q"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+instrumentColumns+` FROM instruments i
+		WHERE ($1 = '' OR listing_status = $1)
+		  AND ($2 = ''
+		       OR symbol ILIKE '%' || $2 || '%'
+		       OR nse_symbol ILIKE '%' || $2 || '%'
+		       OR bse_symbol ILIKE '%' || $2 || '%'
+		       OR isin ILIKE '%' || $2 || '%'
+		       OR name ILIKE '%' || $2 || '%'
+		       OR EXISTS (
+		           SELECT 1 FROM instrument_aliases a
+		           WHERE a.instrument_id = i.id AND a.alias ILIKE '%' || $2 || '%'
+		       ))
+		ORDER BY symbol
+	`, status, search)
+	if err != nil {
+		logger.WithError(err).Error("Failed to list instruments")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	instruments := []models.Instrument{}
+	for rows.Next() {
+		inst, err := scanInstrument(rows)
+		if err != nil {
+			logger.WithError(err).Error("Failed to scan instrument")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		instruments = append(instruments, inst)
+	}
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Failed to read instruments")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"data": instruments,
+	})
+}
+
+// getInstrument looks an instrument up by any of its symbols, its ISIN or an
+// alias, and returns it with its aliases and renames.
+func getInstrument(c *gin.Context) {
+	symbol := normaliseSymbol(c.Param("symbol"))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"symbol":     symbol,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	inst, ok, err := findInstrument(ctx, db, symbol)
+	if err == nil && ok {
+		err = loadInstrumentHistory(ctx, db, &inst)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if !ok {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("instrument not found"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"data": inst,
+	})
+}
+
+// createInstrument adds an instrument. Its ISIN is required and no other
+// instrument may use its ISIN or symbols.
+func createInstrument(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	var req models.InstrumentRequest
+	if !bindInstrument(c, logger, &req) {
+		return
+	}
+	if err := validateInstrument(&req, true); err != nil {
+		writeError(c, logger, err, "Invalid instrument")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := checkIdentifiers(ctx, db, 0, req.ISIN, req.Symbol, req.NSESymbol, req.BSESymbol); err != nil {
+		writeError(c, logger, err, "Failed to check instrument identifiers")
+		return
+	}
+	inst, err := scanInstrument(db.QueryRowContext(ctx, `
+		INSERT INTO instruments
+			(isin, symbol, nse_symbol, bse_symbol, name, sector, face_value, listing_status, created_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), $9)
+		RETURNING `+instrumentColumns,
+		req.ISIN, req.Symbol, nullIfEmpty(req.NSESymbol), nullIfEmpty(req.BSESymbol), req.Name,
+		nullIfEmpty(req.Sector), req.FaceValue, req.ListingStatus, actor))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("an instrument with this isin or symbol already exists"))
+			return
+		}
+		logger.WithError(err).Error("Failed to insert instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"instrument_id": inst.ID,
+		"symbol":        inst.Symbol,
+		"actor":         actor,
+	}).Info("Instrument created")
+	response.WriteJson(c.Writer, http.StatusCreated, map[string]interface{}{
+		"message": "Instrument created successfully",
+		"data":    inst,
+	})
+}
+
+// updateInstrument replaces an instrument's details. The symbol is left
+// alone; it only changes through a rename, which keeps the old one.
+func updateInstrument(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "instrument id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"instrument_id": id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	var req models.InstrumentRequest
+	if !bindInstrument(c, logger, &req) {
+		return
+	}
+	if err := validateInstrument(&req, false); err != nil {
+		writeError(c, logger, err, "Invalid instrument")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	existing, err := lockInstrument(ctx, tx, id)
+	if err == nil && existing.ISIN != nil && req.ISIN != *existing.ISIN {
+		err = conflict("isin cannot be changed once set")
+	}
+	if err == nil {
+		err = checkIdentifiers(ctx, tx, id, req.ISIN, req.NSESymbol, req.BSESymbol)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to validate instrument")
+		return
+	}
+
+	inst, err := scanInstrument(tx.QueryRowContext(ctx, `
+		UPDATE instruments
+		SET isin = $2, nse_symbol = $3, bse_symbol = $4, name = $5, sector = $6, face_value = $7,
+			listing_status = $8, updated_by = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+instrumentColumns,
+		id, nullIfEmpty(req.ISIN), nullIfEmpty(req.NSESymbol), nullIfEmpty(req.BSESymbol), req.Name,
+		nullIfEmpty(req.Sector), req.FaceValue, req.ListingStatus, actor))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("an instrument with this isin or symbol already exists"))
+			return
+		}
+		logger.WithError(err).Error("Failed to update instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithField("actor", actor).Info("Instrument updated")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Instrument updated successfully",
+		"data":    inst,
+	})
+}
+
+// renameInstrument changes an instrument's symbol. Rows already written keep
+// the symbol they were recorded under and stay linked by instrument; the old
+// symbol becomes a former_symbol alias, so lookups by it still work. The
+// current price, sanity band, open override and pending stock events move to
+// the new symbol, which the price updater fetches from then on.
+func renameInstrument(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "instrument id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"instrument_id": id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	var req models.InstrumentRenameRequest
+	if !bindInstrument(c, logger, &req) {
+		return
+	}
+	req.Symbol = normaliseSymbol(req.Symbol)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if !symbolPattern.MatchString(req.Symbol) {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("symbol must be 1-20 letters, digits or & . _ -"))
+		return
+	}
+	if req.Reason == "" || len(req.Reason) > maxInstrumentReasonLength {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("reason is required and must be at most 500 characters"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	inst, err := lockInstrument(ctx, tx, id)
+	oldSymbol := inst.Symbol
+	if err == nil && inst.Symbol == req.Symbol {
+		err = badRequest("instrument already has symbol " + req.Symbol)
+	}
+	if err == nil {
+		err = checkIdentifiers(ctx, tx, id, req.Symbol)
+	}
+	if err == nil {
+		err = applyRename(ctx, tx, inst, req, actor)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to rename instrument")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit rename")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	jobs.RenameCachedSymbol(oldSymbol, req.Symbol)
+
+	inst, _, err = findInstrument(ctx, db, req.Symbol)
+	if err == nil {
+		err = loadInstrumentHistory(ctx, db, &inst)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to reload instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"old_symbol": oldSymbol,
+		"new_symbol": req.Symbol,
+		"actor":      actor,
+	}).Info("Instrument renamed")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Instrument renamed successfully",
+		"data":    inst,
+	})
+}
+
+func applyRename(ctx context.Context, tx *sql.Tx, inst models.Instrument, req models.InstrumentRenameRequest, actor string) error {
+	old := inst.Symbol
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO instrument_symbol_changes (instrument_id, old_symbol, new_symbol, reason, changed_by, changed_at)
+		  VALUES ($1, $2, $3, $4, $5, NOW())`, []interface{}{inst.ID, old, req.Symbol, req.Reason, actor}},
+		// The new symbol is no longer an alias of this instrument; the old one
+		// becomes one, even if it had been added as a plain alias before.
+		{`DELETE FROM instrument_aliases WHERE instrument_id = $1 AND alias = $2`, []interface{}{inst.ID, req.Symbol}},
+		{`INSERT INTO instrument_aliases (instrument_id, alias, alias_type, created_by, created_at)
+		  VALUES ($1, $2, 'former_symbol', $3, NOW())
+		  ON CONFLICT (instrument_id, alias) DO UPDATE
+		  SET alias_type = EXCLUDED.alias_type, created_by = EXCLUDED.created_by, created_at = EXCLUDED.created_at`,
+			[]interface{}{inst.ID, old, actor}},
+		{`UPDATE instruments
+		  SET symbol = $2, nse_symbol = CASE WHEN nse_symbol = $3 THEN $2 ELSE nse_symbol END,
+		      updated_by = $4, updated_at = NOW()
+		  WHERE id = $1`, []interface{}{inst.ID, req.Symbol, old, actor}},
+		{`UPDATE stock_prices SET stock_symbol = $2 WHERE instrument_id = $1`, []interface{}{inst.ID, req.Symbol}},
+		{`UPDATE stock_price_bands SET stock_symbol = $2 WHERE instrument_id = $1`, []interface{}{inst.ID, req.Symbol}},
+		{`UPDATE stock_price_overrides SET stock_symbol = $2 WHERE instrument_id = $1 AND released_at IS NULL`, []interface{}{inst.ID, req.Symbol}},
+		{`UPDATE stock_events SET stock_symbol = $2 WHERE instrument_id = $1 AND applied_at IS NULL`, []interface{}{inst.ID, req.Symbol}},
+		{`UPDATE stock_events SET target_symbol = $2 WHERE target_instrument_id = $1 AND applied_at IS NULL`, []interface{}{inst.ID, req.Symbol}},
+	}
+	for _, s := range statements {
+		if _, err := tx.ExecContext(ctx, s.query, s.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addInstrumentAlias adds a name the instrument can be looked up by. It may
+// not be any instrument's symbol or ISIN, or an alias of another instrument.
+func addInstrumentAlias(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "instrument id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"instrument_id": id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	var req models.InstrumentAliasRequest
+	if !bindInstrument(c, logger, &req) {
+		return
+	}
+	req.Alias = normaliseSymbol(req.Alias)
+	if !symbolPattern.MatchString(req.Alias) {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("alias must be 1-20 letters, digits or & . _ -"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = lockInstrument(ctx, tx, id)
+	if err == nil {
+		err = checkIdentifiers(ctx, tx, 0, req.Alias)
+	}
+	if err == nil {
+		var taken bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS(SELECT 1 FROM instrument_aliases WHERE alias = $1 AND instrument_id <> $2)
+		`, req.Alias, id).Scan(&taken)
+		if err == nil && taken {
+			err = conflict("alias " + req.Alias + " already names another instrument")
+		}
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to validate alias")
+		return
+	}
+	var alias models.InstrumentAlias
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO instrument_aliases (instrument_id, alias, alias_type, created_by, created_at)
+		VALUES ($1, $2, 'alias', $3, NOW())
+		RETURNING alias, alias_type, created_by, created_at
+	`, id, req.Alias, actor).Scan(&alias.Alias, &alias.AliasType, &alias.CreatedBy, &alias.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("alias "+req.Alias+" is already in use"))
+			return
+		}
+		logger.WithError(err).Error("Failed to insert alias")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit alias")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{"alias": alias.Alias, "actor": actor}).Info("Instrument alias added")
+	response.WriteJson(c.Writer, http.StatusCreated, map[string]interface{}{
+		"message": "Alias added successfully",
+		"data":    alias,
+	})
+}
+
+// deleteInstrumentAlias removes an alias. Former symbols are part of the
+// instrument's history and cannot be removed.
+func deleteInstrumentAlias(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "instrument id")
+	if !ok {
+		return
+	}
+	alias := normaliseSymbol(c.Param("alias"))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"instrument_id": id,
+		"alias":         alias,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var aliasType string
+	err := db.QueryRowContext(ctx, `
+		SELECT alias_type FROM instrument_aliases WHERE instrument_id = $1 AND alias = $2
+	`, id, alias).Scan(&aliasType)
+	if err == sql.ErrNoRows {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("alias not found"))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch alias")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if aliasType == models.AliasFormerSymbol {
+		response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("former symbols are kept as history and cannot be removed"))
+		return
+	}
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM instrument_aliases WHERE instrument_id = $1 AND alias = $2 AND alias_type = 'alias'
+	`, id, alias); err != nil {
+		logger.WithError(err).Error("Failed to delete alias")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithField("actor", actor).Info("Instrument alias removed")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Alias removed successfully",
+	})
+}
@@ -0,0 +1,90 @@
+package stocky
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+)
+
+func TestValidISIN(t *testing.T) {
+	tests := []struct {
+		isin string
+		want bool
+	}{
+		{isin: "INE467B01029", want: true},
+		{isin: "INE009A01021", want: true},
+		{isin: "US0378331005", want: true},
+		{isin: "INE467B01028", want: false}, // wrong check digit
+		{isin: "ine467b01029", want: false},
+		{isin: "INE467B0102", want: false},
+		{isin: "1NE467B01029", want: false},
+		{isin: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := validISIN(tt.isin); got != tt.want {
+			t.Errorf("validISIN(%q) = %v, want %v", tt.isin, got, tt.want)
+		}
+	}
+}
+
+func TestNormaliseSymbol(t *testing.T) {
+	if got := normaliseSymbol("  m&m "); got != "M&M" {
+		t.Errorf("normaliseSymbol = %q, want %q", got, "M&M")
+	}
+}
+
+func TestValidateInstrument(t *testing.T) {
+	faceValue := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		req     models.InstrumentRequest
+		create  bool
+		want    models.InstrumentRequest
+		wantErr string
+	}{
+		{
+			name:   "create normalises and defaults",
+			req:    models.InstrumentRequest{ISIN: " ine467b01029", NSESymbol: "tcs ", Name: " Tata Consultancy Services ", ListingStatus: ""},
+			create: true,
+			want:   models.InstrumentRequest{ISIN: "INE467B01029", Symbol: "TCS", NSESymbol: "TCS", Name: "Tata Consultancy Services", ListingStatus: models.ListingListed},
+		},
+		{
+			name:   "create falls back to the BSE symbol",
+			req:    models.InstrumentRequest{ISIN: "INE467B01029", BSESymbol: "532540", Name: "TCS", ListingStatus: "Suspended"},
+			create: true,
+			want:   models.InstrumentRequest{ISIN: "INE467B01029", Symbol: "532540", BSESymbol: "532540", Name: "TCS", ListingStatus: models.ListingSuspended},
+		},
+		{
+			name: "update may leave the ISIN and symbol out",
+			req:  models.InstrumentRequest{Name: "TCS"},
+			want: models.InstrumentRequest{Name: "TCS", ListingStatus: models.ListingListed},
+		},
+		{name: "create without ISIN", req: models.InstrumentRequest{Symbol: "TCS", Name: "TCS"}, create: true, wantErr: "isin is required"},
+		{name: "bad ISIN", req: models.InstrumentRequest{ISIN: "INE467B01028", Name: "TCS"}, wantErr: "valid 12-character ISIN"},
+		{name: "create without any symbol", req: models.InstrumentRequest{ISIN: "INE467B01029", Name: "TCS"}, create: true, wantErr: "symbol, nse_symbol or bse_symbol is required"},
+		{name: "bad symbol", req: models.InstrumentRequest{ISIN: "INE467B01029", Symbol: "TC S", Name: "TCS"}, create: true, wantErr: "symbol must be"},
+		{name: "bad exchange symbol", req: models.InstrumentRequest{NSESymbol: strings.Repeat("A", 21), Name: "TCS"}, wantErr: "nse_symbol and bse_symbol"},
+		{name: "no name", req: models.InstrumentRequest{Name: " "}, wantErr: "name is required"},
+		{name: "zero face value", req: models.InstrumentRequest{Name: "TCS", FaceValue: faceValue(0)}, wantErr: "face_value must be positive"},
+		{name: "unknown listing status", req: models.InstrumentRequest{Name: "TCS", ListingStatus: "halted"}, wantErr: "invalid listing_status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := validateInstrument(&req, tt.create)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req != tt.want {
+				t.Errorf("request = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package stocky
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/LoganX64/stocky-api/internal/storage/models"
@@ -20,36 +21,50 @@ func PortfolioHandler(c *gin.Context) {
 		"user_id":    userID,
 	})
 
-	rows, err := db.Query(`
-		SELECT stock_symbol, adjusted_quantity, current_price, inr_value
-		FROM user_portfolio
-		WHERE user_id = $1
-		  AND (stock_symbol NOT IN (
-		      SELECT stock_symbol
-		      FROM stock_events
-		      WHERE event_type = 'delist' AND effective_date <= CURRENT_DATE
-		  ))
-	`, userID)
-
+	portfolio, err := loadPortfolio(c.Request.Context(), userID)
 	if err != nil {
 		logger.WithError(err).Error("Failed to fetch portfolio data for user ")
 		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("An internal server error occurred"))
 		return
 	}
+	unlisted, err := loadUnlistedHoldings(c.Request.Context(), userID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch unlisted holdings for user")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("An internal server error occurred"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"userId":    userID,
+		"portfolio": utils.OrEmpty(portfolio),
+		"unlisted":  unlisted,
+	})
+}
+
+// loadPortfolio returns the user's holdings valued at current prices.
+// Delisted stocks have been settled out of the holdings by their delisting.
+func loadPortfolio(ctx context.Context, userID int) ([]models.PortfolioItem, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT stock_symbol, isin, instrument_name, adjusted_quantity, current_price, inr_value, price_source
+		FROM user_portfolio
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	portfolio := []models.PortfolioItem{}
 	for rows.Next() {
-
 		var item models.PortfolioItem
 		if err := rows.Scan(
 			&item.StockSymbol,
+			&item.ISIN,
+			&item.Name,
 			&item.Quantity,
 			&item.CurrentPrice,
-			&item.INRValue); err != nil {
-			logger.WithError(err).Error("Failed to scan portfolio data for user ")
-			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("An internal server error occurred"))
-			return
+			&item.INRValue,
+			&item.PriceSource); err != nil {
+			return nil, err
 		}
 
 		item.Quantity = utils.RoundQuantity(item.Quantity)
@@ -57,8 +72,31 @@ func PortfolioHandler(c *gin.Context) {
 		item.INRValue = utils.RoundAmount(item.INRValue)
 		portfolio = append(portfolio, item)
 	}
-	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
-		"userId":    userID,
-		"portfolio": utils.OrEmpty(portfolio),
-	})
+	return portfolio, rows.Err()
+}
+
+// loadUnlistedHoldings returns the units the user holds of stocks delisted
+// into the unlisted bucket. They have no price and are not valued.
+func loadUnlistedHoldings(ctx context.Context, userID int) ([]models.UnlistedHolding, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT stock_symbol, isin, instrument_name, quantity
+		FROM user_unlisted_holdings
+		WHERE user_id = $1
+		ORDER BY stock_symbol
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holdings := []models.UnlistedHolding{}
+	for rows.Next() {
+		var h models.UnlistedHolding
+		if err := rows.Scan(&h.StockSymbol, &h.ISIN, &h.Name, &h.Quantity); err != nil {
+			return nil, err
+		}
+		h.Quantity = utils.RoundQuantity(h.Quantity)
+		holdings = append(holdings, h)
+	}
+	return holdings, rows.Err()
 }
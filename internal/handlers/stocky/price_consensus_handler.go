@@ -0,0 +1,44 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// listPriceDivergence lists the current prices whose sources disagreed by
+// more than the divergence threshold, with every source's quote.
+func listPriceDivergence(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prices, err := jobs.ListDivergentPrices(ctx, db)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch divergent prices")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	var sources []string
+	for _, kind := range strings.Split(appCfg.PriceProvider.Kind, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			sources = append(sources, kind)
+		}
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"sources":        sources,
+		"tolerance_pct":  appCfg.PriceProvider.ConsensusTolerancePct,
+		"divergence_pct": appCfg.PriceProvider.DivergencePct,
+		"prices":         utils.OrEmpty(prices),
+	})
+}
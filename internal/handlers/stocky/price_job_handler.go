@@ -0,0 +1,118 @@
+package stocky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxRefreshSymbols = 500
+
+// refreshPrices starts a price refresh outside the schedule. The refresh runs
+// in the background; its outcome shows up in the price updater status.
+func refreshPrices(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	if priceUpdater == nil {
+		response.WriteJson(c.Writer, http.StatusServiceUnavailable, response.ErrorResponse("price updater is not running"))
+		return
+	}
+
+	var req models.PriceRefreshRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.WithError(err).Warn("Invalid price refresh payload")
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+			return
+		}
+	}
+	// Symbols may also be given as ?symbols=TCS,INFY.
+	if q := c.Query("symbols"); q != "" {
+		req.Symbols = append(req.Symbols, strings.Split(q, ",")...)
+	}
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, s := range req.Symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			symbols = append(symbols, s)
+		}
+	}
+	if len(symbols) > maxRefreshSymbols {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("at most 500 symbols per refresh"))
+		return
+	}
+
+	if len(symbols) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		unknown, err := jobs.UnknownSymbols(ctx, db, symbols)
+		if err != nil {
+			logger.WithError(err).Error("Failed to validate refresh symbols")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		if len(unknown) > 0 {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("unknown symbols: "+strings.Join(unknown, ", ")))
+			return
+		}
+	}
+
+	if err := priceUpdater.Trigger(jobs.RunOptions{Trigger: jobs.TriggerManual, Symbols: symbols}); err != nil {
+		if errors.Is(err, jobs.ErrRunInProgress) {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("a price refresh is already running"))
+			return
+		}
+		logger.WithError(err).Error("Failed to start price refresh")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"actor":   actor,
+		"symbols": len(symbols),
+	}).Info("Manual price refresh started")
+	response.WriteJson(c.Writer, http.StatusAccepted, map[string]interface{}{
+		"message": "Price refresh started; follow it at /api/v1/admin/jobs/price-updater",
+		"symbols": symbols,
+	})
+}
+
+// priceUpdaterStatus reports the updater's last and next runs and the
+// contents of the price cache.
+func priceUpdaterStatus(c *gin.Context) {
+	if priceUpdater == nil {
+		response.WriteJson(c.Writer, http.StatusServiceUnavailable, response.ErrorResponse("price updater is not running"))
+		return
+	}
+
+	cache := jobs.CacheSnapshot()
+	stale := 0
+	for _, e := range cache {
+		if e.Stale {
+			stale++
+		}
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"updater": priceUpdater.Status(),
+		"cache": map[string]interface{}{
+			"symbols": len(cache),
+			"stale":   stale,
+			"entries": cache,
+		},
+	})
+}
@@ -0,0 +1,208 @@
+package stocky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxOverrideDuration = 7 * 24 * time.Hour
+	defaultAuditLimit   = 100
+	maxAuditLimit       = 1000
+)
+
+// overrideExpiry resolves when an override should end from either an
+// absolute expires_at or a ttl_minutes relative to now.
+func overrideExpiry(req models.PriceOverrideRequest, now time.Time) (time.Time, error) {
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != "" && req.TTLMinutes != 0:
+		return expiresAt, badRequest("set either expires_at or ttl_minutes, not both")
+	case req.ExpiresAt != "":
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return expiresAt, badRequest("invalid expires_at – expected RFC 3339")
+		}
+		expiresAt = t
+	case req.TTLMinutes > 0:
+		expiresAt = now.Add(time.Duration(req.TTLMinutes) * time.Minute)
+	default:
+		return expiresAt, badRequest("expires_at or a positive ttl_minutes is required")
+	}
+	if !expiresAt.After(now) {
+		return expiresAt, badRequest("override expiry must be in the future")
+	}
+	if expiresAt.Sub(now) > maxOverrideDuration {
+		return expiresAt, badRequest("override may last at most 7 days")
+	}
+	return expiresAt, nil
+}
+
+// setPriceOverride pins a symbol's price until the override expires or is
+// released. The refresh job leaves the price alone in the meantime.
+func setPriceOverride(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"symbol":     symbol,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req models.PriceOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid price override payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Price <= 0 {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("price must be positive"))
+		return
+	}
+	if req.Reason == "" {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("reason is required"))
+		return
+	}
+	expiresAt, err := overrideExpiry(req, time.Now())
+	if err != nil {
+		writeError(c, logger, err, "Invalid price override")
+		return
+	}
+
+	today, err := marketToday()
+	if err != nil {
+		logger.WithError(err).Error("Failed to read the market date")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	override, err := jobs.SetPriceOverride(ctx, db, symbol, utils.RoundAmount(req.Price), expiresAt, req.Reason, actor, today)
+	if err != nil {
+		if errors.Is(err, jobs.ErrUnknownSymbol) {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to set price override")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"price":      override.Price,
+		"expires_at": override.ExpiresAt,
+		"actor":      actor,
+	}).Info("Price override set")
+	response.WriteJson(c.Writer, http.StatusCreated, map[string]interface{}{
+		"message": "Price override set successfully",
+		"data":    override,
+	})
+}
+
+func releasePriceOverride(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"symbol":     symbol,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReleasePriceOverrideRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.WithError(err).Warn("Invalid release payload")
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	override, err := jobs.ReleasePriceOverride(ctx, db, symbol, strings.TrimSpace(req.Reason), actor)
+	if err != nil {
+		if errors.Is(err, jobs.ErrNoActiveOverride) {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("no active price override for this stock"))
+			return
+		}
+		logger.WithError(err).Error("Failed to release price override")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithField("actor", actor).Info("Price override released")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Price override released; the next refresh restores the feed price",
+		"data":    override,
+	})
+}
+
+func listPriceOverrides(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	overrides, err := jobs.ListActiveOverrides(ctx, db)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch price overrides")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"overrides": utils.OrEmpty(overrides),
+	})
+}
+
+func listPriceOverrideAudit(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	limit := defaultAuditLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid limit – must be a positive integer"))
+			return
+		}
+		if n > maxAuditLimit {
+			n = maxAuditLimit
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, err := jobs.ListOverrideAudit(ctx, db, strings.TrimSpace(c.Query("symbol")), limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch price override audit")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"audit": utils.OrEmpty(entries),
+	})
+}
@@ -0,0 +1,208 @@
+package stocky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxBandPct = 100
+
+// listPriceQuarantine shows provider prices held back by the sanity checks.
+// Only pending entries are returned unless status is given ("all" for every
+// entry).
+func listPriceQuarantine(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	status := strings.ToLower(strings.TrimSpace(c.DefaultQuery("status", jobs.QuarantinePending)))
+	switch status {
+	case jobs.QuarantinePending, jobs.QuarantineAccepted, jobs.QuarantineRejected:
+	case "all":
+		status = ""
+	default:
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid status – use pending, accepted, rejected or all"))
+		return
+	}
+
+	limit := defaultAuditLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid limit – must be a positive integer"))
+			return
+		}
+		if n > maxAuditLimit {
+			n = maxAuditLimit
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, err := jobs.ListQuarantine(ctx, db, status, strings.TrimSpace(c.Query("symbol")), limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch price quarantine")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"quarantine": utils.OrEmpty(entries),
+	})
+}
+
+// reviewPriceQuarantine accepts a quarantined price, applying it, or rejects
+// it for good.
+func reviewPriceQuarantine(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "quarantine id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":    requestID(c),
+		"quarantine_id": id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req models.PriceQuarantineReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid quarantine review payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+	var accept bool
+	switch strings.ToLower(strings.TrimSpace(req.Decision)) {
+	case "accept":
+		accept = true
+	case "reject":
+	default:
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("decision must be accept or reject"))
+		return
+	}
+
+	today, err := marketToday()
+	if err != nil {
+		logger.WithError(err).Error("Failed to read the market date")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry, err := jobs.ReviewQuarantine(ctx, db, id, accept, strings.TrimSpace(req.Comment), actor, today)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrQuarantineNotFound):
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("quarantined price not found"))
+		case errors.Is(err, jobs.ErrQuarantineReviewed):
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("quarantined price was already reviewed"))
+		case errors.Is(err, jobs.ErrOverrideActive):
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("stock has an active price override; release it first"))
+		default:
+			logger.WithError(err).Error("Failed to review quarantined price")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		}
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"symbol": entry.StockSymbol,
+		"status": entry.Status,
+		"actor":  actor,
+	}).Info("Quarantined price reviewed")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Quarantined price " + entry.Status,
+		"data":    entry,
+	})
+}
+
+func listPriceBands(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bands, err := jobs.ListPriceBands(ctx, db)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch price bands")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"defaults": map[string]float64{
+			"band_pct":     appCfg.PriceUpdater.BandPct,
+			"max_move_pct": appCfg.PriceUpdater.MaxMovePct,
+		},
+		"bands": utils.OrEmpty(bands),
+	})
+}
+
+// setPriceBand sets the circuit band and max move of one symbol.
+func setPriceBand(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"symbol":     symbol,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	var req models.PriceBandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid price band payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+	if req.BandPct == nil || req.MaxMovePct == nil {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("band_pct and max_move_pct are required"))
+		return
+	}
+	if *req.BandPct < 0 || *req.BandPct > maxBandPct || *req.MaxMovePct < 0 || *req.MaxMovePct > maxBandPct {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("band_pct and max_move_pct must be between 0 and 100"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	band, err := jobs.SetPriceBand(ctx, db, symbol, *req.BandPct, *req.MaxMovePct, actor)
+	if err != nil {
+		if errors.Is(err, jobs.ErrUnknownSymbol) {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to set price band")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"band_pct":     band.BandPct,
+		"max_move_pct": band.MaxMovePct,
+		"actor":        actor,
+	}).Info("Price band set")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Price band set successfully",
+		"data":    band,
+	})
+}
@@ -0,0 +1,301 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxTicketRefLength = 64
+	revertReasonCode   = "REVERT"
+)
+
+var reasonCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,39}$`)
+
+const reasonCodeColumns = `
+	code, description, allowed_types, quantity_sign, amount_sign, active, created_by, created_at, updated_by, updated_at`
+
+func scanReasonCode(row rowScanner) (models.AdjustmentReasonCode, error) {
+	var rc models.AdjustmentReasonCode
+	err := row.Scan(
+		&rc.Code,
+		&rc.Description,
+		pq.Array(&rc.AllowedTypes),
+		&rc.QuantitySign,
+		&rc.AmountSign,
+		&rc.Active,
+		&rc.CreatedBy,
+		&rc.CreatedAt,
+		&rc.UpdatedBy,
+		&rc.UpdatedAt,
+	)
+	return rc, err
+}
+
+// loadReasonCodes returns the requested reason codes keyed by code. Unknown
+// codes are simply absent from the result.
+func loadReasonCodes(ctx context.Context, q querier, codes []string) (map[string]models.AdjustmentReasonCode, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+reasonCodeColumns+` FROM adjustment_reason_codes WHERE code = ANY($1)
+	`, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]models.AdjustmentReasonCode, len(codes))
+	for rows.Next() {
+		rc, err := scanReasonCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[rc.Code] = rc
+	}
+	return out, rows.Err()
+}
+
+func signMatches(rule string, v float64) bool {
+	switch rule {
+	case models.SignPositive:
+		return v > 0
+	case models.SignNegative:
+		return v < 0
+	case models.SignZero:
+		return v == 0
+	default:
+		return true
+	}
+}
+
+// checkReasonCode enforces a reason code's rules on an adjustment. The
+// quantity sign is checked on the entered quantity; conversion between unit
+// bases never changes it.
+func checkReasonCode(rc models.AdjustmentReasonCode, ok bool, adjustmentType string, deltaQty, deltaAmount float64) error {
+	if !ok {
+		return badRequest("unknown reason_code")
+	}
+	if !rc.Active {
+		return badRequest("reason_code " + rc.Code + " is no longer active")
+	}
+	allowed := false
+	for _, t := range rc.AllowedTypes {
+		if t == adjustmentType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return badRequest("reason_code " + rc.Code + " is not allowed for " + adjustmentType + "; allowed types: " + strings.Join(rc.AllowedTypes, ", "))
+	}
+	if !signMatches(rc.QuantitySign, deltaQty) {
+		return badRequest("reason_code " + rc.Code + " requires delta_quantity to be " + rc.QuantitySign)
+	}
+	if !signMatches(rc.AmountSign, deltaAmount) {
+		return badRequest("reason_code " + rc.Code + " requires delta_amount to be " + rc.AmountSign)
+	}
+	return nil
+}
+
+// validateReasonCode looks up code and checks the adjustment against it.
+func validateReasonCode(ctx context.Context, q querier, code, adjustmentType string, deltaQty, deltaAmount float64) error {
+	if code == "" {
+		return badRequest("reason_code is required")
+	}
+	codes, err := loadReasonCodes(ctx, q, []string{code})
+	if err != nil {
+		return err
+	}
+	rc, ok := codes[code]
+	return checkReasonCode(rc, ok, adjustmentType, deltaQty, deltaAmount)
+}
+
+func validateTicketRef(ticketRef string) error {
+	if ticketRef == "" {
+		return badRequest("ticket_ref is required")
+	}
+	if len(ticketRef) > maxTicketRefLength {
+		return badRequest("ticket_ref must be at most 64 characters")
+	}
+	return nil
+}
+
+func validateReasonCodeRules(rc models.AdjustmentReasonCode) error {
+	if !reasonCodePattern.MatchString(rc.Code) {
+		return badRequest("code must be 2-40 upper-case letters, digits or underscores, starting with a letter")
+	}
+	if strings.TrimSpace(rc.Description) == "" {
+		return badRequest("description is required")
+	}
+	if len(rc.AllowedTypes) == 0 {
+		return badRequest("allowed_types must list at least one adjustment type")
+	}
+	for _, t := range rc.AllowedTypes {
+		if !validAdjustmentTypes[t] {
+			return badRequest("invalid adjustment type in allowed_types. must be one of: reward_reversal, fee_refund, manual_correction")
+		}
+	}
+	for _, sign := range []string{rc.QuantitySign, rc.AmountSign} {
+		switch sign {
+		case models.SignAny, models.SignPositive, models.SignNegative, models.SignZero:
+		default:
+			return badRequest("quantity_sign and amount_sign must be one of: any, positive, negative, zero")
+		}
+	}
+	return nil
+}
+
+func listReasonCodes(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT ` + reasonCodeColumns + ` FROM adjustment_reason_codes`
+	if c.Query("include_inactive") != "true" {
+		query += ` WHERE active`
+	}
+	query += ` ORDER BY code`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch reason codes")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	var codes []models.AdjustmentReasonCode
+	for rows.Next() {
+		rc, err := scanReasonCode(rows)
+		if err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		codes = append(codes, rc)
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"reasonCodes": utils.OrEmpty(codes),
+	})
+}
+
+func createReasonCode(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	req := models.AdjustmentReasonCode{QuantitySign: models.SignAny, AmountSign: models.SignAny}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid reason code payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if err := validateReasonCodeRules(req); err != nil {
+		writeError(c, logger, err, "Invalid reason code")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rc, err := scanReasonCode(db.QueryRowContext(ctx, `
+		INSERT INTO adjustment_reason_codes
+			(code, description, allowed_types, quantity_sign, amount_sign, active, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6, NOW(), NOW())
+		RETURNING `+reasonCodeColumns,
+		req.Code, req.Description, pq.Array(req.AllowedTypes), req.QuantitySign, req.AmountSign, actor))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			response.WriteJson(c.Writer, http.StatusConflict, response.ErrorResponse("reason code already exists"))
+			return
+		}
+		logger.WithError(err).Error("Failed to insert reason code")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{"reason_code": rc.Code, "actor": actor}).Info("Reason code created")
+	response.WriteJson(c.Writer, http.StatusCreated, map[string]interface{}{
+		"message": "Reason code created successfully",
+		"data":    rc,
+	})
+}
+
+// updateReasonCode replaces a code's description, rules and active flag.
+// Codes are never deleted because applied adjustments reference them;
+// deactivating a code stops new adjustments from using it.
+func updateReasonCode(c *gin.Context) {
+	code := strings.ToUpper(c.Param("code"))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id":  requestID(c),
+		"reason_code": code,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := scanReasonCode(db.QueryRowContext(ctx, `
+		SELECT `+reasonCodeColumns+` FROM adjustment_reason_codes WHERE code = $1
+	`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("reason code not found"))
+			return
+		}
+		logger.WithError(err).Error("Failed to fetch reason code")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	req := existing
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid reason code payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return
+	}
+	req.Code = code
+	if err := validateReasonCodeRules(req); err != nil {
+		writeError(c, logger, err, "Invalid reason code")
+		return
+	}
+
+	rc, err := scanReasonCode(db.QueryRowContext(ctx, `
+		UPDATE adjustment_reason_codes
+		SET description = $2, allowed_types = $3, quantity_sign = $4, amount_sign = $5, active = $6,
+			updated_by = $7, updated_at = NOW()
+		WHERE code = $1
+		RETURNING `+reasonCodeColumns,
+		code, req.Description, pq.Array(req.AllowedTypes), req.QuantitySign, req.AmountSign, req.Active, actor))
+	if err != nil {
+		logger.WithError(err).Error("Failed to update reason code")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithField("actor", actor).Info("Reason code updated")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Reason code updated successfully",
+		"data":    rc,
+	})
+}
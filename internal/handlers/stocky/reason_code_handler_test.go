@@ -0,0 +1,127 @@
+package stocky
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+)
+
+func TestSignMatches(t *testing.T) {
+	tests := []struct {
+		rule string
+		v    float64
+		want bool
+	}{
+		{rule: models.SignAny, v: -1, want: true},
+		{rule: models.SignPositive, v: 1, want: true},
+		{rule: models.SignPositive, v: 0, want: false},
+		{rule: models.SignNegative, v: -0.5, want: true},
+		{rule: models.SignNegative, v: 0, want: false},
+		{rule: models.SignZero, v: 0, want: true},
+		{rule: models.SignZero, v: 1, want: false},
+	}
+	for _, tt := range tests {
+		if got := signMatches(tt.rule, tt.v); got != tt.want {
+			t.Errorf("signMatches(%q, %v) = %v, want %v", tt.rule, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCheckReasonCode(t *testing.T) {
+	feeWaiver := models.AdjustmentReasonCode{
+		Code:         "FEE_WAIVER",
+		AllowedTypes: []string{models.Fee_Refund},
+		QuantitySign: models.SignZero,
+		AmountSign:   models.SignPositive,
+		Active:       true,
+	}
+	inactive := feeWaiver
+	inactive.Active = false
+
+	tests := []struct {
+		name           string
+		rc             models.AdjustmentReasonCode
+		found          bool
+		adjustmentType string
+		deltaQty       float64
+		deltaAmount    float64
+		wantErr        string
+	}{
+		{name: "allowed", rc: feeWaiver, found: true, adjustmentType: models.Fee_Refund, deltaAmount: 10},
+		{name: "unknown code", found: false, adjustmentType: models.Fee_Refund, deltaAmount: 10, wantErr: "unknown reason_code"},
+		{name: "inactive code", rc: inactive, found: true, adjustmentType: models.Fee_Refund, deltaAmount: 10, wantErr: "no longer active"},
+		{name: "type not allowed", rc: feeWaiver, found: true, adjustmentType: models.Manual_Correction, deltaAmount: 10, wantErr: "not allowed for manual_correction"},
+		{name: "quantity sign", rc: feeWaiver, found: true, adjustmentType: models.Fee_Refund, deltaQty: 1, deltaAmount: 10, wantErr: "delta_quantity to be zero"},
+		{name: "amount sign", rc: feeWaiver, found: true, adjustmentType: models.Fee_Refund, deltaAmount: -10, wantErr: "delta_amount to be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReasonCode(tt.rc, tt.found, tt.adjustmentType, tt.deltaQty, tt.deltaAmount)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTicketRef(t *testing.T) {
+	if err := validateTicketRef("OPS-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateTicketRef(strings.Repeat("x", maxTicketRefLength)); err != nil {
+		t.Errorf("ticket at the length limit: unexpected error %v", err)
+	}
+	if err := validateTicketRef(""); err == nil {
+		t.Error("empty ticket: expected an error")
+	}
+	if err := validateTicketRef(strings.Repeat("x", maxTicketRefLength+1)); err == nil {
+		t.Error("ticket over the length limit: expected an error")
+	}
+}
+
+func TestValidateReasonCodeRules(t *testing.T) {
+	valid := models.AdjustmentReasonCode{
+		Code:         "DATA_FIX",
+		Description:  "Data entry correction",
+		AllowedTypes: []string{models.Manual_Correction},
+		QuantitySign: models.SignAny,
+		AmountSign:   models.SignAny,
+	}
+	tests := []struct {
+		name    string
+		edit    func(rc *models.AdjustmentReasonCode)
+		wantErr string
+	}{
+		{name: "valid", edit: func(rc *models.AdjustmentReasonCode) {}},
+		{name: "lower-case code", edit: func(rc *models.AdjustmentReasonCode) { rc.Code = "data_fix" }, wantErr: "code must be"},
+		{name: "code starting with a digit", edit: func(rc *models.AdjustmentReasonCode) { rc.Code = "1FIX" }, wantErr: "code must be"},
+		{name: "one-letter code", edit: func(rc *models.AdjustmentReasonCode) { rc.Code = "F" }, wantErr: "code must be"},
+		{name: "blank description", edit: func(rc *models.AdjustmentReasonCode) { rc.Description = "  " }, wantErr: "description is required"},
+		{name: "no allowed types", edit: func(rc *models.AdjustmentReasonCode) { rc.AllowedTypes = nil }, wantErr: "at least one"},
+		{name: "unknown allowed type", edit: func(rc *models.AdjustmentReasonCode) { rc.AllowedTypes = []string{"gift"} }, wantErr: "invalid adjustment type"},
+		{name: "unknown sign", edit: func(rc *models.AdjustmentReasonCode) { rc.AmountSign = "up" }, wantErr: "amount_sign must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := valid
+			tt.edit(&rc)
+			err := validateReasonCodeRules(rc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -66,10 +66,28 @@ func CreateReward(c *gin.Context) {
 		return
 	}
 
+	// The reward is recorded under the instrument's canonical symbol, whatever
+	// spelling, alias or ISIN the request used.
+	instrument, found, err := findInstrument(ctx, tx, req.StockSymbol)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if !found {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Stock symbol not found"))
+		return
+	}
+	if instrument.ListingStatus != models.ListingListed {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("stock "+instrument.Symbol+" is "+instrument.ListingStatus))
+		return
+	}
+	req.StockSymbol = instrument.Symbol
+
 	var currentPrice float64
-	if err := tx.QueryRowContext(ctx, `SELECT price FROM stock_prices WHERE UPPER(stock_symbol) = UPPER($1)`, req.StockSymbol).Scan(&currentPrice); err != nil {
+	if err := tx.QueryRowContext(ctx, `SELECT price FROM stock_prices WHERE instrument_id = $1`, instrument.ID).Scan(&currentPrice); err != nil {
 		if err == sql.ErrNoRows {
-			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Stock symbol not found"))
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("no price for stock "+instrument.Symbol+" yet"))
 			return
 		}
 		logger.WithError(err).Error("Failed to fetch stock price")
@@ -81,15 +99,17 @@ func CreateReward(c *gin.Context) {
 
 	var reward models.Reward
 	err = tx.QueryRowContext(ctx, `
-    INSERT INTO rewards (user_id, stock_symbol, quantity, idempotency_key, created_at)
-    VALUES ($1, $2, $3, $4, NOW())
-    RETURNING id, user_id, stock_symbol, quantity, idempotency_key, created_at`,
+    INSERT INTO rewards (user_id, stock_symbol, instrument_id, quantity, idempotency_key, created_at)
+    VALUES ($1, $2, $3, $4, $5, NOW())
+    RETURNING id, user_id, stock_symbol, instrument_id, quantity, idempotency_key, created_at`,
 		req.UserID,
 		req.StockSymbol,
+		instrument.ID,
 		req.Quantity,
 		idempotencyKey).Scan(
 		&reward.ID, &reward.User_ID,
 		&reward.Stock_Symbol,
+		&reward.InstrumentID,
 		&reward.Quantity,
 		&reward.IdempotencyKey, &reward.CreatedAt,
 	)
@@ -123,6 +143,7 @@ func CreateReward(c *gin.Context) {
 			Reward_ID:    reward.ID,
 			Entry_Type:   models.StockUnits,
 			Stock_Symbol: req.StockSymbol,
+			InstrumentID: &instrument.ID,
 			Quantity:     req.Quantity,
 			Amount:       0,
 		},
@@ -151,16 +172,26 @@ func CreateReward(c *gin.Context) {
 		)
 	}
 
+	// The grant takes effect on the exchange's date, which is what dividend
+	// record dates are compared with.
+	grantDate, err := marketToday()
+	if err != nil {
+		logger.WithError(err).Error("Failed to read the market date")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
 	for _, entry := range ledgerEntries {
 		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO ledger (reward_id, entry_type, stock_symbol, quantity, amount, created_at)
-			VALUES ($1, $2, $3, $4, $5, NOW())
+			INSERT INTO ledger (reward_id, entry_type, stock_symbol, instrument_id, quantity, amount, business_date, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
 		`,
 			entry.Reward_ID,
 			entry.Entry_Type,
 			entry.Stock_Symbol,
+			entry.InstrumentID,
 			utils.RoundQuantity(entry.Quantity),
-			utils.RoundAmount(entry.Amount)); err != nil {
+			utils.RoundAmount(entry.Amount),
+			grantDate); err != nil {
 			logger.WithError(err).Error("Failed to insert ledger entry")
 			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
 			return
@@ -1,25 +1,55 @@
 package stocky
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
+	"errors"
+	"strings"
 
 	"fmt"
 
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/leader"
 	"github.com/LoganX64/stocky-api/internal/utils/response"
 	"github.com/gin-gonic/gin"
 
 	"github.com/sirupsen/logrus"
 )
 
-var db *sql.DB
+var (
+	db               *sql.DB
+	appCfg           = &config.Config{}
+	priceUpdater     *jobs.PriceUpdater
+	corporateActions *jobs.CorporateActions
+	elector          *leader.Elector
+)
 
 func InitDB(database *sql.DB) {
 	db = database
 }
+
+func InitConfig(cfg *config.Config) {
+	appCfg = cfg
+}
+
+func InitPriceUpdater(u *jobs.PriceUpdater) {
+	priceUpdater = u
+}
+
+func InitCorporateActions(j *jobs.CorporateActions) {
+	corporateActions = j
+}
+
+func InitLeader(e *leader.Elector) {
+	elector = e
+}
+
 func shortRequestID() string {
 	b := make([]byte, 4)
 	_, err := rand.Read(b)
@@ -50,16 +80,91 @@ func parseUserID(c *gin.Context) (int, bool) {
 	return id, true
 }
 
+// actorHeader identifies the operator making a change. There is no
+// authentication layer yet, so it is trusted as sent by the gateway.
+const actorHeader = "X-Actor-ID"
+
+func requireActor(c *gin.Context) (string, bool) {
+	actor := strings.TrimSpace(c.GetHeader(actorHeader))
+	if actor == "" {
+		response.WriteJson(c.Writer, http.StatusUnauthorized, response.ErrorResponse(actorHeader+" header is required"))
+		return "", false
+	}
+	return actor, true
+}
+
+func parseIDParam(c *gin.Context, name, label string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid "+label))
+		return 0, false
+	}
+	return id, true
+}
+
+// apiError is returned by helpers shared between handlers when the failure
+// should be reported to the client as-is rather than as an internal error.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func badRequest(msg string) error {
+	return &apiError{status: http.StatusBadRequest, msg: msg}
+}
+
+func conflict(msg string) error {
+	return &apiError{status: http.StatusConflict, msg: msg}
+}
+
+func writeError(c *gin.Context, logger *logrus.Entry, err error, logMsg string) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		response.WriteJson(c.Writer, apiErr.status, response.ErrorResponse(apiErr.msg))
+		return
+	}
+	logger.WithError(err).Error(logMsg)
+	response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+}
+
+// healthHandler reports liveness and, when leader election is on, which
+// instance runs the scheduled jobs. A failed leader lookup does not fail the
+// health check.
+func healthHandler(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+	logger.Info("Health check endpoint hit")
+
+	body := map[string]interface{}{
+		"status": "OK",
+	}
+	if elector != nil {
+		body["instance_id"] = elector.InstanceID()
+		body["is_leader"] = elector.IsLeader()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		switch current, ok, err := elector.Current(ctx); {
+		case err != nil:
+			logger.WithError(err).Warn("Failed to look up the job leader")
+			body["leader"] = nil
+		case !ok:
+			body["leader"] = nil
+		default:
+			body["leader"] = current
+		}
+	}
+	response.WriteJson(c.Writer, http.StatusOK, body)
+}
+
 func Routes(r *gin.Engine) {
 	r.Use(RequestIDLogger())
 
 	// Health Check Endpoint
-	r.GET("/health", func(c *gin.Context) {
-		logrus.WithField("request_id", requestID(c)).Info("Health check endpoint hit")
-		response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
-			"status": "OK",
-		})
-	})
+	r.GET("/health", healthHandler)
 
 	// API v1 routes group
 	v1 := r.Group("/api/v1")
@@ -69,7 +174,56 @@ func Routes(r *gin.Engine) {
 		v1.GET("/historical-inr/:userId", GetHistoricalINR)
 		v1.GET("/stats/:userId", StatsHandler)
 		v1.GET("/portfolio/:userId", PortfolioHandler)
+		v1.GET("/wallet/:userId", getWallet)
+		v1.GET("/dividends/:userId", listUserDividends)
+		v1.GET("/delistings/:userId", listUserDelistings)
+		v1.GET("/stocks/:symbol/history", GetStockHistory)
+		v1.GET("/instruments/:symbol", getInstrument)
+		v1.GET("/stream/prices", StreamPrices)
+		v1.GET("/stream/portfolio/:userId", StreamPortfolio)
 		v1.POST("/adjustments/:id", adjustmentHandler)
+		v1.GET("/adjustments", listAdjustments)
+		v1.POST("/adjustments/:id/revert", revertAdjustmentHandler)
+		v1.POST("/adjustments/bulk", bulkAdjustmentHandler)
+		v1.GET("/adjustment-batches/:id", getAdjustmentBatch)
+		v1.POST("/adjustment-batches/:id/approve", approveAdjustmentBatch)
+		v1.POST("/adjustment-batches/:id/reject", rejectAdjustmentBatch)
+		v1.GET("/rewards/:id/adjustments", listRewardAdjustments)
+		v1.GET("/adjustment-requests", listAdjustmentRequests)
+		v1.GET("/adjustment-requests/:id", getAdjustmentRequest)
+		v1.POST("/adjustment-requests/:id/approve", approveAdjustmentRequest)
+		v1.POST("/adjustment-requests/:id/reject", rejectAdjustmentRequest)
+		v1.GET("/adjustment-reason-codes", listReasonCodes)
+		v1.POST("/adjustment-reason-codes", createReasonCode)
+		v1.PUT("/adjustment-reason-codes/:code", updateReasonCode)
+		v1.GET("/reports/adjustments/by-reason-code", adjustmentsByReasonCode)
+		v1.GET("/admin/prices/overrides", listPriceOverrides)
+		v1.GET("/admin/prices/overrides/audit", listPriceOverrideAudit)
+		v1.POST("/admin/prices/:symbol/override", setPriceOverride)
+		v1.DELETE("/admin/prices/:symbol/override", releasePriceOverride)
+		v1.GET("/admin/prices/quarantine", listPriceQuarantine)
+		v1.POST("/admin/prices/quarantine/:id/review", reviewPriceQuarantine)
+		v1.GET("/admin/prices/bands", listPriceBands)
+		v1.PUT("/admin/prices/:symbol/band", setPriceBand)
+		v1.GET("/admin/prices/divergence", listPriceDivergence)
+		v1.POST("/admin/prices/refresh", refreshPrices)
+		v1.POST("/admin/prices/bhavcopy", importBhavcopy)
+		v1.GET("/admin/jobs/price-updater", priceUpdaterStatus)
+		v1.GET("/admin/jobs/corporate-actions", corporateActionsStatus)
+		v1.POST("/admin/jobs/corporate-actions/run", runCorporateActions)
+		v1.GET("/admin/stock-events", listStockEvents)
+		v1.POST("/admin/stock-events", createStockEvent)
+		v1.POST("/admin/stock-events/preview", previewStockEvent)
+		v1.GET("/admin/stock-events/:id", getStockEvent)
+		v1.PUT("/admin/stock-events/:id", updateStockEvent)
+		v1.DELETE("/admin/stock-events/:id", deleteStockEvent)
+		v1.GET("/admin/stock-events/:id/payouts", listEventPayouts)
+		v1.GET("/admin/instruments", listInstruments)
+		v1.POST("/admin/instruments", createInstrument)
+		v1.PUT("/admin/instruments/:id", updateInstrument)
+		v1.POST("/admin/instruments/:id/rename", renameInstrument)
+		v1.POST("/admin/instruments/:id/aliases", addInstrumentAlias)
+		v1.DELETE("/admin/instruments/:id/aliases/:alias", deleteInstrumentAlias)
 	}
 
 }
@@ -0,0 +1,915 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/market"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxStockEventNoteLength = 500
+
+const stockEventColumns = `
+	id, stock_symbol, event_type::text, ratio_num, ratio_den, effective_date::text, target_symbol,
+	record_date::text, amount_per_share, cash_per_share, fraction_handling, fraction_price,
+	delist_outcome, exit_price, note, created_by, created_at, updated_by, updated_at, applied_at, applied_lots`
+
+func scanStockEvent(row rowScanner) (models.Stock_Events, error) {
+	var e models.Stock_Events
+	err := row.Scan(
+		&e.ID,
+		&e.Stock_Symbol,
+		&e.EventType,
+		&e.RatioNum,
+		&e.RatioDen,
+		&e.EffectiveDate,
+		&e.TargetSymbol,
+		&e.RecordDate,
+		&e.AmountPerShare,
+		&e.CashPerShare,
+		&e.FractionHandling,
+		&e.FractionPrice,
+		&e.DelistOutcome,
+		&e.ExitPrice,
+		&e.Note,
+		&e.CreatedBy,
+		&e.CreatedAt,
+		&e.UpdatedBy,
+		&e.UpdatedAt,
+		&e.AppliedAt,
+		&e.AppliedLots,
+	)
+	return e, err
+}
+
+// eventsAppliedSince lists the events effective after date that have been
+// posted to lots of symbol's instrument held since before date. An event applied
+// retroactively would have to come before them, so they block it.
+func eventsAppliedSince(ctx context.Context, q querier, symbol, date string) ([]string, error) {
+	return queryEventIDs(ctx, q, `
+		SELECT DISTINCT a.event_id
+		FROM stock_event_applications a
+		JOIN stock_events e ON e.id = a.event_id
+		WHERE a.reversed_at IS NULL
+		  AND e.effective_date > $2::date
+		  AND a.reward_id IN (
+			  SELECT lg.reward_id
+			  FROM ledger lg
+			  JOIN rewards r ON r.id = lg.reward_id
+			  WHERE lg.entry_type = 'stock_units'
+				AND lg.instrument_id = resolve_instrument($1)
+				AND r.created_at::date < $2::date
+		  )
+		ORDER BY a.event_id
+	`, symbol, date)
+}
+
+// eventsAppliedAfter lists the events posted after eventID to any lot it
+// changed. Their rows build on eventID's, so they must be undone first.
+func eventsAppliedAfter(ctx context.Context, q querier, eventID int) ([]string, error) {
+	return queryEventIDs(ctx, q, `
+		SELECT DISTINCT b.event_id
+		FROM stock_event_applications a
+		JOIN stock_event_applications b
+		  ON b.reward_id = a.reward_id AND b.id > a.id AND b.reversed_at IS NULL
+		WHERE a.event_id = $1 AND a.reversed_at IS NULL
+		ORDER BY b.event_id
+	`, eventID)
+}
+
+func queryEventIDs(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return ids, rows.Err()
+}
+
+// applyInEffect posts an event that is already in effect to the ledger, or
+// pays a dividend already payable, instead of leaving it to the next
+// scheduled run. It returns the event as stored afterwards.
+func applyInEffect(ctx context.Context, tx *sql.Tx, event models.Stock_Events, today string) (models.Stock_Events, error) {
+	if event.EffectiveDate > today {
+		return event, nil
+	}
+	if event.EventType == models.EventDividend {
+		if _, err := jobs.PayDividend(ctx, tx, event.ID, today, appCfg.CorporateActions); err != nil {
+			return event, err
+		}
+		return scanStockEvent(tx.QueryRowContext(ctx, `SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1`, event.ID))
+	}
+	if event.EventType != models.EventDelist {
+		ids, err := eventsAppliedSince(ctx, tx, event.Stock_Symbol, event.EffectiveDate)
+		if err != nil {
+			return event, err
+		}
+		if len(ids) > 0 {
+			return event, conflict("stock events " + strings.Join(ids, ", ") + " take effect later and are already posted to lots holding " +
+				event.Stock_Symbol + "; change or delete them first")
+		}
+	}
+	if _, err := jobs.ApplyStockEvent(ctx, tx, event.ID, today); err != nil {
+		return event, err
+	}
+	return scanStockEvent(tx.QueryRowContext(ctx, `SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1`, event.ID))
+}
+
+// unapplyEvent cancels the ledger rows of an event that has been posted. A
+// paid dividend, or a merger or exit offer that paid cash, cannot be taken
+// back from the wallets.
+func unapplyEvent(ctx context.Context, tx *sql.Tx, event models.Stock_Events) error {
+	if event.AppliedAt == nil {
+		return nil
+	}
+	if event.EventType == models.EventDividend {
+		return conflict("dividend has already been paid to wallets and can no longer be changed")
+	}
+	var paidCash bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM stock_event_applications
+			WHERE event_id = $1 AND reversed_at IS NULL AND merger_cash + cash_in_lieu + exit_payout > 0
+		)
+	`, event.ID).Scan(&paidCash); err != nil {
+		return err
+	}
+	if paidCash {
+		return conflict("stock event has already paid cash to wallets and can no longer be changed")
+	}
+	ids, err := eventsAppliedAfter(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return conflict("stock events " + strings.Join(ids, ", ") + " were posted after this one to the same lots; change or delete them first")
+	}
+	_, err = jobs.UnapplyStockEvent(ctx, tx, event.ID)
+	return err
+}
+
+// marketToday is the date on the exchange, which decides whether an event is
+// in effect.
+func marketToday() (string, error) {
+	loc, err := market.LoadLocation(appCfg.Market.Timezone)
+	if err != nil {
+		return "", err
+	}
+	return time.Now().In(loc).Format("2006-01-02"), nil
+}
+
+// validateStockEvent normalises req and checks it. Ratios must be positive,
+// a merger needs a target symbol and only a merger may have one, a dividend
+// needs a record date and amount per share and only a dividend may have them,
+// only a merger may pay cash or cash out fractions, only a delisting has an
+// outcome and only an exit offer an exit price, both symbols must be known
+// instruments, and the effective date may only be in the past with Force.
+func validateStockEvent(ctx context.Context, q querier, req *models.StockEventRequest, today string) error {
+	req.StockSymbol = strings.ToUpper(strings.TrimSpace(req.StockSymbol))
+	req.TargetSymbol = strings.ToUpper(strings.TrimSpace(req.TargetSymbol))
+	req.EventType = strings.ToLower(strings.TrimSpace(req.EventType))
+	req.Note = strings.TrimSpace(req.Note)
+
+	if req.StockSymbol == "" {
+		return badRequest("stock_symbol is required")
+	}
+	switch req.EventType {
+	case models.EventSplit, models.EventBonus, models.EventMerger:
+		if req.RatioNum <= 0 || req.RatioDen <= 0 {
+			return badRequest("ratio_num and ratio_den must be positive")
+		}
+	case models.EventDelist, models.EventDividend:
+		// Neither has a ratio; store the neutral one.
+		req.RatioNum, req.RatioDen = 1, 1
+	default:
+		return badRequest("invalid event_type. must be one of: split, bonus, merger, delist, dividend")
+	}
+	if req.EventType == models.EventMerger {
+		if req.TargetSymbol == "" {
+			return badRequest("target_symbol is required for a merger")
+		}
+		if req.TargetSymbol == req.StockSymbol {
+			return badRequest("target_symbol must differ from stock_symbol")
+		}
+	} else if req.TargetSymbol != "" {
+		return badRequest("target_symbol is only allowed for a merger")
+	}
+	if len(req.Note) > maxStockEventNoteLength {
+		return badRequest("note must be at most 500 characters")
+	}
+
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(req.EffectiveDate))
+	if err != nil {
+		return badRequest("effective_date must be a date in YYYY-MM-DD format")
+	}
+	req.EffectiveDate = date.Format("2006-01-02")
+	if req.EventType == models.EventDividend {
+		req.AmountPerShare = utils.RoundAmount(req.AmountPerShare)
+		if req.AmountPerShare <= 0 {
+			return badRequest("amount_per_share must be positive for a dividend")
+		}
+		recordDate, err := time.Parse("2006-01-02", strings.TrimSpace(req.RecordDate))
+		if err != nil {
+			return badRequest("record_date must be a date in YYYY-MM-DD format")
+		}
+		req.RecordDate = recordDate.Format("2006-01-02")
+		if req.RecordDate > req.EffectiveDate {
+			return badRequest("record_date must not be after effective_date, the payment date")
+		}
+	} else if strings.TrimSpace(req.RecordDate) != "" || req.AmountPerShare != 0 {
+		return badRequest("record_date and amount_per_share are only allowed for a dividend")
+	}
+	req.FractionHandling = strings.ToLower(strings.TrimSpace(req.FractionHandling))
+	if req.EventType == models.EventMerger {
+		req.CashPerShare = utils.RoundAmount(req.CashPerShare)
+		req.FractionPrice = utils.RoundAmount(req.FractionPrice)
+		if req.CashPerShare < 0 {
+			return badRequest("cash_per_share must not be negative")
+		}
+		switch req.FractionHandling {
+		case "":
+			req.FractionHandling = models.FractionKeep
+		case models.FractionKeep, models.FractionCash:
+		default:
+			return badRequest("invalid fraction_handling. must be one of: fractional, cash")
+		}
+		if req.FractionPrice < 0 {
+			return badRequest("fraction_price must not be negative")
+		}
+		if req.FractionPrice != 0 && req.FractionHandling != models.FractionCash {
+			return badRequest("fraction_price is only allowed with fraction_handling cash")
+		}
+	} else {
+		if req.CashPerShare != 0 || req.FractionPrice != 0 ||
+			(req.FractionHandling != "" && req.FractionHandling != models.FractionKeep) {
+			return badRequest("cash_per_share, fraction_handling and fraction_price are only allowed for a merger")
+		}
+		req.FractionHandling = models.FractionKeep
+	}
+	req.DelistOutcome = strings.ToLower(strings.TrimSpace(req.DelistOutcome))
+	if req.EventType == models.EventDelist {
+		req.ExitPrice = utils.RoundAmount(req.ExitPrice)
+		switch req.DelistOutcome {
+		case "":
+			req.DelistOutcome = models.DelistUnlisted
+		case models.DelistExitOffer, models.DelistWriteOff, models.DelistUnlisted:
+		default:
+			return badRequest("invalid delist_outcome. must be one of: exit_offer, write_off, unlisted")
+		}
+		if req.DelistOutcome == models.DelistExitOffer && req.ExitPrice <= 0 {
+			return badRequest("exit_price must be positive for an exit offer")
+		}
+		if req.DelistOutcome != models.DelistExitOffer && req.ExitPrice != 0 {
+			return badRequest("exit_price is only allowed for an exit offer")
+		}
+	} else if req.DelistOutcome != "" || req.ExitPrice != 0 {
+		return badRequest("delist_outcome and exit_price are only allowed for a delisting")
+	}
+	if req.EffectiveDate < today && !req.Force {
+		return badRequest("effective_date is in the past; set force to apply the event retroactively")
+	}
+
+	// Events are stored under the canonical symbols, so an alias or ISIN in
+	// the request names the same instrument.
+	inst, ok, err := findInstrument(ctx, q, req.StockSymbol)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return badRequest("unknown stock_symbol " + req.StockSymbol)
+	}
+	req.StockSymbol = inst.Symbol
+	if req.TargetSymbol != "" {
+		target, ok, err := findInstrument(ctx, q, req.TargetSymbol)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return badRequest("unknown target_symbol " + req.TargetSymbol)
+		}
+		if target.ID == inst.ID {
+			return badRequest("target_symbol must differ from stock_symbol")
+		}
+		req.TargetSymbol = target.Symbol
+		if req.FractionHandling == models.FractionCash && req.FractionPrice == 0 {
+			_, ok, err := fractionPrice(ctx, q, *req)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return badRequest("fraction_price is required: " + target.Symbol + " has no price yet")
+			}
+		}
+	}
+	return nil
+}
+
+// fractionPrice is the price a merger pays per fractional target share: its
+// fraction_price, else the target's stored price, as apply_stock_event takes it.
+func fractionPrice(ctx context.Context, q querier, req models.StockEventRequest) (float64, bool, error) {
+	if req.FractionPrice > 0 {
+		return req.FractionPrice, true, nil
+	}
+	var price float64
+	err := q.QueryRowContext(ctx, `
+		SELECT price FROM stock_prices WHERE instrument_id = resolve_instrument($1)
+	`, req.TargetSymbol).Scan(&price)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return price, err == nil, err
+}
+
+// checkDuplicateEvent rejects a second event of the same type on the same
+// instrument and date; excludeID skips the event being updated.
+func checkDuplicateEvent(ctx context.Context, q querier, req models.StockEventRequest, excludeID int) error {
+	var id int
+	err := q.QueryRowContext(ctx, `
+		SELECT id FROM stock_events
+		WHERE instrument_id = resolve_instrument($1) AND event_type = $2 AND effective_date = $3 AND id <> $4
+		LIMIT 1
+	`, req.StockSymbol, req.EventType, req.EffectiveDate, excludeID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conflict("a " + req.EventType + " of " + req.StockSymbol + " on " + req.EffectiveDate + " already exists")
+}
+
+func auditStockEvent(ctx context.Context, tx *sql.Tx, eventID int, action, actor string, forced bool, before, after *models.Stock_Events) error {
+	toJSON := func(e *models.Stock_Events) (interface{}, error) {
+		if e == nil {
+			return nil, nil
+		}
+		b, err := json.Marshal(e)
+		return string(b), err
+	}
+	b, err := toJSON(before)
+	if err != nil {
+		return err
+	}
+	a, err := toJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO stock_event_audit (event_id, action, actor, forced, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, NOW())
+	`, eventID, action, actor, forced, b, a)
+	return err
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullIfZero(f float64) interface{} {
+	if f == 0 {
+		return nil
+	}
+	return f
+}
+
+func bindStockEvent(c *gin.Context, logger *logrus.Entry) (models.StockEventRequest, bool) {
+	var req models.StockEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithError(err).Warn("Invalid stock event payload")
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("Invalid request payload"))
+		return req, false
+	}
+	return req, true
+}
+
+// listStockEvents lists stock events, optionally by symbol, type or only
+// those not yet in effect (upcoming=true).
+func listStockEvents(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	today, err := marketToday()
+	if err != nil {
+		logger.WithError(err).Error("Failed to read the market date")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+stockEventColumns+` FROM stock_events
+		WHERE ($1 = '' OR resolve_instrument($1) IN (instrument_id, target_instrument_id))
+		  AND ($2 = '' OR event_type::text = $2)
+		  AND (NOT $3 OR effective_date > $4::date)
+		ORDER BY effective_date DESC, id DESC
+	`, strings.TrimSpace(c.Query("symbol")), strings.ToLower(c.Query("event_type")), c.Query("upcoming") == "true", today)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock events")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	var events []models.Stock_Events
+	for rows.Next() {
+		e, err := scanStockEvent(rows)
+		if err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		events = append(events, e)
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"events": utils.OrEmpty(events),
+	})
+}
+
+// getStockEvent returns an event with its audit trail.
+func getStockEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "stock event id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"event_id":   id,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event, err := scanStockEvent(db.QueryRowContext(ctx, `SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock event not found"))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT action, actor, forced, before, after, created_at
+		FROM stock_event_audit WHERE event_id = $1 ORDER BY created_at, id
+	`, id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock event audit")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	type auditEntry struct {
+		Action    string          `json:"action"`
+		Actor     string          `json:"actor"`
+		Forced    bool            `json:"forced"`
+		Before    json.RawMessage `json:"before"`
+		After     json.RawMessage `json:"after"`
+		CreatedAt string          `json:"created_at"`
+	}
+	var audit []auditEntry
+	for rows.Next() {
+		var a auditEntry
+		var before, after []byte
+		if err := rows.Scan(&a.Action, &a.Actor, &a.Forced, &before, &after, &a.CreatedAt); err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		if before != nil {
+			a.Before = before
+		}
+		if after != nil {
+			a.After = after
+		}
+		audit = append(audit, a)
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"data":  event,
+		"audit": utils.OrEmpty(audit),
+	})
+}
+
+func createStockEvent(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	req, ok := bindStockEvent(c, logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	today, err := marketToday()
+	if err == nil {
+		err = validateStockEvent(ctx, tx, &req, today)
+	}
+	if err == nil {
+		err = checkDuplicateEvent(ctx, tx, req, 0)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to validate stock event")
+		return
+	}
+
+	event, err := scanStockEvent(tx.QueryRowContext(ctx, `
+		INSERT INTO stock_events
+			(stock_symbol, event_type, ratio_num, ratio_den, effective_date, target_symbol, record_date, amount_per_share,
+			 cash_per_share, fraction_handling, fraction_price, delist_outcome, exit_price, note, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+		RETURNING `+stockEventColumns,
+		req.StockSymbol, req.EventType, req.RatioNum, req.RatioDen, req.EffectiveDate,
+		nullIfEmpty(req.TargetSymbol), nullIfEmpty(req.RecordDate), nullIfZero(req.AmountPerShare),
+		nullIfZero(req.CashPerShare), req.FractionHandling, nullIfZero(req.FractionPrice),
+		nullIfEmpty(req.DelistOutcome), nullIfZero(req.ExitPrice),
+		nullIfEmpty(req.Note), actor))
+	if err != nil {
+		logger.WithError(err).Error("Failed to insert stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if event, err = applyInEffect(ctx, tx, event, today); err != nil {
+		writeError(c, logger, err, "Failed to apply stock event")
+		return
+	}
+	forced := req.EffectiveDate < today
+	if err := auditStockEvent(ctx, tx, event.ID, "create", actor, forced, nil, &event); err != nil {
+		logger.WithError(err).Error("Failed to audit stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"event_id":   event.ID,
+		"symbol":     event.Stock_Symbol,
+		"event_type": event.EventType,
+		"actor":      actor,
+		"forced":     forced,
+		"applied":    event.AppliedAt != nil,
+	}).Info("Stock event created")
+	response.WriteJson(c.Writer, http.StatusCreated, map[string]interface{}{
+		"message": "Stock event created successfully",
+		"data":    event,
+	})
+}
+
+// updateStockEvent replaces an event. An event already in effect has
+// changed holdings, so it can only be changed with force; its ledger rows are
+// cancelled and, if it is still in effect, posted again as changed.
+func updateStockEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "stock event id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"event_id":   id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	req, ok := bindStockEvent(c, logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	existing, err := scanStockEvent(tx.QueryRowContext(ctx, `
+		SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1 FOR UPDATE
+	`, id))
+	if err == sql.ErrNoRows {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock event not found"))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	today, err := marketToday()
+	if err == nil && existing.EffectiveDate <= today && !req.Force {
+		err = conflict("stock event is already in effect; set force to change it")
+	}
+	if err == nil {
+		err = validateStockEvent(ctx, tx, &req, today)
+	}
+	if err == nil {
+		err = checkDuplicateEvent(ctx, tx, req, id)
+	}
+	if err == nil {
+		err = unapplyEvent(ctx, tx, existing)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to validate stock event")
+		return
+	}
+
+	event, err := scanStockEvent(tx.QueryRowContext(ctx, `
+		UPDATE stock_events
+		SET stock_symbol = $2, event_type = $3, ratio_num = $4, ratio_den = $5, effective_date = $6,
+			target_symbol = $7, record_date = $8, amount_per_share = $9, cash_per_share = $10,
+			fraction_handling = $11, fraction_price = $12, delist_outcome = $13, exit_price = $14,
+			note = $15, updated_by = $16, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+stockEventColumns,
+		id, req.StockSymbol, req.EventType, req.RatioNum, req.RatioDen, req.EffectiveDate,
+		nullIfEmpty(req.TargetSymbol), nullIfEmpty(req.RecordDate), nullIfZero(req.AmountPerShare),
+		nullIfZero(req.CashPerShare), req.FractionHandling, nullIfZero(req.FractionPrice),
+		nullIfEmpty(req.DelistOutcome), nullIfZero(req.ExitPrice),
+		nullIfEmpty(req.Note), actor))
+	if err != nil {
+		logger.WithError(err).Error("Failed to update stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if event, err = applyInEffect(ctx, tx, event, today); err != nil {
+		writeError(c, logger, err, "Failed to apply stock event")
+		return
+	}
+	forced := existing.EffectiveDate <= today || req.EffectiveDate < today
+	if err := auditStockEvent(ctx, tx, id, "update", actor, forced, &existing, &event); err != nil {
+		logger.WithError(err).Error("Failed to audit stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{"actor": actor, "forced": forced}).Info("Stock event updated")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Stock event updated successfully",
+		"data":    event,
+	})
+}
+
+// deleteStockEvent removes an event; one already in effect needs
+// ?force=true, and its ledger rows are cancelled.
+func deleteStockEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "stock event id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"event_id":   id,
+	})
+
+	actor, ok := requireActor(c)
+	if !ok {
+		return
+	}
+	force := c.Query("force") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		logger.WithError(err).Error("Failed to begin transaction")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer tx.Rollback()
+
+	existing, err := scanStockEvent(tx.QueryRowContext(ctx, `
+		SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1 FOR UPDATE
+	`, id))
+	if err == sql.ErrNoRows {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock event not found"))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	today, err := marketToday()
+	if err == nil && existing.EffectiveDate <= today && !force {
+		err = conflict("stock event is already in effect; pass force=true to delete it")
+	}
+	if err == nil {
+		err = unapplyEvent(ctx, tx, existing)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to check stock event")
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_events WHERE id = $1`, id); err != nil {
+		logger.WithError(err).Error("Failed to delete stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	forced := existing.EffectiveDate <= today
+	if err := auditStockEvent(ctx, tx, id, "delete", actor, forced, &existing, nil); err != nil {
+		logger.WithError(err).Error("Failed to audit stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		logger.WithError(err).Error("Failed to commit stock event deletion")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	logger.WithFields(logrus.Fields{"actor": actor, "forced": forced}).Info("Stock event deleted")
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"message": "Stock event deleted successfully",
+		"data":    existing,
+	})
+}
+
+// previewStockEvent validates an event without saving it and lists the
+// users whose holdings it would change: the lots granted before its effective
+// date that hold the symbol now, as the corporate actions job selects them. A
+// delisting settles every lot holding the symbol; the other events are
+// applied to them. For a dividend it lists the holders on the record date and
+// their gross cash, and for a merger or exit offer the cash it pays.
+func previewStockEvent(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	req, ok := bindStockEvent(c, logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	today, err := marketToday()
+	if err == nil {
+		err = validateStockEvent(ctx, db, &req, today)
+	}
+	if err != nil {
+		writeError(c, logger, err, "Failed to validate stock event")
+		return
+	}
+
+	impacts, err := stockEventImpact(ctx, db, req)
+	if err != nil {
+		logger.WithError(err).Error("Failed to preview stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	var lots int
+	var before, after, fraction, cash float64
+	for _, i := range impacts {
+		lots += i.Lots
+		before += i.UnitsBefore
+		after += i.UnitsAfter
+		fraction += i.FractionUnits
+		cash += i.CashINR
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"event":              req,
+		"retroactive":        req.EffectiveDate < today,
+		"affectedUsers":      len(impacts),
+		"affectedLots":       lots,
+		"totalUnitsBefore":   utils.RoundQuantity(before),
+		"totalUnitsAfter":    utils.RoundQuantity(after),
+		"totalFractionUnits": utils.RoundQuantity(fraction),
+		"totalCashInr":       utils.RoundAmount(cash),
+		"users":              utils.OrEmpty(impacts),
+	})
+}
+
+// stockEventImpact sums each affected user's units from the ledger and applies
+// the event to them, rounding each lot as the ledger posting does. Dividend
+// holdings are those in effect by the end of the record date, by the rows'
+// business date, as the dividend payment counts them; a delisting takes every
+// lot, whenever granted. A merger that cashes out fractions delivers each
+// user the whole shares of their total.
+func stockEventImpact(ctx context.Context, q querier, req models.StockEventRequest) ([]models.StockEventImpact, error) {
+	dividend := req.EventType == models.EventDividend
+	date := req.EffectiveDate
+	if dividend {
+		date = req.RecordDate
+	}
+	var price float64
+	if req.EventType == models.EventMerger && req.FractionHandling == models.FractionCash {
+		var err error
+		if price, _, err = fractionPrice(ctx, q, req); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT l.user_id, COUNT(*), SUM(l.units), SUM(ROUND(l.units * $4::numeric / $5::numeric, 6))
+		FROM (
+			SELECT r.user_id, lg.reward_id, SUM(lg.quantity) AS units
+			FROM ledger lg
+			JOIN rewards r ON r.id = lg.reward_id
+			WHERE lg.entry_type = 'stock_units'
+			  AND lg.instrument_id = resolve_instrument($1)
+			  AND CASE WHEN $3 THEN lg.business_date <= $2::date
+			           WHEN $6 THEN TRUE
+			           ELSE r.created_at::date < $2::date END
+			GROUP BY r.user_id, lg.reward_id
+		) l
+		WHERE l.units > 0
+		GROUP BY l.user_id
+		ORDER BY l.user_id
+	`, req.StockSymbol, date, dividend, req.RatioNum, req.RatioDen, req.EventType == models.EventDelist)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.StockEventImpact
+	for rows.Next() {
+		var i models.StockEventImpact
+		var entitled float64
+		if err := rows.Scan(&i.UserID, &i.Lots, &i.UnitsBefore, &entitled); err != nil {
+			return nil, err
+		}
+		switch req.EventType {
+		case models.EventDelist:
+			i.UnitsAfter = 0
+			i.CashINR = utils.RoundAmount(i.UnitsBefore * req.ExitPrice)
+		case models.EventDividend:
+			i.UnitsAfter = i.UnitsBefore
+			i.SymbolAfter = req.StockSymbol
+			i.CashINR = utils.RoundAmount(i.UnitsBefore * req.AmountPerShare)
+		case models.EventMerger:
+			i.UnitsAfter = utils.RoundQuantity(entitled)
+			i.SymbolAfter = req.TargetSymbol
+			if req.FractionHandling == models.FractionCash {
+				whole := math.Floor(i.UnitsAfter)
+				i.FractionUnits = utils.RoundQuantity(i.UnitsAfter - whole)
+				i.UnitsAfter = whole
+			}
+			i.CashINR = utils.RoundAmount(i.UnitsBefore*req.CashPerShare + i.FractionUnits*price)
+		default:
+			i.UnitsAfter = entitled
+			i.SymbolAfter = req.StockSymbol
+		}
+		i.UnitsBefore = utils.RoundQuantity(i.UnitsBefore)
+		i.UnitsAfter = utils.RoundQuantity(i.UnitsAfter)
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,148 @@
+package stocky
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+)
+
+// stockEventTestToday is the date validateStockEvent is told it is.
+const stockEventTestToday = "2026-10-18"
+
+// validStockEvent is a request that passes every check made before the
+// symbols are looked up; cases edit a copy of it.
+var validStockEvent = models.StockEventRequest{
+	StockSymbol:   "TCS",
+	EventType:     models.EventSplit,
+	RatioNum:      2,
+	RatioDen:      1,
+	EffectiveDate: "2026-10-20",
+}
+
+// stockEventCase is shared by the validateStockEvent tests of later event
+// types, which only run cases rejected before the database is reached.
+type stockEventCase struct {
+	name    string
+	edit    func(req *models.StockEventRequest)
+	wantErr string
+}
+
+func runStockEventCases(t *testing.T, tests []stockEventCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validStockEvent
+			tt.edit(&req)
+			err := validateStockEvent(context.Background(), nil, &req, stockEventTestToday)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStockEventRejectsBadInput(t *testing.T) {
+	runStockEventCases(t, []stockEventCase{
+		{name: "no symbol", edit: func(r *models.StockEventRequest) { r.StockSymbol = "  " }, wantErr: "stock_symbol is required"},
+		{name: "unknown type", edit: func(r *models.StockEventRequest) { r.EventType = "rights" }, wantErr: "invalid event_type"},
+		{name: "zero ratio", edit: func(r *models.StockEventRequest) { r.RatioDen = 0 }, wantErr: "must be positive"},
+		{name: "negative ratio", edit: func(r *models.StockEventRequest) { r.RatioNum = -1 }, wantErr: "must be positive"},
+		{name: "merger without target", edit: func(r *models.StockEventRequest) { r.EventType = models.EventMerger }, wantErr: "target_symbol is required"},
+		{name: "merger into itself", edit: func(r *models.StockEventRequest) {
+			r.EventType, r.TargetSymbol = models.EventMerger, " tcs"
+		}, wantErr: "must differ"},
+		{name: "target on a split", edit: func(r *models.StockEventRequest) { r.TargetSymbol = "INFY" }, wantErr: "only allowed for a merger"},
+		{name: "long note", edit: func(r *models.StockEventRequest) { r.Note = strings.Repeat("x", maxStockEventNoteLength+1) }, wantErr: "at most 500"},
+		{name: "bad date", edit: func(r *models.StockEventRequest) { r.EffectiveDate = "20/10/2026" }, wantErr: "YYYY-MM-DD"},
+		{name: "past date without force", edit: func(r *models.StockEventRequest) { r.EffectiveDate = "2026-10-17" }, wantErr: "set force"},
+	})
+}
+
+func TestValidateStockEventNormalisesInput(t *testing.T) {
+	// A past date stops validation just before the symbol lookup, after
+	// every field has been normalised.
+	req := models.StockEventRequest{
+		StockSymbol:   " tcs ",
+		EventType:     " DELIST ",
+		RatioNum:      5,
+		RatioDen:      3,
+		EffectiveDate: " 2026-10-01 ",
+		Note:          "  delisted by exchange  ",
+	}
+	if err := validateStockEvent(context.Background(), nil, &req, stockEventTestToday); err == nil {
+		t.Fatal("expected the past date to be rejected")
+	}
+	want := models.StockEventRequest{
+		StockSymbol:      "TCS",
+		EventType:        models.EventDelist,
+		RatioNum:         1,
+		RatioDen:         1,
+		EffectiveDate:    "2026-10-01",
+		FractionHandling: models.FractionKeep,
+		DelistOutcome:    models.DelistUnlisted,
+		Note:             "delisted by exchange",
+	}
+	if req != want {
+		t.Errorf("request = %+v, want %+v", req, want)
+	}
+}
+
+func TestValidateStockEventRejectsBadDividend(t *testing.T) {
+	dividend := func(r *models.StockEventRequest) {
+		r.EventType, r.RecordDate, r.AmountPerShare = models.EventDividend, "2026-10-19", 12.5
+	}
+	runStockEventCases(t, []stockEventCase{
+		{name: "no amount", edit: func(r *models.StockEventRequest) { dividend(r); r.AmountPerShare = 0 }, wantErr: "amount_per_share must be positive"},
+		{name: "amount rounds to zero", edit: func(r *models.StockEventRequest) { dividend(r); r.AmountPerShare = 0.00001 }, wantErr: "amount_per_share must be positive"},
+		{name: "no record date", edit: func(r *models.StockEventRequest) { dividend(r); r.RecordDate = "" }, wantErr: "record_date must be a date"},
+		{name: "record date after payment", edit: func(r *models.StockEventRequest) { dividend(r); r.RecordDate = "2026-10-21" }, wantErr: "must not be after effective_date"},
+		{name: "target on a dividend", edit: func(r *models.StockEventRequest) { dividend(r); r.TargetSymbol = "INFY" }, wantErr: "only allowed for a merger"},
+		{name: "record date on a split", edit: func(r *models.StockEventRequest) { r.RecordDate = "2026-10-19" }, wantErr: "only allowed for a dividend"},
+		{name: "amount on a split", edit: func(r *models.StockEventRequest) { r.AmountPerShare = 1 }, wantErr: "only allowed for a dividend"},
+		{name: "past payment date without force", edit: func(r *models.StockEventRequest) {
+			dividend(r)
+			r.EffectiveDate, r.RecordDate = "2026-10-17", "2026-10-16"
+		}, wantErr: "set force"},
+	})
+}
+
+func TestValidateStockEventRejectsBadMergerCash(t *testing.T) {
+	merger := func(r *models.StockEventRequest) {
+		r.EventType, r.TargetSymbol = models.EventMerger, "INFY"
+	}
+	runStockEventCases(t, []stockEventCase{
+		{name: "negative cash", edit: func(r *models.StockEventRequest) { merger(r); r.CashPerShare = -1 }, wantErr: "cash_per_share must not be negative"},
+		{name: "unknown fraction handling", edit: func(r *models.StockEventRequest) { merger(r); r.FractionHandling = "round" }, wantErr: "invalid fraction_handling"},
+		{name: "negative fraction price", edit: func(r *models.StockEventRequest) {
+			merger(r)
+			r.FractionHandling, r.FractionPrice = models.FractionCash, -5
+		}, wantErr: "fraction_price must not be negative"},
+		{name: "fraction price when fractions are kept", edit: func(r *models.StockEventRequest) { merger(r); r.FractionPrice = 100 }, wantErr: "only allowed with fraction_handling cash"},
+		{name: "cash on a split", edit: func(r *models.StockEventRequest) { r.CashPerShare = 10 }, wantErr: "only allowed for a merger"},
+		{name: "cashed-out fractions on a bonus", edit: func(r *models.StockEventRequest) {
+			r.EventType, r.FractionHandling = models.EventBonus, models.FractionCash
+		}, wantErr: "only allowed for a merger"},
+	})
+}
+
+func TestValidateStockEventRejectsBadDelisting(t *testing.T) {
+	delist := func(r *models.StockEventRequest) {
+		r.EventType, r.RatioNum, r.RatioDen = models.EventDelist, 0, 0
+	}
+	runStockEventCases(t, []stockEventCase{
+		{name: "unknown outcome", edit: func(r *models.StockEventRequest) { delist(r); r.DelistOutcome = "buyback" }, wantErr: "invalid delist_outcome"},
+		{name: "exit offer without price", edit: func(r *models.StockEventRequest) { delist(r); r.DelistOutcome = models.DelistExitOffer }, wantErr: "exit_price must be positive"},
+		{name: "exit price that rounds to zero", edit: func(r *models.StockEventRequest) {
+			delist(r)
+			r.DelistOutcome, r.ExitPrice = models.DelistExitOffer, 0.00001
+		}, wantErr: "exit_price must be positive"},
+		{name: "exit price on a write-off", edit: func(r *models.StockEventRequest) {
+			delist(r)
+			r.DelistOutcome, r.ExitPrice = models.DelistWriteOff, 10
+		}, wantErr: "only allowed for an exit offer"},
+		{name: "outcome on a split", edit: func(r *models.StockEventRequest) { r.DelistOutcome = models.DelistWriteOff }, wantErr: "only allowed for a delisting"},
+		{name: "exit price on a split", edit: func(r *models.StockEventRequest) { r.ExitPrice = 10 }, wantErr: "only allowed for a delisting"},
+	})
+}
@@ -0,0 +1,171 @@
+package stocky
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	interval1h = "1h"
+	interval1d = "1d"
+)
+
+// Default and maximum lookback per interval, so a request without from/to
+// stays small and hourly bars are never built over years of ticks.
+var (
+	defaultHistoryRange = map[string]time.Duration{interval1h: 7 * 24 * time.Hour, interval1d: 90 * 24 * time.Hour}
+	maxHistoryRange     = map[string]time.Duration{interval1h: 31 * 24 * time.Hour, interval1d: 5 * 366 * 24 * time.Hour}
+)
+
+// parseHistoryTime accepts a date (YYYY-MM-DD) or an RFC 3339 timestamp. A
+// date used as the upper bound covers the whole day.
+func parseHistoryTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// GetStockHistory returns OHLC bars for a symbol. interval=1d (default) reads
+// the daily bars; interval=1h aggregates the stored ticks per hour. from is
+// inclusive and to exclusive when given as timestamps; a to date includes
+// that whole day.
+func GetStockHistory(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"symbol":     symbol,
+	})
+
+	interval := c.DefaultQuery("interval", interval1d)
+	if interval != interval1h && interval != interval1d {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid interval. must be one of: 1h, 1d"))
+		return
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := parseHistoryTime(v, true)
+		if err != nil {
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid to – expected YYYY-MM-DD or RFC 3339"))
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultHistoryRange[interval])
+	if v := c.Query("from"); v != "" {
+		t, err := parseHistoryTime(v, false)
+		if err != nil {
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid from – expected YYYY-MM-DD or RFC 3339"))
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("from must be before to"))
+		return
+	}
+	if to.Sub(from) > maxHistoryRange[interval] {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("requested range is too large for interval "+interval))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// History follows the instrument, so bars recorded under a former symbol
+	// are included.
+	instrument, found, err := findInstrument(ctx, db, symbol)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up instrument")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if !found {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock not found"))
+		return
+	}
+
+	var query string
+	if interval == interval1d {
+		// A rename during the day leaves a bar under each symbol; they are
+		// merged in the order they were last updated.
+		query = `
+			SELECT date::timestamptz,
+				(ARRAY_AGG(open ORDER BY updated_at))[1],
+				MAX(high),
+				MIN(low),
+				(ARRAY_AGG(close ORDER BY updated_at DESC))[1],
+				SUM(tick_count)
+			FROM stock_price_daily_bars
+			WHERE instrument_id = $1 AND date >= $2::date AND date < $3::date
+			GROUP BY date
+			ORDER BY date`
+	} else {
+		query = `
+			SELECT date_trunc('hour', fetched_at) AS bucket,
+				(ARRAY_AGG(price ORDER BY fetched_at, id))[1],
+				MAX(price),
+				MIN(price),
+				(ARRAY_AGG(price ORDER BY fetched_at DESC, id DESC))[1],
+				COUNT(*)
+			FROM stock_price_ticks
+			WHERE instrument_id = $1 AND fetched_at >= $2 AND fetched_at < $3
+			  AND price_source <> 'carried_forward'
+			GROUP BY bucket
+			ORDER BY bucket`
+	}
+
+	rows, err := db.QueryContext(ctx, query, instrument.ID, from, to)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch price history")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	var bars []models.PriceBar
+	for rows.Next() {
+		var b models.PriceBar
+		var t time.Time
+		if err := rows.Scan(&t, &b.Open, &b.High, &b.Low, &b.Close, &b.TickCount); err != nil {
+			logger.WithError(err).Error("scan error")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		if interval == interval1d {
+			b.Time = t.Format("2006-01-02")
+		} else {
+			b.Time = t.Format(time.RFC3339)
+		}
+		b.Open = utils.RoundAmount(b.Open)
+		b.High = utils.RoundAmount(b.High)
+		b.Low = utils.RoundAmount(b.Low)
+		b.Close = utils.RoundAmount(b.Close)
+		bars = append(bars, b)
+	}
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("row iteration error")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"symbol":   instrument.Symbol,
+		"interval": interval,
+		"from":     from.Format(time.RFC3339),
+		"to":       to.Format(time.RFC3339),
+		"bars":     utils.OrEmpty(bars),
+	})
+}
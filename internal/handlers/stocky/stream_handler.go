@@ -0,0 +1,254 @@
+package stocky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/jobs"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// streamHeartbeat keeps idle connections (and proxies) from timing out.
+	streamHeartbeat = 15 * time.Second
+	// streamWriteTimeout drops clients that stop reading; the updater never
+	// waits for them since updates are coalesced per subscriber.
+	streamWriteTimeout = 10 * time.Second
+	// portfolioDebounce lets a refresh finish publishing before the
+	// portfolio is recomputed once for the whole batch.
+	portfolioDebounce = 500 * time.Millisecond
+)
+
+// sseStream writes server-sent events to one client.
+type sseStream struct {
+	c  *gin.Context
+	rc *http.ResponseController
+}
+
+// startStream subscribes to price updates for symbols and switches the
+// response to an event stream. It rejects the request when too many streams
+// are open. The caller must unsubscribe when the stream ends.
+func startStream(c *gin.Context, symbols []string) (*sseStream, *jobs.PriceSubscription, bool) {
+	sub, err := jobs.SubscribePrices(symbols)
+	if err != nil {
+		response.WriteJson(c.Writer, http.StatusServiceUnavailable, response.ErrorResponse("too many open streams, try again later"))
+		return nil, nil, false
+	}
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	return &sseStream{c: c, rc: http.NewResponseController(c.Writer)}, sub, true
+}
+
+func (s *sseStream) write(payload []byte) error {
+	if err := s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+	if _, err := s.c.Writer.Write(payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseStream) event(name string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.write([]byte(fmt.Sprintf("event: %s\ndata: %s\n\n", name, body)))
+}
+
+func (s *sseStream) heartbeat() error {
+	return s.write([]byte(": heartbeat\n\n"))
+}
+
+// StreamPrices pushes price changes as the updater stores them. The stream
+// opens with a "snapshot" event of the current prices followed by "prices"
+// events; symbols limits it to a comma-separated list.
+func StreamPrices(c *gin.Context) {
+	logger := logrus.WithField("request_id", requestID(c))
+
+	symbols := []string{}
+	for _, s := range strings.Split(c.Query("symbols"), ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			symbols = append(symbols, s)
+		}
+	}
+
+	// The subscription is opened before the snapshot is read so no update
+	// falls in between.
+	stream, sub, ok := startStream(c, symbols)
+	if !ok {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	ctx := c.Request.Context()
+	snapshot, err := currentPrices(ctx, symbols)
+	if err != nil {
+		logger.WithError(err).Error("Failed to load price snapshot")
+		_ = stream.event("error", response.ErrorResponse("failed to load prices"))
+		return
+	}
+	if err := stream.event("snapshot", utils.OrEmpty(snapshot)); err != nil {
+		return
+	}
+
+	logger.WithField("symbols", len(symbols)).Info("Price stream opened")
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Price stream closed by client")
+			return
+		case <-ticker.C:
+			if err := stream.heartbeat(); err != nil {
+				logger.WithError(err).Info("Price stream dropped")
+				return
+			}
+		case _, open := <-sub.Ready():
+			if !open {
+				return
+			}
+			if err := stream.event("prices", sub.Drain()); err != nil {
+				logger.WithError(err).Info("Price stream dropped")
+				return
+			}
+		}
+	}
+}
+
+func currentPrices(ctx context.Context, symbols []string) ([]jobs.PriceUpdate, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT stock_symbol, price, price_source, updated_at
+		FROM stock_prices
+		WHERE cardinality($1::text[]) = 0 OR UPPER(stock_symbol) = ANY($1)
+		ORDER BY stock_symbol
+	`, pq.Array(symbols))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []jobs.PriceUpdate
+	for rows.Next() {
+		var u jobs.PriceUpdate
+		if err := rows.Scan(&u.Symbol, &u.Price, &u.Source, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		u.Price = utils.RoundAmount(u.Price)
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// portfolioEvent is the payload of the portfolio stream.
+type portfolioEvent struct {
+	UserID     int                    `json:"userId"`
+	Portfolio  []models.PortfolioItem `json:"portfolio"`
+	TotalValue float64                `json:"totalValue"`
+	AsOf       time.Time              `json:"asOf"`
+}
+
+// StreamPortfolio pushes the user's portfolio, revalued whenever the price of
+// a stock they hold changes. Unchanged valuations are not resent.
+func StreamPortfolio(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"user_id":    userID,
+	})
+
+	stream, sub, ok := startStream(c, nil)
+	if !ok {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	ctx := c.Request.Context()
+	held := map[string]bool{}
+	var last []byte
+	push := func() error {
+		portfolio, err := loadPortfolio(ctx, userID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to revalue streamed portfolio")
+			return stream.event("error", response.ErrorResponse("failed to load portfolio"))
+		}
+		held = make(map[string]bool, len(portfolio))
+		var total float64
+		for _, item := range portfolio {
+			held[strings.ToUpper(item.StockSymbol)] = true
+			total += item.INRValue
+		}
+		// Compare on the holdings only; AsOf changes every time.
+		key, _ := json.Marshal(portfolio)
+		if bytes.Equal(key, last) {
+			return nil
+		}
+		last = key
+		return stream.event("portfolio", portfolioEvent{
+			UserID:     userID,
+			Portfolio:  portfolio,
+			TotalValue: utils.RoundAmount(total),
+			AsOf:       time.Now(),
+		})
+	}
+	if err := push(); err != nil {
+		return
+	}
+
+	logger.Info("Portfolio stream opened")
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Portfolio stream closed by client")
+			return
+		case <-ticker.C:
+			if err := stream.heartbeat(); err != nil {
+				logger.WithError(err).Info("Portfolio stream dropped")
+				return
+			}
+		case _, open := <-sub.Ready():
+			if !open {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(portfolioDebounce):
+			}
+			touched := false
+			for _, u := range sub.Drain() {
+				if held[strings.ToUpper(u.Symbol)] {
+					touched = true
+				}
+			}
+			if !touched {
+				continue
+			}
+			if err := push(); err != nil {
+				logger.WithError(err).Info("Portfolio stream dropped")
+				return
+			}
+		}
+	}
+}
@@ -27,7 +27,8 @@ func GetTodayStocks(c *gin.Context) {
 			adjusted_quantity,
 			current_price,
 			total_adjustment_amount,
-			inr_value
+			inr_value,
+			price_source
 		FROM today_rewards
 		WHERE user_id = $1
 		ORDER BY stock_symbol, reward_event_id
@@ -50,6 +51,7 @@ func GetTodayStocks(c *gin.Context) {
 			&s.CurrentPrice,
 			&s.TotalAdjustmentAmount,
 			&s.INRValue,
+			&s.PriceSource,
 		); err != nil {
 			logger.WithError(err).Error("scan error")
 			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
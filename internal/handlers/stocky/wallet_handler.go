@@ -0,0 +1,207 @@
+package stocky
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/LoganX64/stocky-api/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const payoutColumns = `
+	id, event_id, user_id, stock_symbol, record_date::text, payment_date::text, units, amount_per_share,
+	gross_amount, tds_pct, tds_amount, net_amount, paid_at`
+
+// getWallet returns the user's INR balance and their latest wallet
+// transactions, newest first (limit, default 50, at most 200). A user who has
+// never been paid has a zero balance.
+func getWallet(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"user_id":    userID,
+	})
+	limit := defaultHistoryLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("invalid limit – must be a positive integer"))
+			return
+		}
+		limit = min(n, maxHistoryLimit)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wallet := models.Wallet{UserID: userID}
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(balance), 0), MAX(updated_at)::text FROM user_wallets WHERE user_id = $1
+	`, userID).Scan(&wallet.Balance, &wallet.UpdatedAt)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch wallet")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	wallet.Balance = utils.RoundAmount(wallet.Balance)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, txn_type, amount, balance_after, stock_event_id, dividend_payout_id, note, created_at
+		FROM wallet_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch wallet transactions")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	defer rows.Close()
+
+	txns := []models.WalletTransaction{}
+	for rows.Next() {
+		var t models.WalletTransaction
+		if err := rows.Scan(&t.ID, &t.TxnType, &t.Amount, &t.BalanceAfter, &t.StockEventID,
+			&t.DividendPayoutID, &t.Note, &t.CreatedAt); err != nil {
+			logger.WithError(err).Error("Failed to scan wallet transaction")
+			response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+			return
+		}
+		t.Amount = utils.RoundAmount(t.Amount)
+		t.BalanceAfter = utils.RoundAmount(t.BalanceAfter)
+		txns = append(txns, t)
+	}
+	if err := rows.Err(); err != nil {
+		logger.WithError(err).Error("Failed to read wallet transactions")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"userId":       userID,
+		"wallet":       wallet,
+		"transactions": txns,
+	})
+}
+
+// listUserDividends returns the dividends paid to a user, newest first, with
+// the gross, TDS and net totals.
+func listUserDividends(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"user_id":    userID,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	payouts, err := queryPayouts(ctx, `
+		SELECT `+payoutColumns+` FROM dividend_payouts
+		WHERE user_id = $1
+		ORDER BY payment_date DESC, id DESC
+	`, userID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch dividend payouts")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"userId":  userID,
+		"totals":  payoutTotals(payouts),
+		"payouts": payouts,
+	})
+}
+
+// listEventPayouts returns what each holder received from a dividend event.
+func listEventPayouts(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "stock event id")
+	if !ok {
+		return
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"request_id": requestID(c),
+		"event_id":   id,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	event, err := scanStockEvent(db.QueryRowContext(ctx, `SELECT `+stockEventColumns+` FROM stock_events WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		response.WriteJson(c.Writer, http.StatusNotFound, response.ErrorResponse("stock event not found"))
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch stock event")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	if event.EventType != models.EventDividend {
+		response.WriteJson(c.Writer, http.StatusBadRequest, response.ErrorResponse("stock event is not a dividend"))
+		return
+	}
+
+	payouts, err := queryPayouts(ctx, `
+		SELECT `+payoutColumns+` FROM dividend_payouts
+		WHERE event_id = $1
+		ORDER BY user_id
+	`, id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch dividend payouts")
+		response.WriteJson(c.Writer, http.StatusInternalServerError, response.ErrorResponse("internal server error"))
+		return
+	}
+	response.WriteJson(c.Writer, http.StatusOK, map[string]interface{}{
+		"event":   event,
+		"totals":  payoutTotals(payouts),
+		"payouts": payouts,
+	})
+}
+
+func queryPayouts(ctx context.Context, query string, args ...interface{}) ([]models.DividendPayout, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payouts := []models.DividendPayout{}
+	for rows.Next() {
+		var p models.DividendPayout
+		if err := rows.Scan(&p.ID, &p.EventID, &p.UserID, &p.StockSymbol, &p.RecordDate, &p.PaymentDate,
+			&p.Units, &p.AmountPerShare, &p.GrossAmount, &p.TDSPct, &p.TDSAmount, &p.NetAmount, &p.PaidAt); err != nil {
+			return nil, err
+		}
+		p.Units = utils.RoundQuantity(p.Units)
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
+
+func payoutTotals(payouts []models.DividendPayout) map[string]float64 {
+	var gross, tds, net float64
+	for _, p := range payouts {
+		gross += p.GrossAmount
+		tds += p.TDSAmount
+		net += p.NetAmount
+	}
+	return map[string]float64{
+		"gross": utils.RoundAmount(gross),
+		"tds":   utils.RoundAmount(tds),
+		"net":   utils.RoundAmount(net),
+	}
+}
@@ -0,0 +1,334 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// ApplyStockEvent posts an event in effect to the ledger and returns the
+// number of lots it changed. Events already applied or not yet in effect on
+// today, the exchange's date, are left alone. Cash a merger or an exit offer
+// pays is credited to the holders' wallets.
+func ApplyStockEvent(ctx context.Context, tx *sql.Tx, eventID int, today string) (int, error) {
+	var lots int
+	if err := tx.QueryRowContext(ctx, `SELECT apply_stock_event($1, $2::date)`, eventID, today).Scan(&lots); err != nil {
+		return 0, err
+	}
+	if lots == 0 {
+		return 0, nil
+	}
+	return lots, creditEventCash(ctx, tx, eventID)
+}
+
+// eventCash is what an event pays one holder: a merger's cash part and the
+// cash in lieu of units fractional target units at price, or an exit offer
+// for exitUnits.
+type eventCash struct {
+	userID         int
+	symbol, target string
+	cash, inLieu   float64
+	units          float64
+	price          sql.NullFloat64
+	exit           float64
+	exitUnits      float64
+}
+
+// creditEventCash credits each holder the cash part and the cash in lieu of
+// fractions posted for a merger, or the exit offer payout of a delisting, one
+// wallet transaction for each.
+func creditEventCash(ctx context.Context, tx *sql.Tx, eventID int) error {
+	credits, err := eventCashCredits(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	for _, c := range credits {
+		if c.cash > 0 {
+			note := fmt.Sprintf("Cash part of the %s merger into %s", c.symbol, c.target)
+			if err := creditWallet(ctx, tx, c.userID, models.WalletMergerCash, c.cash, eventID, 0, note); err != nil {
+				return err
+			}
+		}
+		if c.inLieu > 0 {
+			note := fmt.Sprintf("Cash in lieu of %.6f %s units at %.4f", c.units, c.target, c.price.Float64)
+			if err := creditWallet(ctx, tx, c.userID, models.WalletCashInLieu, c.inLieu, eventID, 0, note); err != nil {
+				return err
+			}
+		}
+		if c.exit > 0 {
+			note := fmt.Sprintf("Exit offer for %.6f %s units on delisting", c.exitUnits, c.symbol)
+			if err := creditWallet(ctx, tx, c.userID, models.WalletExitOffer, c.exit, eventID, 0, note); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func eventCashCredits(ctx context.Context, tx *sql.Tx, eventID int) ([]eventCash, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.user_id, MIN(a.symbol_before), MIN(a.symbol_after),
+			SUM(a.merger_cash), SUM(a.cash_in_lieu), SUM(a.fraction_units), MAX(a.fraction_price),
+			SUM(a.exit_payout), SUM(a.units_before) FILTER (WHERE a.exit_payout > 0)
+		FROM stock_event_applications a
+		JOIN rewards r ON r.id = a.reward_id
+		WHERE a.event_id = $1 AND a.reversed_at IS NULL
+		GROUP BY r.user_id
+		HAVING SUM(a.merger_cash) > 0 OR SUM(a.cash_in_lieu) > 0 OR SUM(a.exit_payout) > 0
+		ORDER BY r.user_id
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []eventCash
+	for rows.Next() {
+		var c eventCash
+		var exitUnits sql.NullFloat64
+		if err := rows.Scan(&c.userID, &c.symbol, &c.target, &c.cash, &c.inLieu, &c.units, &c.price,
+			&c.exit, &exitUnits); err != nil {
+			return nil, err
+		}
+		c.exitUnits = exitUnits.Float64
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// UnapplyStockEvent cancels the ledger rows of an applied event and marks it
+// pending again. Callers check first that no later event was applied to the
+// same lots.
+func UnapplyStockEvent(ctx context.Context, tx *sql.Tx, eventID int) (int, error) {
+	var lots int
+	err := tx.QueryRowContext(ctx, `SELECT unapply_stock_event($1)`, eventID).Scan(&lots)
+	return lots, err
+}
+
+// AppliedStockEvent is what a run did with one event. Lots counts the lots
+// changed, or for a dividend the holders paid.
+type AppliedStockEvent struct {
+	EventID       int    `json:"event_id"`
+	StockSymbol   string `json:"stock_symbol"`
+	EventType     string `json:"event_type"`
+	EffectiveDate string `json:"effective_date"`
+	Lots          int    `json:"lots"`
+	Error         string `json:"error,omitempty"`
+}
+
+// CorporateActionRun summarises one pass over the pending events.
+type CorporateActionRun struct {
+	Trigger    string              `json:"trigger"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Applied    int                 `json:"applied"`
+	Failed     int                 `json:"failed"`
+	Lots       int                 `json:"lots"`
+	Events     []AppliedStockEvent `json:"events"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// TriggerStartup marks the catch-up pass run when the job starts.
+const TriggerStartup = "startup"
+
+// CorporateActions posts stock events to the ledger once they take effect,
+// settles delistings, and pays dividends on their payment date.
+// Events are applied oldest first, each in its own transaction. A failure
+// ends the run, since later events may build on the failed one's units; the
+// next run retries from there.
+type CorporateActions struct {
+	db       *sql.DB
+	cfg      config.CorporateActions
+	location *time.Location
+	leader   Leadership
+
+	running sync.Mutex
+
+	mu      sync.RWMutex
+	lastRun *CorporateActionRun
+	cron    *cron.Cron
+	entry   cron.EntryID
+}
+
+func NewCorporateActions(db *sql.DB, cfg config.CorporateActions, location *time.Location) *CorporateActions {
+	return &CorporateActions{db: db, cfg: cfg, location: location}
+}
+
+// SetLeadership makes scheduled runs conditional on l. Without it every
+// instance runs them.
+func (j *CorporateActions) SetLeadership(l Leadership) {
+	j.leader = l
+}
+
+// catchUpPollInterval is how often a follower checks whether it has become
+// leader and should run the catch-up pass.
+const catchUpPollInterval = time.Second
+
+// Start schedules the daily run and, once this instance leads, applies the
+// events that took effect while the service was down.
+func (j *CorporateActions) Start() {
+	go j.catchUp()
+
+	c := cron.New(
+		cron.WithLocation(j.location),
+		cron.WithChain(cron.Recover(cron.DefaultLogger)),
+	)
+	entry, err := c.AddFunc(j.cfg.Schedule, j.scheduledRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to schedule corporate actions")
+	}
+	c.Start()
+
+	j.mu.Lock()
+	j.cron, j.entry = c, entry
+	j.mu.Unlock()
+	logrus.WithField("schedule", j.cfg.Schedule).Info("Corporate actions job started")
+}
+
+// catchUp runs the startup pass when this instance first leads, so replicas
+// starting together do not contend for the same events. A follower that takes
+// over later catches up then.
+func (j *CorporateActions) catchUp() {
+	if j.leader != nil && !j.leader.IsLeader() {
+		ticker := time.NewTicker(catchUpPollInterval)
+		for !j.leader.IsLeader() {
+			<-ticker.C
+		}
+		ticker.Stop()
+	}
+	j.Run(context.Background(), TriggerStartup)
+}
+
+func (j *CorporateActions) scheduledRun() {
+	if j.leader != nil && !j.leader.IsLeader() {
+		logrus.Debug("Not the leader, skipping corporate actions")
+		return
+	}
+	j.Run(context.Background(), TriggerScheduled)
+}
+
+// Run applies every event in effect that has not been applied yet. Runs on
+// one instance do not overlap; a call made during a run waits for it.
+func (j *CorporateActions) Run(ctx context.Context, trigger string) CorporateActionRun {
+	j.running.Lock()
+	defer j.running.Unlock()
+
+	run := CorporateActionRun{Trigger: trigger, StartedAt: time.Now(), Events: []AppliedStockEvent{}}
+	today := j.Today()
+	pending, err := j.pending(ctx, today)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	for _, ev := range pending {
+		ev.Lots, err = j.apply(ctx, ev, today)
+		if err != nil {
+			ev.Error = err.Error()
+			run.Failed++
+			run.Events = append(run.Events, ev)
+			logrus.WithError(err).WithField("event_id", ev.EventID).Error("Failed to apply stock event")
+			break
+		}
+		run.Applied++
+		run.Lots += ev.Lots
+		run.Events = append(run.Events, ev)
+	}
+	run.FinishedAt = time.Now()
+
+	entry := logrus.WithFields(logrus.Fields{
+		"trigger": trigger,
+		"applied": run.Applied,
+		"failed":  run.Failed,
+		"lots":    run.Lots,
+	})
+	if run.Error != "" {
+		entry.WithField("error", run.Error).Error("Corporate actions run failed")
+	} else if len(pending) > 0 {
+		entry.Info("Corporate actions applied")
+	}
+
+	j.mu.Lock()
+	j.lastRun = &run
+	j.mu.Unlock()
+	return run
+}
+
+// Today is the date on the exchange, which decides whether an event is in
+// effect.
+func (j *CorporateActions) Today() string {
+	return time.Now().In(j.location).Format("2006-01-02")
+}
+
+func (j *CorporateActions) pending(ctx context.Context, today string) ([]AppliedStockEvent, error) {
+	rows, err := j.db.QueryContext(ctx, `
+		SELECT id, stock_symbol, event_type::text, effective_date::text
+		FROM stock_events
+		WHERE applied_at IS NULL AND effective_date <= $1::date
+		ORDER BY effective_date, id
+	`, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AppliedStockEvent
+	for rows.Next() {
+		var ev AppliedStockEvent
+		if err := rows.Scan(&ev.EventID, &ev.StockSymbol, &ev.EventType, &ev.EffectiveDate); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+// apply posts a unit-changing event to the ledger, or pays a dividend.
+func (j *CorporateActions) apply(ctx context.Context, ev AppliedStockEvent, today string) (int, error) {
+	tx, err := j.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var lots int
+	if ev.EventType == models.EventDividend {
+		lots, err = PayDividend(ctx, tx, ev.EventID, today, j.cfg)
+	} else {
+		lots, err = ApplyStockEvent(ctx, tx, ev.EventID, today)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return lots, tx.Commit()
+}
+
+// CorporateActionsStatus reports the schedule and the last run.
+type CorporateActionsStatus struct {
+	Schedule string              `json:"schedule"`
+	Leader   bool                `json:"leader"`
+	NextRun  *time.Time          `json:"next_run,omitempty"`
+	LastRun  *CorporateActionRun `json:"last_run"`
+}
+
+func (j *CorporateActions) Status() CorporateActionsStatus {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	st := CorporateActionsStatus{
+		Schedule: j.cfg.Schedule,
+		Leader:   j.leader == nil || j.leader.IsLeader(),
+		LastRun:  j.lastRun,
+	}
+	if j.cron != nil {
+		if next := j.cron.Entry(j.entry).Next; !next.IsZero() {
+			st.NextRun = &next
+		}
+	}
+	return st
+}
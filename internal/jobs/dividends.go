@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/storage/models"
+	"github.com/LoganX64/stocky-api/internal/utils"
+)
+
+// dividendHolding is a user's units of the dividend's stock at the end of
+// the record date.
+type dividendHolding struct {
+	userID int
+	units  float64
+}
+
+// PayDividend credits every holder of a dividend payable by today, the
+// exchange's date, and returns how many were paid. Entitlements come from the
+// stock_units ledger rows whose business date is on or before the record
+// date, so a corporate action in effect by then counts even if it was posted
+// later, and one cancelled later does not. Events that are not dividends,
+// already paid or not yet payable are left alone.
+//
+// TDS of cfg.DividendTDSPct is withheld from a payout once the holder's gross
+// dividends in the financial year of the payment date, this one included,
+// exceed cfg.DividendTDSThresholdINR. The gross credit and the withholding are
+// separate wallet transactions.
+func PayDividend(ctx context.Context, tx *sql.Tx, eventID int, today string, cfg config.CorporateActions) (int, error) {
+	var symbol, eventType, recordDate, paymentDate string
+	var instrumentID int
+	var perShare sql.NullFloat64
+	var applied, payable bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT stock_symbol, instrument_id, event_type::text, COALESCE(record_date::text, ''), effective_date::text, amount_per_share,
+			applied_at IS NOT NULL, effective_date <= $2::date
+		FROM stock_events WHERE id = $1 FOR UPDATE
+	`, eventID, today).Scan(&symbol, &instrumentID, &eventType, &recordDate, &paymentDate, &perShare, &applied, &payable)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if eventType != models.EventDividend || applied || !payable {
+		return 0, nil
+	}
+	if recordDate == "" || !perShare.Valid || perShare.Float64 <= 0 {
+		return 0, fmt.Errorf("dividend %d has no record date or amount per share", eventID)
+	}
+
+	holdings, err := dividendHoldings(ctx, tx, instrumentID, recordDate)
+	if err != nil {
+		return 0, err
+	}
+	fyStart, err := financialYearStart(paymentDate)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := 0
+	for _, h := range holdings {
+		gross := utils.RoundAmount(h.units * perShare.Float64)
+		if gross <= 0 {
+			continue
+		}
+
+		var paidThisYear float64
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(gross_amount), 0)
+			FROM dividend_payouts
+			WHERE user_id = $1 AND payment_date >= $2::date AND payment_date < $2::date + INTERVAL '1 year'
+		`, h.userID, fyStart).Scan(&paidThisYear); err != nil {
+			return 0, err
+		}
+		tdsPct, tds, net := dividendTDS(gross, paidThisYear, cfg)
+
+		var payoutID int
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO dividend_payouts
+				(event_id, user_id, stock_symbol, instrument_id, record_date, payment_date, units, amount_per_share,
+				 gross_amount, tds_pct, tds_amount, net_amount, paid_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+			RETURNING id
+		`, eventID, h.userID, symbol, instrumentID, recordDate, paymentDate, utils.RoundQuantity(h.units), perShare.Float64,
+			gross, tdsPct, tds, net).Scan(&payoutID); err != nil {
+			return 0, err
+		}
+
+		note := fmt.Sprintf("%s dividend of %.4f per share on %.6f units", symbol, perShare.Float64, h.units)
+		err = creditWallet(ctx, tx, h.userID, models.WalletDividend, gross, eventID, payoutID, note)
+		if err == nil && tds > 0 {
+			note = fmt.Sprintf("TDS at %.2f%% on %s dividend", tdsPct, symbol)
+			err = creditWallet(ctx, tx, h.userID, models.WalletTDS, -tds, eventID, payoutID, note)
+		}
+		if err != nil {
+			return 0, err
+		}
+		paid++
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE stock_events SET applied_at = NOW(), applied_lots = $2 WHERE id = $1
+	`, eventID, paid); err != nil {
+		return 0, err
+	}
+	return paid, nil
+}
+
+// dividendTDS works out the withholding on a gross payout, given the holder's
+// gross dividends already paid in the financial year.
+func dividendTDS(gross, paidThisYear float64, cfg config.CorporateActions) (tdsPct, tds, net float64) {
+	if paidThisYear+gross > cfg.DividendTDSThresholdINR {
+		tdsPct = cfg.DividendTDSPct
+	}
+	tds = utils.RoundAmount(gross * tdsPct / 100)
+	net = utils.RoundAmount(gross - tds)
+	return tdsPct, tds, net
+}
+
+func dividendHoldings(ctx context.Context, tx *sql.Tx, instrumentID int, recordDate string) ([]dividendHolding, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.user_id, SUM(lg.quantity)
+		FROM ledger lg
+		JOIN rewards r ON r.id = lg.reward_id
+		WHERE lg.entry_type = 'stock_units'
+		  AND lg.instrument_id = $1
+		  AND lg.business_date <= $2::date
+		GROUP BY r.user_id
+		HAVING SUM(lg.quantity) > 0
+		ORDER BY r.user_id
+	`, instrumentID, recordDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []dividendHolding
+	for rows.Next() {
+		var h dividendHolding
+		if err := rows.Scan(&h.userID, &h.units); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// creditWallet adds amount, which may be negative, to the user's wallet and
+// records the transaction with the resulting balance. payoutID is 0 for
+// credits that are not dividend payouts.
+func creditWallet(ctx context.Context, tx *sql.Tx, userID int, txnType string, amount float64, eventID, payoutID int, note string) error {
+	var balance float64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO user_wallets (user_id, balance, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET balance = user_wallets.balance + EXCLUDED.balance, updated_at = NOW()
+		RETURNING balance
+	`, userID, amount).Scan(&balance); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO wallet_transactions
+			(user_id, txn_type, amount, balance_after, stock_event_id, dividend_payout_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`, userID, txnType, amount, balance, eventID, sql.NullInt64{Int64: int64(payoutID), Valid: payoutID != 0}, note)
+	return err
+}
+
+// financialYearStart returns the 1 April on or before date, the start of the
+// Indian financial year.
+func financialYearStart(date string) (string, error) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", err
+	}
+	year := d.Year()
+	if d.Month() < time.April {
+		year--
+	}
+	return fmt.Sprintf("%d-04-01", year), nil
+}
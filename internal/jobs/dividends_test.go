@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+)
+
+func TestFinancialYearStart(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{date: "2026-04-01", want: "2026-04-01"},
+		{date: "2026-10-18", want: "2026-04-01"},
+		{date: "2026-03-31", want: "2025-04-01"},
+		{date: "2027-01-01", want: "2026-04-01"},
+	}
+	for _, tt := range tests {
+		got, err := financialYearStart(tt.date)
+		if err != nil || got != tt.want {
+			t.Errorf("financialYearStart(%q) = %q, %v, want %q", tt.date, got, err, tt.want)
+		}
+	}
+	if _, err := financialYearStart("18-10-2026"); err == nil {
+		t.Error("expected an error for a malformed date")
+	}
+}
+
+func TestDividendTDS(t *testing.T) {
+	cfg := config.CorporateActions{DividendTDSThresholdINR: 5000, DividendTDSPct: 10}
+	tests := []struct {
+		name         string
+		gross        float64
+		paidThisYear float64
+		wantPct      float64
+		wantTDS      float64
+		wantNet      float64
+	}{
+		{name: "below the threshold", gross: 1000, paidThisYear: 3000, wantPct: 0, wantTDS: 0, wantNet: 1000},
+		{name: "reaching the threshold exactly", gross: 1000, paidThisYear: 4000, wantPct: 0, wantTDS: 0, wantNet: 1000},
+		{name: "crossing the threshold withholds on the whole payout", gross: 1000, paidThisYear: 4500, wantPct: 10, wantTDS: 100, wantNet: 900},
+		{name: "one payout above the threshold", gross: 6000, paidThisYear: 0, wantPct: 10, wantTDS: 600, wantNet: 5400},
+		{name: "withholding is rounded", gross: 123.45, paidThisYear: 5000, wantPct: 10, wantTDS: 12.345, wantNet: 111.105},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pct, tds, net := dividendTDS(tt.gross, tt.paidThisYear, cfg)
+			if pct != tt.wantPct || tds != tt.wantTDS || net != tt.wantNet {
+				t.Errorf("dividendTDS = %v, %v, %v, want %v, %v, %v", pct, tds, net, tt.wantPct, tt.wantTDS, tt.wantNet)
+			}
+		})
+	}
+}
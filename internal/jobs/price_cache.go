@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PriceCache holds the last stored price per symbol and any active manual
+// overrides. While an override is active GetPrice returns the pinned price.
+type PriceCache struct {
+	mu        sync.RWMutex
+	prices    map[string]CachedPrice
+	overrides map[string]cachedOverride
+}
+
+type CachedPrice struct {
+	Price     float64
+	UpdatedAt time.Time
+}
+
+type cachedOverride struct {
+	Price     float64
+	SetAt     time.Time
+	ExpiresAt time.Time
+}
+
+var (
+	priceCache           = &PriceCache{prices: make(map[string]CachedPrice), overrides: make(map[string]cachedOverride)}
+	maxPriceStaleMinutes = 120
+)
+
+func initializePriceCache(db *sql.DB) {
+	rows, err := db.Query(`
+		SELECT stock_symbol, price, updated_at
+		FROM stock_prices
+	`)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize price cache")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var symbol string
+		var price float64
+		var updatedAt time.Time
+		if err := rows.Scan(&symbol, &price, &updatedAt); err != nil {
+			continue
+		}
+		priceCache.SetPrice(symbol, price, updatedAt)
+	}
+}
+
+func (pc *PriceCache) SetPrice(symbol string, price float64, updatedAt time.Time) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.prices[symbol] = CachedPrice{
+		Price:     price,
+		UpdatedAt: updatedAt,
+	}
+}
+
+func (pc *PriceCache) GetPrice(symbol string) (float64, time.Time, bool) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	if o, ok := pc.overrides[strings.ToUpper(symbol)]; ok && time.Now().Before(o.ExpiresAt) {
+		return o.Price, o.SetAt, true
+	}
+	if cached, ok := pc.prices[symbol]; ok {
+		return cached.Price, cached.UpdatedAt, true
+	}
+	return 0, time.Time{}, false
+}
+
+// freshPrice returns the cached price of symbol if it is recent enough to
+// stand in for a failed update.
+func (pc *PriceCache) freshPrice(symbol string) (float64, bool) {
+	price, updatedAt, ok := pc.GetPrice(symbol)
+	if !ok || time.Since(updatedAt).Minutes() >= float64(maxPriceStaleMinutes) {
+		return 0, false
+	}
+	return price, true
+}
+
+func (pc *PriceCache) SetOverride(symbol string, price float64, expiresAt time.Time) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.overrides[strings.ToUpper(symbol)] = cachedOverride{Price: price, SetAt: time.Now(), ExpiresAt: expiresAt}
+}
+
+func (pc *PriceCache) ClearOverride(symbol string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.overrides, strings.ToUpper(symbol))
+}
+
+// ReplaceOverrides swaps in the overrides loaded from the database, so
+// overrides set or released by another instance are picked up.
+func (pc *PriceCache) ReplaceOverrides(active map[string]PriceOverride) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.overrides = make(map[string]cachedOverride, len(active))
+	for symbol, o := range active {
+		pc.overrides[symbol] = cachedOverride{Price: o.Price, SetAt: o.SetAt, ExpiresAt: o.ExpiresAt}
+	}
+}
+
+// Rename moves the cached price and any override of from to to, after the
+// instrument has been renamed.
+func (pc *PriceCache) Rename(from, to string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for symbol, cached := range pc.prices {
+		if strings.EqualFold(symbol, from) {
+			delete(pc.prices, symbol)
+			pc.prices[to] = cached
+		}
+	}
+	if o, ok := pc.overrides[strings.ToUpper(from)]; ok {
+		delete(pc.overrides, strings.ToUpper(from))
+		pc.overrides[strings.ToUpper(to)] = o
+	}
+}
+
+// RenameCachedSymbol renames a symbol in this instance's price cache. The
+// caches of other instances keep the old symbol until they store a price
+// under the new one or restart.
+func RenameCachedSymbol(from, to string) {
+	priceCache.Rename(from, to)
+}
+
+// CacheEntry is one symbol in a cache snapshot.
+type CacheEntry struct {
+	Symbol            string     `json:"symbol"`
+	Price             float64    `json:"price"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	AgeSeconds        int64      `json:"age_seconds"`
+	Stale             bool       `json:"stale"`
+	OverridePrice     *float64   `json:"override_price,omitempty"`
+	OverrideExpiresAt *time.Time `json:"override_expires_at,omitempty"`
+}
+
+// Snapshot returns the cached prices ordered by symbol. A price is stale once
+// it is too old to stand in for a failed update.
+func (pc *PriceCache) Snapshot(now time.Time) []CacheEntry {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	out := make([]CacheEntry, 0, len(pc.prices))
+	for symbol, cached := range pc.prices {
+		age := now.Sub(cached.UpdatedAt)
+		e := CacheEntry{
+			Symbol:     symbol,
+			Price:      cached.Price,
+			UpdatedAt:  cached.UpdatedAt,
+			AgeSeconds: int64(age.Seconds()),
+			Stale:      age.Minutes() >= float64(maxPriceStaleMinutes),
+		}
+		if o, ok := pc.overrides[strings.ToUpper(symbol)]; ok && now.Before(o.ExpiresAt) {
+			price, expiresAt := o.Price, o.ExpiresAt
+			e.OverridePrice, e.OverrideExpiresAt = &price, &expiresAt
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// CacheSnapshot returns the contents of the shared price cache.
+func CacheSnapshot() []CacheEntry {
+	return priceCache.Snapshot(time.Now())
+}
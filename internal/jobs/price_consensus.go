@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/utils"
+)
+
+// ProviderConsensus names the provider that combines several sources.
+const ProviderConsensus = "consensus"
+
+// Consensus methods.
+const (
+	// ConsensusPrimary: the primary source agreed with the others, or was
+	// the only one to answer.
+	ConsensusPrimary = "primary"
+	// ConsensusMedian: the primary failed or strayed beyond the tolerance,
+	// so the median quote was used.
+	ConsensusMedian = "median"
+)
+
+// SourceQuote is one source's answer for a symbol.
+type SourceQuote struct {
+	Provider  string     `json:"provider"`
+	Price     float64    `json:"price,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Consensus records how the sources compared for one price. It is stored
+// with the price so that disagreements can be reviewed later.
+type Consensus struct {
+	Method string  `json:"method"`
+	Winner string  `json:"winner"`
+	Median float64 `json:"median"`
+	// SpreadPct is the gap between the highest and lowest quote as a share
+	// of the median, in percent.
+	SpreadPct float64       `json:"spread_pct"`
+	Divergent bool          `json:"divergent"`
+	Quotes    []SourceQuote `json:"quotes"`
+}
+
+// ConsensusProvider asks every source for each symbol at once and settles on
+// one price. The first source is the primary: its price is taken while it is
+// within TolerancePct of the median of the others. Otherwise the median of all
+// answers wins; with an even count the lower middle quote is used, so the
+// price is always one a source actually reported. A spread above
+// DivergencePct marks the price as divergent.
+type ConsensusProvider struct {
+	Sources       []PriceProvider
+	TolerancePct  float64
+	DivergencePct float64
+}
+
+func (p *ConsensusProvider) Name() string { return ProviderConsensus }
+
+func (p *ConsensusProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	answers := make([]SourceQuote, len(p.Sources))
+	errs := make([]error, len(p.Sources))
+
+	var wg sync.WaitGroup
+	for i, source := range p.Sources {
+		wg.Add(1)
+		go func(i int, source PriceProvider) {
+			defer wg.Done()
+			answers[i].Provider = source.Name()
+			q, err := source.FetchPrice(ctx, symbol, lastPrice)
+			if err == nil && q.Price <= 0 {
+				err = fmt.Errorf("non-positive price %v", q.Price)
+			}
+			if err != nil {
+				answers[i].Error, errs[i] = err.Error(), err
+				return
+			}
+			ts := q.Timestamp
+			answers[i].Price, answers[i].Timestamp = utils.RoundAmount(q.Price), &ts
+		}(i, source)
+	}
+	wg.Wait()
+
+	c, winner, ok := p.resolve(answers)
+	if !ok {
+		return Quote{}, allSourcesFailed(symbol, errs)
+	}
+	return Quote{Price: answers[winner].Price, Timestamp: *answers[winner].Timestamp, Provider: c.Winner, Consensus: &c}, nil
+}
+
+// resolve picks the winning answer. It reports false when no source
+// answered.
+func (p *ConsensusProvider) resolve(answers []SourceQuote) (Consensus, int, bool) {
+	var ok []int
+	for i, a := range answers {
+		if a.Error == "" {
+			ok = append(ok, i)
+		}
+	}
+	if len(ok) == 0 {
+		return Consensus{}, 0, false
+	}
+
+	sort.SliceStable(ok, func(a, b int) bool { return answers[ok[a]].Price < answers[ok[b]].Price })
+	c := Consensus{Quotes: answers, Median: utils.RoundAmount(medianOf(answers, ok))}
+	if c.Median > 0 {
+		low, high := answers[ok[0]].Price, answers[ok[len(ok)-1]].Price
+		c.SpreadPct = utils.RoundAmount((high - low) / c.Median * 100)
+	}
+	c.Divergent = len(ok) > 1 && c.SpreadPct > p.DivergencePct
+
+	winner := ok[(len(ok)-1)/2]
+	c.Method = ConsensusMedian
+	if answers[0].Error == "" {
+		var others []int
+		for _, i := range ok {
+			if i != 0 {
+				others = append(others, i)
+			}
+		}
+		if len(others) == 0 || withinPct(answers[0].Price, medianOf(answers, others), p.TolerancePct) {
+			winner, c.Method = 0, ConsensusPrimary
+		}
+	}
+	c.Winner = answers[winner].Provider
+	return c, winner, true
+}
+
+// medianOf returns the median price of the answers at idx, which must be
+// sorted by price.
+func medianOf(answers []SourceQuote, idx []int) float64 {
+	n := len(idx)
+	if n%2 == 1 {
+		return answers[idx[n/2]].Price
+	}
+	return (answers[idx[n/2-1]].Price + answers[idx[n/2]].Price) / 2
+}
+
+func withinPct(price, reference, pct float64) bool {
+	if reference <= 0 {
+		return false
+	}
+	return math.Abs(price-reference)/reference*100 <= pct
+}
+
+// allSourcesFailed combines the sources' errors. It matches
+// ErrSymbolNotFound only when no source knows the symbol, so that a single
+// source missing it does not stop the retries of the others.
+func allSourcesFailed(symbol string, errs []error) error {
+	notFound := true
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+		if !errors.Is(err, ErrSymbolNotFound) {
+			notFound = false
+		}
+	}
+	msg := fmt.Sprintf("all price sources failed for %s: %s", symbol, strings.Join(msgs, "; "))
+	if notFound {
+		return fmt.Errorf("%s: %w", msg, ErrSymbolNotFound)
+	}
+	return errors.New(msg)
+}
+
+// DivergentPrice is a stored price whose sources disagreed beyond the
+// divergence threshold.
+type DivergentPrice struct {
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Provider  string    `json:"provider"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Consensus Consensus `json:"consensus"`
+}
+
+// ListDivergentPrices returns the current prices flagged as divergent,
+// widest spread first.
+func ListDivergentPrices(ctx context.Context, db *sql.DB) ([]DivergentPrice, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT stock_symbol, price, COALESCE(provider, ''), updated_at, consensus
+		FROM stock_prices
+		WHERE divergent
+		ORDER BY (consensus->>'spread_pct')::numeric DESC, stock_symbol
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DivergentPrice
+	for rows.Next() {
+		var d DivergentPrice
+		var raw []byte
+		if err := rows.Scan(&d.Symbol, &d.Price, &d.Provider, &d.UpdatedAt, &raw); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &d.Consensus); err != nil {
+			return nil, fmt.Errorf("consensus of %s: %w", d.Symbol, err)
+		}
+		d.Price = utils.RoundAmount(d.Price)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubProvider answers every symbol with a fixed price or error.
+type stubProvider struct {
+	name  string
+	price float64
+	err   error
+}
+
+func (p stubProvider) Name() string { return p.name }
+
+func (p stubProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	if p.err != nil {
+		return Quote{}, p.err
+	}
+	return Quote{Price: p.price, Timestamp: time.Unix(1760600000, 0)}, nil
+}
+
+func quoting(name string, price float64) stubProvider {
+	return stubProvider{name: name, price: price}
+}
+
+func failing(name string, err error) stubProvider {
+	return stubProvider{name: name, err: err}
+}
+
+func TestConsensusProvider(t *testing.T) {
+	down := errors.New("feed down")
+
+	tests := []struct {
+		name       string
+		sources    []stubProvider
+		tolerance  float64
+		divergence float64
+		wantPrice  float64
+		wantWinner string
+		wantMethod string
+		wantMedian float64
+		wantSpread float64
+		divergent  bool
+	}{
+		{
+			name:       "primary agrees with the others",
+			sources:    []stubProvider{quoting("a", 100), quoting("b", 101), quoting("c", 99)},
+			tolerance:  2,
+			divergence: 5,
+			wantPrice:  100, wantWinner: "a", wantMethod: ConsensusPrimary,
+			wantMedian: 100, wantSpread: 2,
+		},
+		{
+			name:       "primary exactly at the tolerance",
+			sources:    []stubProvider{quoting("a", 102), quoting("b", 100)},
+			tolerance:  2,
+			divergence: 5,
+			wantPrice:  102, wantWinner: "a", wantMethod: ConsensusPrimary,
+			wantMedian: 101, wantSpread: 1.9802,
+		},
+		{
+			name:       "primary strays, odd count takes the median",
+			sources:    []stubProvider{quoting("a", 110), quoting("b", 100), quoting("c", 102)},
+			tolerance:  2,
+			divergence: 5,
+			wantPrice:  102, wantWinner: "c", wantMethod: ConsensusMedian,
+			wantMedian: 102, wantSpread: 9.8039, divergent: true,
+		},
+		{
+			name:       "primary strays, even count takes the lower middle quote",
+			sources:    []stubProvider{quoting("a", 120), quoting("b", 100), quoting("c", 101), quoting("d", 103)},
+			tolerance:  1,
+			divergence: 25,
+			wantPrice:  101, wantWinner: "c", wantMethod: ConsensusMedian,
+			wantMedian: 102, wantSpread: 19.6078,
+		},
+		{
+			name:       "primary fails, even count takes the lower middle quote",
+			sources:    []stubProvider{failing("a", down), quoting("b", 104), quoting("c", 100)},
+			tolerance:  2,
+			divergence: 5,
+			wantPrice:  100, wantWinner: "c", wantMethod: ConsensusMedian,
+			wantMedian: 102, wantSpread: 3.9216,
+		},
+		{
+			name:       "primary with a non-positive price counts as failed",
+			sources:    []stubProvider{quoting("a", 0), quoting("b", 100)},
+			tolerance:  2,
+			divergence: 5,
+			wantPrice:  100, wantWinner: "b", wantMethod: ConsensusMedian,
+			wantMedian: 100,
+		},
+		{
+			name:       "only the primary answers",
+			sources:    []stubProvider{quoting("a", 100), failing("b", down)},
+			tolerance:  2,
+			divergence: 0,
+			wantPrice:  100, wantWinner: "a", wantMethod: ConsensusPrimary,
+			wantMedian: 100,
+		},
+		{
+			name:       "spread at the divergence threshold is not divergent",
+			sources:    []stubProvider{quoting("a", 95), quoting("b", 105)},
+			tolerance:  10,
+			divergence: 10,
+			wantPrice:  95, wantWinner: "a", wantMethod: ConsensusPrimary,
+			wantMedian: 100, wantSpread: 10,
+		},
+		{
+			name:       "spread above the divergence threshold is divergent",
+			sources:    []stubProvider{quoting("a", 95), quoting("b", 105)},
+			tolerance:  10,
+			divergence: 9.99,
+			wantPrice:  95, wantWinner: "a", wantMethod: ConsensusPrimary,
+			wantMedian: 100, wantSpread: 10, divergent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ConsensusProvider{TolerancePct: tt.tolerance, DivergencePct: tt.divergence}
+			for _, s := range tt.sources {
+				p.Sources = append(p.Sources, s)
+			}
+			q, err := p.FetchPrice(context.Background(), "TCS", 100)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := q.Consensus
+			if c == nil {
+				t.Fatal("quote has no consensus")
+			}
+			if q.Price != tt.wantPrice || q.Provider != tt.wantWinner || c.Winner != tt.wantWinner {
+				t.Errorf("winner = %s at %v, want %s at %v", q.Provider, q.Price, tt.wantWinner, tt.wantPrice)
+			}
+			if c.Method != tt.wantMethod {
+				t.Errorf("method = %s, want %s", c.Method, tt.wantMethod)
+			}
+			if c.Median != tt.wantMedian {
+				t.Errorf("median = %v, want %v", c.Median, tt.wantMedian)
+			}
+			if c.SpreadPct != tt.wantSpread {
+				t.Errorf("spread = %v, want %v", c.SpreadPct, tt.wantSpread)
+			}
+			if c.Divergent != tt.divergent {
+				t.Errorf("divergent = %v, want %v", c.Divergent, tt.divergent)
+			}
+			if len(c.Quotes) != len(tt.sources) {
+				t.Errorf("got %d source quotes, want %d", len(c.Quotes), len(tt.sources))
+			}
+		})
+	}
+}
+
+func TestConsensusProviderAllFail(t *testing.T) {
+	notFound := failing("a", ErrSymbolNotFound)
+	tests := []struct {
+		name         string
+		sources      []stubProvider
+		wantNotFound bool
+	}{
+		{name: "no source knows the symbol", sources: []stubProvider{notFound, failing("b", ErrSymbolNotFound)}, wantNotFound: true},
+		{name: "one source is down", sources: []stubProvider{notFound, failing("b", errors.New("timeout"))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ConsensusProvider{}
+			for _, s := range tt.sources {
+				p.Sources = append(p.Sources, s)
+			}
+			_, err := p.FetchPrice(context.Background(), "TCS", 100)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := errors.Is(err, ErrSymbolNotFound); got != tt.wantNotFound {
+				t.Errorf("errors.Is(ErrSymbolNotFound) = %v, want %v (%v)", got, tt.wantNotFound, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// priceChannel is the Postgres notification channel writePricesTx announces
+// stored prices on. Every instance listens on it, so stream clients get
+// updates whichever instance stored the price.
+const priceChannel = "stock_prices"
+
+const (
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+	// listenerPing checks an idle connection, since a dropped one is otherwise
+	// only noticed on the next notification.
+	listenerPing = 90 * time.Second
+)
+
+// PriceListener relays the prices announced on priceChannel to this
+// instance's stream subscribers.
+type PriceListener struct {
+	listener *pq.Listener
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPriceListener connects to the database at connStr and listens on
+// priceChannel. The connection is re-established in the background if it
+// drops.
+func NewPriceListener(connStr string) (*PriceListener, error) {
+	listener := pq.NewListener(connStr, listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			logrus.WithError(err).Warn("Price listener disconnected")
+		case pq.ListenerEventReconnected:
+			logrus.Info("Price listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			logrus.WithError(err).Warn("Price listener failed to reconnect")
+		}
+	})
+	if err := listener.Listen(priceChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &PriceListener{listener: listener, stop: make(chan struct{}), done: make(chan struct{})}, nil
+}
+
+// Run relays notifications until Stop is called. Prices announced while the
+// connection was down are not replayed; subscribers get the next ones.
+func (l *PriceListener) Run() {
+	defer close(l.done)
+	ticker := time.NewTicker(listenerPing)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case n := <-l.listener.Notify:
+			// A nil notification marks a reconnect.
+			if n == nil {
+				continue
+			}
+			u, err := parsePriceNotification(n.Extra)
+			if err != nil {
+				logrus.WithError(err).Warn("Ignoring malformed price notification")
+				continue
+			}
+			priceBroker.Publish(u)
+		case <-ticker.C:
+			if err := l.listener.Ping(); err != nil {
+				logrus.WithError(err).Warn("Price listener ping failed")
+			}
+		}
+	}
+}
+
+// Stop ends Run and closes the connection.
+func (l *PriceListener) Stop() {
+	close(l.stop)
+	<-l.done
+	l.listener.Close()
+}
+
+// parsePriceNotification decodes the JSON payload written by writePricesTx.
+func parsePriceNotification(payload string) (PriceUpdate, error) {
+	var u PriceUpdate
+	if err := json.Unmarshal([]byte(payload), &u); err != nil {
+		return u, err
+	}
+	if u.Symbol == "" {
+		return u, fmt.Errorf("price notification without a symbol: %s", payload)
+	}
+	return u, nil
+}
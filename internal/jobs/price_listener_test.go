@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePriceNotification(t *testing.T) {
+	// As written by json_build_object in writePricesTx.
+	payload := `{"symbol" : "TCS", "price" : 3521.5, "previous_price" : 3500, "source" : "provider", "updated_at" : "2026-10-18T09:30:00.123456+05:30"}`
+	u, err := parsePriceNotification(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2026, 10, 18, 4, 0, 0, 123456000, time.UTC)
+	if u.Symbol != "TCS" || u.Price != 3521.5 || u.PreviousPrice != 3500 || u.Source != SourceProvider || !u.UpdatedAt.Equal(want) {
+		t.Errorf("update = %+v", u)
+	}
+
+	for _, bad := range []string{`not json`, `{"price" : 1}`} {
+		if _, err := parsePriceNotification(bad); err == nil {
+			t.Errorf("parsePriceNotification(%q): expected an error", bad)
+		}
+	}
+}
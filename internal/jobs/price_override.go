@@ -0,0 +1,305 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Actions recorded in stock_price_override_audit.
+const (
+	OverrideActionSet       = "set"
+	OverrideActionRelease   = "release"
+	OverrideActionExpire    = "expire"
+	OverrideActionSupersede = "supersede"
+
+	SourceOverride = "override"
+
+	systemActor = "system"
+)
+
+var (
+	ErrUnknownSymbol    = errors.New("unknown stock symbol")
+	ErrNoActiveOverride = errors.New("no active price override")
+)
+
+// PriceOverride is a manually pinned price for a symbol.
+type PriceOverride struct {
+	ID            int        `json:"id"`
+	StockSymbol   string     `json:"stock_symbol"`
+	Price         float64    `json:"price"`
+	Reason        string     `json:"reason"`
+	SetBy         string     `json:"set_by"`
+	SetAt         time.Time  `json:"set_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ReleasedBy    *string    `json:"released_by,omitempty"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason *string    `json:"release_reason,omitempty"`
+}
+
+// Active reports whether the override still pins the price at t.
+func (o PriceOverride) Active(t time.Time) bool {
+	return o.ReleasedAt == nil && t.Before(o.ExpiresAt)
+}
+
+// PriceOverrideAudit is one entry of the override audit trail.
+type PriceOverrideAudit struct {
+	ID          int       `json:"id"`
+	OverrideID  int       `json:"override_id"`
+	StockSymbol string    `json:"stock_symbol"`
+	Action      string    `json:"action"`
+	Price       float64   `json:"price"`
+	Actor       string    `json:"actor"`
+	Reason      *string   `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const priceOverrideColumns = `
+	id, stock_symbol, price, reason, set_by, set_at, expires_at, released_by, released_at, release_reason`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPriceOverride(row rowScanner) (PriceOverride, error) {
+	var o PriceOverride
+	err := row.Scan(&o.ID, &o.StockSymbol, &o.Price, &o.Reason, &o.SetBy, &o.SetAt, &o.ExpiresAt,
+		&o.ReleasedBy, &o.ReleasedAt, &o.ReleaseReason)
+	return o, err
+}
+
+func insertOverrideAudit(ctx context.Context, tx *sql.Tx, o PriceOverride, action, actor string, reason *string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_price_override_audit (override_id, stock_symbol, action, price, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, o.ID, o.StockSymbol, action, o.Price, actor, reason)
+	return err
+}
+
+// closeOverride marks an unreleased override as released and audits it.
+func closeOverride(ctx context.Context, tx *sql.Tx, id int, action, actor string, reason *string) (PriceOverride, error) {
+	o, err := scanPriceOverride(tx.QueryRowContext(ctx, `
+		UPDATE stock_price_overrides
+		SET released_by = $2, released_at = NOW(), release_reason = $3
+		WHERE id = $1 AND released_at IS NULL
+		RETURNING `+priceOverrideColumns,
+		id, actor, reason))
+	if err != nil {
+		return o, err
+	}
+	return o, insertOverrideAudit(ctx, tx, o, action, actor, reason)
+}
+
+// SetPriceOverride pins symbol to price until expiresAt. Any override still
+// open for the symbol is superseded. The pinned price is written to
+// stock_prices immediately and kept there by the updater until the override
+// expires or is released. marketDate is the exchange date its history row is
+// recorded under.
+func SetPriceOverride(ctx context.Context, db *sql.DB, symbol string, price float64, expiresAt time.Time, reason, actor, marketDate string) (PriceOverride, error) {
+	var o PriceOverride
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return o, err
+	}
+	defer tx.Rollback()
+
+	var storedSymbol string
+	err = tx.QueryRowContext(ctx, `
+		SELECT stock_symbol FROM stock_prices WHERE UPPER(stock_symbol) = UPPER($1) FOR UPDATE
+	`, symbol).Scan(&storedSymbol)
+	if err == sql.ErrNoRows {
+		return o, ErrUnknownSymbol
+	}
+	if err != nil {
+		return o, err
+	}
+
+	var openID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM stock_price_overrides WHERE UPPER(stock_symbol) = UPPER($1) AND released_at IS NULL
+	`, storedSymbol).Scan(&openID)
+	switch {
+	case err == nil:
+		supersededBy := "superseded by a new override"
+		if _, err := closeOverride(ctx, tx, openID, OverrideActionSupersede, actor, &supersededBy); err != nil {
+			return o, err
+		}
+	case err != sql.ErrNoRows:
+		return o, err
+	}
+
+	o, err = scanPriceOverride(tx.QueryRowContext(ctx, `
+		INSERT INTO stock_price_overrides (stock_symbol, price, reason, set_by, set_at, expires_at)
+		VALUES ($1, $2, $3, $4, NOW(), $5)
+		RETURNING `+priceOverrideColumns,
+		storedSymbol, price, reason, actor, expiresAt))
+	if err != nil {
+		return o, err
+	}
+	if err := insertOverrideAudit(ctx, tx, o, OverrideActionSet, actor, &reason); err != nil {
+		return o, err
+	}
+
+	record := PriceRecord{Price: price, Source: SourceOverride}
+	if err := writePricesTx(ctx, tx, marketDate, []symbolPrice{{Symbol: storedSymbol, PriceRecord: record}}); err != nil {
+		return o, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return o, err
+	}
+
+	priceCache.SetOverride(storedSymbol, price, expiresAt)
+	priceCache.SetPrice(storedSymbol, price, time.Now())
+	return o, nil
+}
+
+// ReleasePriceOverride ends the active override of symbol. The stored price
+// stays until the next refresh replaces it with a provider quote.
+func ReleasePriceOverride(ctx context.Context, db *sql.DB, symbol, reason, actor string) (PriceOverride, error) {
+	var o PriceOverride
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return o, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM stock_price_overrides
+		WHERE UPPER(stock_symbol) = UPPER($1) AND released_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, symbol).Scan(&id)
+	if err == sql.ErrNoRows {
+		return o, ErrNoActiveOverride
+	}
+	if err != nil {
+		return o, err
+	}
+
+	var releaseReason *string
+	if reason != "" {
+		releaseReason = &reason
+	}
+	if o, err = closeOverride(ctx, tx, id, OverrideActionRelease, actor, releaseReason); err != nil {
+		return o, err
+	}
+	if err := tx.Commit(); err != nil {
+		return o, err
+	}
+
+	priceCache.ClearOverride(o.StockSymbol)
+	return o, nil
+}
+
+// ListActiveOverrides returns the overrides currently pinning a price.
+func ListActiveOverrides(ctx context.Context, db *sql.DB) ([]PriceOverride, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+priceOverrideColumns+`
+		FROM stock_price_overrides
+		WHERE released_at IS NULL AND expires_at > NOW()
+		ORDER BY stock_symbol
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PriceOverride
+	for rows.Next() {
+		o, err := scanPriceOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// ListOverrideAudit returns the audit trail, newest first, optionally for one
+// symbol.
+func ListOverrideAudit(ctx context.Context, db *sql.DB, symbol string, limit int) ([]PriceOverrideAudit, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, override_id, stock_symbol, action, price, actor, reason, created_at
+		FROM stock_price_override_audit
+		WHERE $1 = '' OR UPPER(stock_symbol) = UPPER($1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PriceOverrideAudit
+	for rows.Next() {
+		var a PriceOverrideAudit
+		if err := rows.Scan(&a.ID, &a.OverrideID, &a.StockSymbol, &a.Action, &a.Price, &a.Actor, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// syncOverrides closes overrides that have expired, auditing each, and
+// reloads the active ones into the price cache. It returns the active
+// overrides keyed by upper-case symbol.
+func syncOverrides(ctx context.Context, db *sql.DB) (map[string]PriceOverride, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM stock_price_overrides
+		WHERE released_at IS NULL AND expires_at <= NOW()
+		FOR UPDATE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var expired []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reason := "expired"
+	for _, id := range expired {
+		o, err := closeOverride(ctx, tx, id, OverrideActionExpire, systemActor, &reason)
+		if err != nil {
+			return nil, err
+		}
+		logrus.WithField("symbol", o.StockSymbol).Info("Price override expired")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	active, err := ListActiveOverrides(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]PriceOverride, len(active))
+	for _, o := range active {
+		out[strings.ToUpper(o.StockSymbol)] = o
+	}
+	priceCache.ReplaceOverrides(out)
+	return out, nil
+}
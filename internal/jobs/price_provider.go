@@ -0,0 +1,460 @@
+package jobs
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/marketsim"
+	"github.com/LoganX64/stocky-api/internal/utils"
+)
+
+const (
+	ProviderSimulated = "simulated"
+	ProviderMarketSim = "marketsim"
+	ProviderFile      = "file"
+	ProviderHTTP      = "http"
+)
+
+// ErrSymbolNotFound is returned when a provider has no quote for a symbol.
+var ErrSymbolNotFound = errors.New("symbol not found")
+
+// Quote is a price reported by a provider. Timestamp is the provider's own
+// time for the price, or the fetch time when the provider does not give one.
+// Providers that combine several sources also name the source whose price
+// was chosen and how the sources compared.
+type Quote struct {
+	Price     float64
+	Timestamp time.Time
+	Provider  string
+	Consensus *Consensus
+}
+
+// PriceProvider supplies the latest price of a stock. lastPrice is the price
+// currently stored, for providers that derive the next price from it.
+type PriceProvider interface {
+	Name() string
+	FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error)
+}
+
+// NewPriceProvider builds the provider selected by cfg.Kind. A list of kinds
+// builds a ConsensusProvider over them, led by the first.
+func NewPriceProvider(cfg config.PriceProvider) (PriceProvider, error) {
+	kinds := strings.Split(cfg.Kind, ",")
+	if len(kinds) == 1 {
+		return newProvider(strings.TrimSpace(kinds[0]), cfg)
+	}
+
+	consensus := &ConsensusProvider{TolerancePct: cfg.ConsensusTolerancePct, DivergencePct: cfg.DivergencePct}
+	seen := make(map[string]bool)
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		if seen[kind] {
+			return nil, fmt.Errorf("price provider %q is listed twice", kind)
+		}
+		seen[kind] = true
+		source, err := newProvider(kind, cfg)
+		if err != nil {
+			return nil, err
+		}
+		consensus.Sources = append(consensus.Sources, source)
+	}
+	if len(consensus.Sources) == 1 {
+		return consensus.Sources[0], nil
+	}
+	return consensus, nil
+}
+
+func newProvider(kind string, cfg config.PriceProvider) (PriceProvider, error) {
+	switch strings.ToLower(kind) {
+	case "", ProviderSimulated:
+		return &SimulatedProvider{FailureRate: cfg.SimFailureRate, MaxMove: cfg.SimMaxMove}, nil
+	case ProviderMarketSim:
+		simCfg := marketsim.DefaultConfig()
+		simCfg.Seed, simCfg.Volatility, simCfg.Drift = cfg.SimSeed, cfg.SimVolatility, cfg.SimDrift
+		if cfg.SimConfigFile != "" {
+			var err error
+			if simCfg, err = marketsim.LoadConfig(cfg.SimConfigFile, simCfg); err != nil {
+				return nil, fmt.Errorf("PRICE_SIM_CONFIG: %w", err)
+			}
+		}
+		sim, err := marketsim.New(simCfg)
+		if err != nil {
+			return nil, fmt.Errorf("market simulator: %w", err)
+		}
+		return NewMarketSimProvider(sim), nil
+	case ProviderFile:
+		if cfg.FilePath == "" {
+			return nil, errors.New("PRICE_FILE_PATH is required for the file price provider")
+		}
+		return &FileProvider{Path: cfg.FilePath}, nil
+	case ProviderHTTP:
+		if !strings.Contains(cfg.HTTPURL, "{symbol}") {
+			return nil, errors.New("PRICE_HTTP_URL must contain a {symbol} placeholder")
+		}
+		if cfg.HTTPAuthToken != "" && strings.TrimSpace(cfg.HTTPAuthHeader) == "" {
+			return nil, errors.New("PRICE_HTTP_AUTH_HEADER is required when PRICE_HTTP_AUTH_TOKEN is set")
+		}
+		return &HTTPProvider{
+			URLTemplate:    cfg.HTTPURL,
+			PriceField:     cfg.HTTPPriceField,
+			TimestampField: cfg.HTTPTimestampField,
+			AuthHeader:     strings.TrimSpace(cfg.HTTPAuthHeader),
+			AuthToken:      cfg.HTTPAuthToken,
+			Client:         &http.Client{Timeout: cfg.Timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown price provider %q. must be one of: simulated, marketsim, file, http", kind)
+	}
+}
+
+// SimulatedProvider moves the last price by a random factor within MaxMove
+// and fails a FailureRate share of fetches, to exercise the fallbacks.
+type SimulatedProvider struct {
+	FailureRate float64
+	MaxMove     float64
+}
+
+func (p *SimulatedProvider) Name() string { return ProviderSimulated }
+
+func (p *SimulatedProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	if err := ctx.Err(); err != nil {
+		return Quote{}, err
+	}
+	if rand.Float64() < p.FailureRate {
+		return Quote{}, fmt.Errorf("simulated feed failure for %s", symbol)
+	}
+	factor := 1 - p.MaxMove + rand.Float64()*2*p.MaxMove
+	return Quote{Price: utils.RoundAmount(lastPrice * factor), Timestamp: time.Now()}, nil
+}
+
+// MarketSimProvider quotes prices from a seeded market simulator, so the same
+// seed gives the same price for a symbol at the same time on every run.
+// Symbols with a configured start follow their simulated path from it;
+// others follow the path from the price stored when the provider first saw
+// them. Quotes fail with marketsim.ErrOutage during outage windows.
+type MarketSimProvider struct {
+	Sim *marketsim.Simulator
+	// Now is the clock quotes are taken at; tests can pin it.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	anchors map[string]simAnchor
+}
+
+// simAnchor ties a symbol without a configured start to its stored price.
+type simAnchor struct {
+	price  float64
+	factor float64
+}
+
+func NewMarketSimProvider(sim *marketsim.Simulator) *MarketSimProvider {
+	return &MarketSimProvider{Sim: sim, Now: time.Now, anchors: make(map[string]simAnchor)}
+}
+
+func (p *MarketSimProvider) Name() string { return ProviderMarketSim }
+
+func (p *MarketSimProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	if err := ctx.Err(); err != nil {
+		return Quote{}, err
+	}
+	now := p.Now()
+	at := p.Sim.StepTime(p.Sim.StepAt(now))
+	if _, ok := p.Sim.Start(symbol); ok {
+		price, err := p.Sim.PriceAt(symbol, now)
+		return Quote{Price: price, Timestamp: at}, err
+	}
+	if p.Sim.InOutage(symbol, now) {
+		return Quote{}, fmt.Errorf("%s: %w", symbol, marketsim.ErrOutage)
+	}
+
+	factor := p.Sim.Factor(symbol, now)
+	key := strings.ToUpper(symbol)
+	p.mu.Lock()
+	a, ok := p.anchors[key]
+	if !ok {
+		a = simAnchor{price: lastPrice, factor: factor}
+		p.anchors[key] = a
+	}
+	p.mu.Unlock()
+	return Quote{Price: utils.RoundAmount(a.price * factor / a.factor), Timestamp: at}, nil
+}
+
+// FileProvider serves quotes from a local file, re-reading it whenever its
+// modification time changes. A .json file holds either an array of
+// {"symbol", "price", "timestamp"} objects or an object mapping symbol to
+// price; any other file is read as CSV with the columns
+// symbol,price[,timestamp] and an optional header row. Timestamps are RFC 3339
+// or Unix seconds.
+type FileProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	quotes  map[string]Quote
+}
+
+func (p *FileProvider) Name() string { return ProviderFile }
+
+func (p *FileProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	if err := ctx.Err(); err != nil {
+		return Quote{}, err
+	}
+	quotes, err := p.load()
+	if err != nil {
+		return Quote{}, err
+	}
+	q, ok := quotes[strings.ToUpper(symbol)]
+	if !ok {
+		return Quote{}, fmt.Errorf("%s: %w", symbol, ErrSymbolNotFound)
+	}
+	return q, nil
+}
+
+func (p *FileProvider) load() (map[string]Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if p.quotes != nil && info.ModTime().Equal(p.modTime) {
+		return p.quotes, nil
+	}
+
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var quotes map[string]Quote
+	if strings.EqualFold(filepath.Ext(p.Path), ".json") {
+		quotes, err = parseJSONQuotes(f, info.ModTime())
+	} else {
+		quotes, err = parseCSVQuotes(f, info.ModTime())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", p.Path, err)
+	}
+
+	p.quotes = quotes
+	p.modTime = info.ModTime()
+	return quotes, nil
+}
+
+func parseCSVQuotes(r io.Reader, fileTime time.Time) (map[string]Quote, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	quotes := make(map[string]Quote)
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected symbol,price[,timestamp]", line)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if line == 1 {
+				continue // header row
+			}
+			return nil, fmt.Errorf("line %d: invalid price %q", line, record[1])
+		}
+		ts := fileTime
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			if ts, err = parseQuoteTime(strings.TrimSpace(record[2])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		quotes[strings.ToUpper(strings.TrimSpace(record[0]))] = Quote{Price: price, Timestamp: ts}
+	}
+	return quotes, nil
+}
+
+func parseJSONQuotes(r io.Reader, fileTime time.Time) (map[string]Quote, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make(map[string]Quote)
+
+	var bySymbol map[string]float64
+	if err := json.Unmarshal(data, &bySymbol); err == nil {
+		for symbol, price := range bySymbol {
+			quotes[strings.ToUpper(symbol)] = Quote{Price: price, Timestamp: fileTime}
+		}
+		return quotes, nil
+	}
+
+	var entries []struct {
+		Symbol    string      `json:"symbol"`
+		Price     float64     `json:"price"`
+		Timestamp interface{} `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New("expected an array of {symbol, price, timestamp} or an object of symbol to price")
+	}
+	for i, e := range entries {
+		if e.Symbol == "" {
+			return nil, fmt.Errorf("entry %d: missing symbol", i)
+		}
+		ts := fileTime
+		if e.Timestamp != nil {
+			if ts, err = quoteTimeFromJSON(e.Timestamp); err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i, err)
+			}
+		}
+		quotes[strings.ToUpper(e.Symbol)] = Quote{Price: e.Price, Timestamp: ts}
+	}
+	return quotes, nil
+}
+
+// HTTPProvider fetches one symbol per request from a JSON endpoint. The
+// {symbol} placeholder in URLTemplate is replaced with the escaped symbol, and
+// PriceField / TimestampField are dot-separated paths into the response, e.g.
+// "data.lastPrice". AuthToken is sent in the AuthHeader header when both are
+// set.
+type HTTPProvider struct {
+	URLTemplate    string
+	PriceField     string
+	TimestampField string
+	AuthHeader     string
+	AuthToken      string
+	Client         *http.Client
+}
+
+func (p *HTTPProvider) Name() string { return ProviderHTTP }
+
+func (p *HTTPProvider) FetchPrice(ctx context.Context, symbol string, lastPrice float64) (Quote, error) {
+	endpoint := strings.ReplaceAll(p.URLTemplate, "{symbol}", url.PathEscape(symbol))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.AuthHeader != "" && p.AuthToken != "" {
+		req.Header.Set(p.AuthHeader, p.AuthToken)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Quote{}, fmt.Errorf("%s: %w", symbol, ErrSymbolNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("price feed returned %s for %s", resp.Status, symbol)
+	}
+
+	var body interface{}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return Quote{}, fmt.Errorf("decoding price feed response for %s: %w", symbol, err)
+	}
+
+	rawPrice, ok := jsonPath(body, p.PriceField)
+	if !ok {
+		return Quote{}, fmt.Errorf("price feed response for %s has no %q field", symbol, p.PriceField)
+	}
+	price, err := jsonNumber(rawPrice)
+	if err != nil {
+		return Quote{}, fmt.Errorf("price feed response for %s: %s %w", symbol, p.PriceField, err)
+	}
+
+	q := Quote{Price: price, Timestamp: time.Now()}
+	if p.TimestampField != "" {
+		if rawTS, ok := jsonPath(body, p.TimestampField); ok {
+			if q.Timestamp, err = quoteTimeFromJSON(rawTS); err != nil {
+				return Quote{}, fmt.Errorf("price feed response for %s: %w", symbol, err)
+			}
+		}
+	}
+	return q, nil
+}
+
+func jsonPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonNumber accepts JSON numbers and numeric strings, as feeds commonly
+// quote prices as strings to preserve decimals.
+func jsonNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("is not a number: %q", n)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("is not a number: %v", v)
+	}
+}
+
+func quoteTimeFromJSON(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return parseQuoteTime(t)
+	default:
+		secs, err := jsonNumber(t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %w", err)
+		}
+		return time.Unix(int64(secs), 0), nil
+	}
+}
+
+func parseQuoteTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339 or Unix seconds", s)
+}
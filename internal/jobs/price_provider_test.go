@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/marketsim"
+)
+
+func newTestHTTPProvider(srv *httptest.Server, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		URLTemplate:    srv.URL + "/quote/{symbol}",
+		PriceField:     "data.lastPrice",
+		TimestampField: "data.time",
+		AuthHeader:     "X-Api-Key",
+		AuthToken:      "secret",
+		Client:         &http.Client{Timeout: timeout},
+	}
+}
+
+func TestHTTPProviderFetchPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		delay    time.Duration
+		want     float64
+		wantTS   time.Time
+		wantErr  string
+		notFound bool
+	}{
+		{
+			name:   "number price with RFC 3339 timestamp",
+			status: http.StatusOK,
+			body:   `{"data": {"lastPrice": 2501.35, "time": "2026-10-16T10:15:00Z"}}`,
+			want:   2501.35,
+			wantTS: time.Date(2026, 10, 16, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:   "string price with Unix timestamp",
+			status: http.StatusOK,
+			body:   `{"data": {"lastPrice": "101.5", "time": 1760609700}}`,
+			want:   101.5,
+			wantTS: time.Unix(1760609700, 0),
+		},
+		{
+			name:     "unknown symbol",
+			status:   http.StatusNotFound,
+			body:     `{"error": "not found"}`,
+			notFound: true,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			body:    `{"error": "down"}`,
+			wantErr: "500",
+		},
+		{
+			name:    "malformed body",
+			status:  http.StatusOK,
+			body:    `{"data": {"lastPrice": `,
+			wantErr: "decoding price feed response",
+		},
+		{
+			name:    "missing price field",
+			status:  http.StatusOK,
+			body:    `{"data": {"close": 10}}`,
+			wantErr: `no "data.lastPrice" field`,
+		},
+		{
+			name:    "price is not a number",
+			status:  http.StatusOK,
+			body:    `{"data": {"lastPrice": "n/a"}}`,
+			wantErr: "is not a number",
+		},
+		{
+			name:    "bad timestamp",
+			status:  http.StatusOK,
+			body:    `{"data": {"lastPrice": 10, "time": "yesterday"}}`,
+			wantErr: "invalid timestamp",
+		},
+		{
+			name:    "timeout",
+			status:  http.StatusOK,
+			body:    `{"data": {"lastPrice": 10}}`,
+			delay:   200 * time.Millisecond,
+			wantErr: "Client.Timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Path; got != "/quote/M&M" {
+					t.Errorf("path = %q, want /quote/M&M", got)
+				}
+				if got := r.Header.Get("X-Api-Key"); got != "secret" {
+					t.Errorf("auth header = %q, want secret", got)
+				}
+				if tt.delay > 0 {
+					select {
+					case <-time.After(tt.delay):
+					case <-r.Context().Done():
+						return
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			p := newTestHTTPProvider(srv, 50*time.Millisecond)
+			q, err := p.FetchPrice(context.Background(), "M&M", 0)
+
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, ErrSymbolNotFound) {
+					t.Fatalf("err = %v, want ErrSymbolNotFound", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if q.Price != tt.want {
+					t.Errorf("price = %v, want %v", q.Price, tt.want)
+				}
+				if !q.Timestamp.Equal(tt.wantTS) {
+					t.Errorf("timestamp = %v, want %v", q.Timestamp, tt.wantTS)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPProviderCancelledContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"lastPrice": 10}}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newTestHTTPProvider(srv, time.Second).FetchPrice(ctx, "TCS", 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestHTTPProviderAuthHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		token      string
+		wantHeader string
+	}{
+		{name: "header and token", header: "X-Api-Key", token: "secret", wantHeader: "X-Api-Key"},
+		{name: "no token", header: "X-Api-Key"},
+		{name: "no header", token: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				w.Write([]byte(`{"data": {"lastPrice": 10}}`))
+			}))
+			defer srv.Close()
+
+			p := newTestHTTPProvider(srv, time.Second)
+			p.AuthHeader, p.AuthToken = tt.header, tt.token
+			if _, err := p.FetchPrice(context.Background(), "TCS", 0); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantHeader != "" && got.Get(tt.wantHeader) != tt.token {
+				t.Errorf("%s = %q, want %q", tt.wantHeader, got.Get(tt.wantHeader), tt.token)
+			}
+			if tt.wantHeader == "" && (got.Get("X-Api-Key") != "" || got.Get("Authorization") != "") {
+				t.Errorf("unexpected auth header in %v", got)
+			}
+		})
+	}
+}
+
+func TestNewPriceProviderRejectsTokenWithoutHeader(t *testing.T) {
+	cfg := config.PriceProvider{
+		Kind:          ProviderHTTP,
+		HTTPURL:       "http://localhost:9000/{symbol}.json",
+		HTTPAuthToken: "secret",
+	}
+	if _, err := NewPriceProvider(cfg); err == nil || !strings.Contains(err.Error(), "PRICE_HTTP_AUTH_HEADER") {
+		t.Fatalf("err = %v, want it to name PRICE_HTTP_AUTH_HEADER", err)
+	}
+
+	cfg.HTTPAuthHeader = "Authorization"
+	if _, err := NewPriceProvider(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMarketSimProviderPinnedClock(t *testing.T) {
+	cfg := marketsim.DefaultConfig()
+	cfg.Seed = 42
+	cfg.Symbols = map[string]marketsim.Params{"TCS": {Start: 3450}}
+	now := time.Date(2026, 3, 2, 9, 15, 30, 0, time.UTC)
+
+	quote := func() Quote {
+		t.Helper()
+		sim, err := marketsim.New(cfg)
+		if err != nil {
+			t.Fatalf("marketsim.New: %v", err)
+		}
+		p := NewMarketSimProvider(sim)
+		p.Now = func() time.Time { return now }
+		q, err := p.FetchPrice(context.Background(), "TCS", 0)
+		if err != nil {
+			t.Fatalf("FetchPrice: %v", err)
+		}
+		return q
+	}
+
+	a, b := quote(), quote()
+	if a.Price != b.Price {
+		t.Errorf("same seed and time gave %v and %v", a.Price, b.Price)
+	}
+	if want := now.Truncate(time.Minute); !a.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want the step start %v", a.Timestamp, want)
+	}
+}
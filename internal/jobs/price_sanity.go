@@ -0,0 +1,292 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Reasons a price is quarantined.
+const (
+	QuarantineNonPositive = "non_positive"
+	QuarantineCircuitBand = "circuit_band"
+	QuarantineMaxMove     = "max_move"
+)
+
+// Review states of a quarantined price.
+const (
+	QuarantinePending  = "pending"
+	QuarantineAccepted = "accepted"
+	QuarantineRejected = "rejected"
+)
+
+var (
+	ErrQuarantineNotFound = errors.New("quarantined price not found")
+	ErrQuarantineReviewed = errors.New("quarantined price already reviewed")
+	ErrOverrideActive     = errors.New("symbol has an active price override")
+)
+
+// PriceBand holds the sanity limits of a symbol, in percent. A zero limit
+// disables that check.
+type PriceBand struct {
+	StockSymbol string    `json:"stock_symbol"`
+	BandPct     float64   `json:"band_pct"`
+	MaxMovePct  float64   `json:"max_move_pct"`
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// QuarantinedPrice is a provider price held back by a sanity check.
+type QuarantinedPrice struct {
+	ID                int        `json:"id"`
+	StockSymbol       string     `json:"stock_symbol"`
+	Price             float64    `json:"price"`
+	PreviousPrice     float64    `json:"previous_price"`
+	ReferencePrice    *float64   `json:"reference_price,omitempty"`
+	Reason            string     `json:"reason"`
+	Detail            string     `json:"detail"`
+	Provider          *string    `json:"provider,omitempty"`
+	ProviderTimestamp *time.Time `json:"provider_timestamp,omitempty"`
+	Status            string     `json:"status"`
+	ReviewedBy        *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	ReviewComment     *string    `json:"review_comment,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// sanityViolation describes why a price failed checkPrice.
+type sanityViolation struct {
+	Reason string
+	Detail string
+}
+
+func (v *sanityViolation) Error() string {
+	return "quarantined: " + v.Detail
+}
+
+// checkPrice validates a provider price for job. The circuit band is applied
+// around the previous close and the max move against the stored price.
+func checkPrice(job symbolJob, price float64) *sanityViolation {
+	if price <= 0 {
+		return &sanityViolation{QuarantineNonPositive, fmt.Sprintf("non-positive price %.2f", price)}
+	}
+	if job.bandPct > 0 && job.referencePrice > 0 {
+		lower := job.referencePrice * (1 - job.bandPct/100)
+		upper := job.referencePrice * (1 + job.bandPct/100)
+		if price < lower || price > upper {
+			return &sanityViolation{QuarantineCircuitBand, fmt.Sprintf(
+				"price %.2f outside %.2f%% band %.2f–%.2f around previous close %.2f",
+				price, job.bandPct, lower, upper, job.referencePrice)}
+		}
+	}
+	if job.maxMovePct > 0 && job.oldPrice > 0 {
+		move := math.Abs(price-job.oldPrice) / job.oldPrice * 100
+		if move > job.maxMovePct {
+			return &sanityViolation{QuarantineMaxMove, fmt.Sprintf(
+				"move of %.2f%% from %.2f exceeds %.2f%% per refresh", move, job.oldPrice, job.maxMovePct)}
+		}
+	}
+	return nil
+}
+
+// quarantineEntry is a rejected price waiting to be written.
+type quarantineEntry struct {
+	job       symbolJob
+	record    PriceRecord
+	violation *sanityViolation
+}
+
+// quarantinePrices stores rejected prices for review.
+func quarantinePrices(ctx context.Context, db *sql.DB, entries []quarantineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		var reference *float64
+		if e.job.referencePrice > 0 {
+			reference = &e.job.referencePrice
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO stock_price_quarantine
+				(stock_symbol, price, previous_price, reference_price, reason, detail, provider, provider_timestamp)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, e.job.symbol, e.record.Price, e.job.oldPrice, reference, e.violation.Reason, e.violation.Detail,
+			nullString(e.record.Provider), e.record.ProviderTimestamp); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const quarantineColumns = `
+	id, stock_symbol, price, previous_price, reference_price, reason, detail, provider, provider_timestamp,
+	status, reviewed_by, reviewed_at, review_comment, created_at`
+
+func scanQuarantinedPrice(row rowScanner) (QuarantinedPrice, error) {
+	var q QuarantinedPrice
+	err := row.Scan(&q.ID, &q.StockSymbol, &q.Price, &q.PreviousPrice, &q.ReferencePrice, &q.Reason, &q.Detail,
+		&q.Provider, &q.ProviderTimestamp, &q.Status, &q.ReviewedBy, &q.ReviewedAt, &q.ReviewComment, &q.CreatedAt)
+	return q, err
+}
+
+// ListQuarantine returns quarantined prices, newest first. Empty status or
+// symbol match all.
+func ListQuarantine(ctx context.Context, db *sql.DB, status, symbol string, limit int) ([]QuarantinedPrice, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+quarantineColumns+`
+		FROM stock_price_quarantine
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR UPPER(stock_symbol) = UPPER($2))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`, status, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []QuarantinedPrice
+	for rows.Next() {
+		q, err := scanQuarantinedPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
+
+// ReviewQuarantine closes a pending quarantined price. Accepting it applies
+// the price as a provider quote, recorded under marketDate, the exchange
+// date; rejecting it only records the decision.
+func ReviewQuarantine(ctx context.Context, db *sql.DB, id int, accept bool, comment, actor, marketDate string) (QuarantinedPrice, error) {
+	var q QuarantinedPrice
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return q, err
+	}
+	defer tx.Rollback()
+
+	q, err = scanQuarantinedPrice(tx.QueryRowContext(ctx, `
+		SELECT `+quarantineColumns+` FROM stock_price_quarantine WHERE id = $1 FOR UPDATE
+	`, id))
+	if err == sql.ErrNoRows {
+		return q, ErrQuarantineNotFound
+	}
+	if err != nil {
+		return q, err
+	}
+	if q.Status != QuarantinePending {
+		return q, ErrQuarantineReviewed
+	}
+
+	status := QuarantineRejected
+	if accept {
+		status = QuarantineAccepted
+		if q.Price <= 0 {
+			return q, errors.New("a non-positive price cannot be accepted")
+		}
+		var overridden bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM stock_price_overrides
+				WHERE UPPER(stock_symbol) = UPPER($1) AND released_at IS NULL AND expires_at > NOW()
+			)
+		`, q.StockSymbol).Scan(&overridden); err != nil {
+			return q, err
+		}
+		if overridden {
+			return q, ErrOverrideActive
+		}
+		record := PriceRecord{Price: q.Price, Source: SourceProvider, ProviderTimestamp: q.ProviderTimestamp}
+		if q.Provider != nil {
+			record.Provider = *q.Provider
+		}
+		if err := writePricesTx(ctx, tx, marketDate, []symbolPrice{{Symbol: q.StockSymbol, PriceRecord: record}}); err != nil {
+			return q, err
+		}
+	}
+
+	var reviewComment *string
+	if comment != "" {
+		reviewComment = &comment
+	}
+	q, err = scanQuarantinedPrice(tx.QueryRowContext(ctx, `
+		UPDATE stock_price_quarantine
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), review_comment = $4
+		WHERE id = $1
+		RETURNING `+quarantineColumns,
+		id, status, actor, reviewComment))
+	if err != nil {
+		return q, err
+	}
+	if err := tx.Commit(); err != nil {
+		return q, err
+	}
+
+	if accept {
+		priceCache.SetPrice(q.StockSymbol, q.Price, time.Now())
+	}
+	return q, nil
+}
+
+// ListPriceBands returns the per-symbol limits that differ from the defaults.
+func ListPriceBands(ctx context.Context, db *sql.DB) ([]PriceBand, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT stock_symbol, band_pct, max_move_pct, updated_by, updated_at
+		FROM stock_price_bands
+		ORDER BY stock_symbol
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PriceBand
+	for rows.Next() {
+		var b PriceBand
+		if err := rows.Scan(&b.StockSymbol, &b.BandPct, &b.MaxMovePct, &b.UpdatedBy, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// SetPriceBand sets the sanity limits of symbol, replacing earlier ones.
+func SetPriceBand(ctx context.Context, db *sql.DB, symbol string, bandPct, maxMovePct float64, actor string) (PriceBand, error) {
+	var b PriceBand
+
+	var storedSymbol string
+	err := db.QueryRowContext(ctx, `
+		SELECT stock_symbol FROM stock_prices WHERE UPPER(stock_symbol) = UPPER($1)
+	`, symbol).Scan(&storedSymbol)
+	if err == sql.ErrNoRows {
+		return b, ErrUnknownSymbol
+	}
+	if err != nil {
+		return b, err
+	}
+
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO stock_price_bands (stock_symbol, band_pct, max_move_pct, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (stock_symbol) DO UPDATE
+		SET band_pct = EXCLUDED.band_pct,
+			max_move_pct = EXCLUDED.max_move_pct,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING stock_symbol, band_pct, max_move_pct, updated_by, updated_at
+	`, storedSymbol, bandPct, maxMovePct, actor).Scan(&b.StockSymbol, &b.BandPct, &b.MaxMovePct, &b.UpdatedBy, &b.UpdatedAt)
+	return b, err
+}
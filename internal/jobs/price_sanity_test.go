@@ -0,0 +1,54 @@
+package jobs
+
+import "testing"
+
+func TestCheckPrice(t *testing.T) {
+	job := symbolJob{symbol: "TCS", oldPrice: 100, referencePrice: 100, bandPct: 10, maxMovePct: 5}
+
+	tests := []struct {
+		name   string
+		mutate func(*symbolJob)
+		price  float64
+		want   string
+	}{
+		{name: "within band and move", price: 104},
+		{name: "zero price", price: 0, want: QuarantineNonPositive},
+		{name: "negative price", price: -1, want: QuarantineNonPositive},
+		{name: "non-positive without limits", mutate: func(j *symbolJob) { j.bandPct, j.maxMovePct = 0, 0 }, price: 0, want: QuarantineNonPositive},
+
+		{name: "upper band edge is allowed", mutate: func(j *symbolJob) { j.maxMovePct = 0 }, price: 110},
+		{name: "lower band edge is allowed", mutate: func(j *symbolJob) { j.maxMovePct = 0 }, price: 90},
+		{name: "above the band", mutate: func(j *symbolJob) { j.maxMovePct = 0 }, price: 110.01, want: QuarantineCircuitBand},
+		{name: "below the band", mutate: func(j *symbolJob) { j.maxMovePct = 0 }, price: 89.99, want: QuarantineCircuitBand},
+		{name: "band is around the previous close", mutate: func(j *symbolJob) { j.referencePrice, j.oldPrice = 200, 210 }, price: 215},
+		{name: "band wins over max move", price: 120, want: QuarantineCircuitBand},
+		{name: "zero band disables the band", mutate: func(j *symbolJob) { j.bandPct, j.maxMovePct = 0, 0 }, price: 500},
+		{name: "no previous close disables the band", mutate: func(j *symbolJob) { j.referencePrice, j.maxMovePct = 0, 0 }, price: 500},
+
+		{name: "max move edge is allowed", price: 105},
+		{name: "max move edge downwards is allowed", price: 95},
+		{name: "move above the limit", price: 105.01, want: QuarantineMaxMove},
+		{name: "move below the limit", price: 94.99, want: QuarantineMaxMove},
+		{name: "max move is against the stored price", mutate: func(j *symbolJob) { j.oldPrice = 92 }, price: 97, want: QuarantineMaxMove},
+		{name: "zero max move disables the check", mutate: func(j *symbolJob) { j.maxMovePct = 0 }, price: 108},
+		{name: "no stored price disables the check", mutate: func(j *symbolJob) { j.oldPrice = 0 }, price: 108},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := job
+			if tt.mutate != nil {
+				tt.mutate(&j)
+			}
+			v := checkPrice(j, tt.price)
+			switch {
+			case tt.want == "" && v != nil:
+				t.Fatalf("unexpected violation: %v", v)
+			case tt.want != "" && v == nil:
+				t.Fatalf("no violation, want %s", tt.want)
+			case v != nil && v.Reason != tt.want:
+				t.Fatalf("reason = %s, want %s (%v)", v.Reason, tt.want, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxWriteAttempts  = 3
+	writeRetryBackoff = 500 * time.Millisecond
+)
+
+// symbolPrice is a price to store for one symbol.
+type symbolPrice struct {
+	Symbol string
+	PriceRecord
+}
+
+// priceColumns holds a batch as parallel arrays for unnest().
+type priceColumns struct {
+	symbols            []string
+	prices             []float64
+	sources            []string
+	providers          []sql.NullString
+	providerTimestamps []sql.NullTime
+	attempts           []int64
+	consensus          []sql.NullString
+	divergent          []bool
+}
+
+func (pc *priceColumns) add(p symbolPrice) {
+	pc.symbols = append(pc.symbols, p.Symbol)
+	pc.prices = append(pc.prices, p.Price)
+	pc.sources = append(pc.sources, p.Source)
+	pc.providers = append(pc.providers, nullString(p.Provider))
+	ts := sql.NullTime{}
+	if p.ProviderTimestamp != nil {
+		ts = sql.NullTime{Time: *p.ProviderTimestamp, Valid: true}
+	}
+	pc.providerTimestamps = append(pc.providerTimestamps, ts)
+	pc.attempts = append(pc.attempts, int64(p.Attempts))
+	consensus := sql.NullString{}
+	if p.Consensus != nil {
+		// Consensus only holds plain values, so this cannot fail.
+		raw, _ := json.Marshal(p.Consensus)
+		consensus = sql.NullString{String: string(raw), Valid: true}
+	}
+	pc.consensus = append(pc.consensus, consensus)
+	pc.divergent = append(pc.divergent, p.Consensus != nil && p.Consensus.Divergent)
+}
+
+func (pc *priceColumns) args() []interface{} {
+	return []interface{}{
+		pq.Array(pc.symbols),
+		pq.Array(pc.prices),
+		pq.Array(pc.sources),
+		pq.Array(pc.providers),
+		pq.Array(pc.providerTimestamps),
+		pq.Array(pc.attempts),
+		pq.Array(pc.consensus),
+		pq.Array(pc.divergent),
+	}
+}
+
+const priceUnnest = `
+	unnest($1::text[], $2::numeric[], $3::text[], $4::text[], $5::timestamptz[], $6::int[], $7::jsonb[], $8::boolean[])
+		AS v(symbol, price, source, provider, provider_timestamp, attempts, consensus, divergent)`
+
+// priceRows is priceUnnest with the instrument of each symbol, registering
+// symbols not seen before. Prices are stored by instrument, so a renamed
+// stock or an alias writes to the same rows as its current symbol.
+const priceRows = `(
+	SELECT v.*, register_instrument(v.symbol) AS instrument_id
+	FROM ` + priceUnnest + `
+) p`
+
+// writePrices stores a batch in one transaction.
+func writePrices(ctx context.Context, db *sql.DB, marketDate string, batch []symbolPrice) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writePricesTx(ctx, tx, marketDate, batch); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writePricesTx updates stock_prices for every row that is not carried
+// forward and announces it on priceChannel, and adds a history row and a tick
+// for every row. Rows that are not carried forward are also folded into the
+// daily bar. Rows are matched by instrument, and history rows and bars are
+// dated marketDate, the date on the exchange, rather than the database
+// session's CURRENT_DATE. Symbols must name distinct instruments within the
+// batch.
+func writePricesTx(ctx context.Context, tx *sql.Tx, marketDate string, batch []symbolPrice) error {
+	var all, current priceColumns
+	for _, p := range batch {
+		all.add(p)
+		if p.Source != SourceCarriedForward {
+			current.add(p)
+		}
+	}
+
+	if len(current.symbols) > 0 {
+		// Notifications are only delivered once the transaction commits, and
+		// read the previous price before the update below replaces it.
+		if _, err := tx.ExecContext(ctx, `
+			SELECT pg_notify('`+priceChannel+`', json_build_object(
+				'symbol', p.symbol, 'price', p.price, 'previous_price', sp.price,
+				'source', p.source, 'updated_at', NOW())::text)
+			FROM `+priceRows+`
+			JOIN stock_prices sp ON sp.instrument_id = p.instrument_id
+		`, current.args()...); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE stock_prices sp
+			SET price = p.price, updated_at = NOW(), price_source = p.source, provider = p.provider,
+				provider_timestamp = p.provider_timestamp, fetch_attempts = p.attempts,
+				consensus = p.consensus, divergent = p.divergent
+			FROM `+priceRows+`
+			WHERE sp.instrument_id = p.instrument_id
+		`, current.args()...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_price_history
+			(instrument_id, stock_symbol, price, date, price_source, provider, provider_timestamp, fetch_attempts, consensus, divergent)
+		SELECT p.instrument_id, p.symbol, p.price, $9::date, p.source, p.provider, p.provider_timestamp, p.attempts, p.consensus, p.divergent
+		FROM `+priceRows+`
+		ON CONFLICT (instrument_id, date) DO UPDATE
+		SET price = EXCLUDED.price,
+			price_source = EXCLUDED.price_source,
+			provider = EXCLUDED.provider,
+			provider_timestamp = EXCLUDED.provider_timestamp,
+			fetch_attempts = EXCLUDED.fetch_attempts,
+			consensus = EXCLUDED.consensus,
+			divergent = EXCLUDED.divergent
+	`, append(all.args(), marketDate)...); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_price_ticks
+			(instrument_id, stock_symbol, price, price_source, provider, provider_timestamp, consensus, divergent, fetched_at)
+		SELECT p.instrument_id, p.symbol, p.price, p.source, p.provider, p.provider_timestamp, p.consensus, p.divergent, NOW()
+		FROM `+priceRows, all.args()...); err != nil {
+		return err
+	}
+
+	// Carried-forward prices are not new observations and leave the bars alone.
+	if len(current.symbols) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO stock_price_daily_bars (instrument_id, stock_symbol, date, open, high, low, close, tick_count, updated_at)
+			SELECT p.instrument_id, p.symbol, $9::date, p.price, p.price, p.price, p.price, 1, NOW()
+			FROM `+priceRows+`
+			ON CONFLICT (instrument_id, date) DO UPDATE
+			SET high = GREATEST(stock_price_daily_bars.high, EXCLUDED.high),
+				low = LEAST(stock_price_daily_bars.low, EXCLUDED.low),
+				close = EXCLUDED.close,
+				tick_count = stock_price_daily_bars.tick_count + 1,
+				updated_at = NOW()
+		`, append(current.args(), marketDate)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePricesWithRetry retries writePrices with a short backoff until ctx
+// ends.
+func writePricesWithRetry(ctx context.Context, db *sql.DB, marketDate string, batch []symbolPrice) error {
+	var err error
+	for attempt := 1; attempt <= maxWriteAttempts; attempt++ {
+		if err = writePrices(ctx, db, marketDate, batch); err == nil {
+			return nil
+		}
+		logrus.WithError(err).Warnf("Retry %d: Failed to write %d prices", attempt, len(batch))
+		if attempt == maxWriteAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * writeRetryBackoff):
+		}
+	}
+	return err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
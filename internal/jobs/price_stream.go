@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceUpdate is published whenever a new price is stored for a symbol.
+type PriceUpdate struct {
+	Symbol        string    `json:"symbol"`
+	Price         float64   `json:"price"`
+	PreviousPrice float64   `json:"previous_price,omitempty"`
+	Source        string    `json:"source"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// maxPriceSubscribers caps the open subscriptions, and so the open price and
+// portfolio streams, per process.
+const maxPriceSubscribers = 1000
+
+// ErrTooManySubscribers is returned by Subscribe when the broker is full.
+var ErrTooManySubscribers = errors.New("too many open price subscriptions")
+
+// PriceBroker fans price updates out to in-process subscribers. Publishing
+// never blocks: each subscription keeps only the latest update per symbol
+// until the subscriber reads it, so a slow reader skips intermediate prices
+// instead of holding up the updater or growing without bound.
+type PriceBroker struct {
+	mu     sync.Mutex
+	subs   map[*PriceSubscription]struct{}
+	max    int // 0 means no limit
+	closed bool
+}
+
+// PriceSubscription receives coalesced price updates. Ready is signalled when
+// updates are pending and closed when the subscription ends.
+type PriceSubscription struct {
+	broker  *PriceBroker
+	symbols map[string]bool // nil means all symbols
+
+	mu      sync.Mutex
+	pending map[string]PriceUpdate
+	ready   chan struct{}
+	done    bool
+}
+
+var priceBroker = NewPriceBroker(maxPriceSubscribers)
+
+// NewPriceBroker returns a broker that accepts at most max open
+// subscriptions, or any number when max is 0.
+func NewPriceBroker(max int) *PriceBroker {
+	return &PriceBroker{subs: make(map[*PriceSubscription]struct{}), max: max}
+}
+
+// SubscribePrices subscribes to the updater's price updates for symbols, or
+// for every symbol when none are given. It fails with ErrTooManySubscribers
+// when the process already has the maximum number of subscriptions open.
+func SubscribePrices(symbols []string) (*PriceSubscription, error) {
+	return priceBroker.Subscribe(symbols)
+}
+
+// ClosePriceStreams ends every subscription, e.g. on server shutdown.
+func ClosePriceStreams() {
+	priceBroker.Close()
+}
+
+// Subscribe opens a subscription, checking the limit under the broker's lock
+// so concurrent callers cannot overshoot it. Subscribing to a closed broker
+// returns a subscription that is already closed.
+func (b *PriceBroker) Subscribe(symbols []string) (*PriceSubscription, error) {
+	s := &PriceSubscription{
+		broker:  b,
+		pending: make(map[string]PriceUpdate),
+		ready:   make(chan struct{}, 1),
+	}
+	if len(symbols) > 0 {
+		s.symbols = make(map[string]bool, len(symbols))
+		for _, sym := range symbols {
+			s.symbols[strings.ToUpper(sym)] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.close()
+		return s, nil
+	}
+	if b.max > 0 && len(b.subs) >= b.max {
+		return nil, ErrTooManySubscribers
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Publish hands u to every interested subscriber.
+func (b *PriceBroker) Publish(u PriceUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		s.offer(u)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *PriceBroker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *PriceBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		s.close()
+		delete(b.subs, s)
+	}
+}
+
+func (s *PriceSubscription) offer(u PriceUpdate) {
+	if s.symbols != nil && !s.symbols[strings.ToUpper(u.Symbol)] {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	if prev, ok := s.pending[u.Symbol]; ok {
+		// Keep the price the subscriber last saw as the previous one.
+		u.PreviousPrice = prev.PreviousPrice
+	}
+	s.pending[u.Symbol] = u
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when Drain has updates to return and is closed when the
+// subscription ends.
+func (s *PriceSubscription) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Drain returns the pending updates ordered by symbol.
+func (s *PriceSubscription) Drain() []PriceUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]PriceUpdate, 0, len(s.pending))
+	for _, u := range s.pending {
+		out = append(out, u)
+	}
+	s.pending = make(map[string]PriceUpdate)
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// Unsubscribe ends the subscription. It is safe to call more than once.
+func (s *PriceSubscription) Unsubscribe() {
+	s.broker.mu.Lock()
+	delete(s.broker.subs, s)
+	s.broker.mu.Unlock()
+	s.close()
+}
+
+func (s *PriceSubscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.done = true
+		close(s.ready)
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPriceBrokerLimit(t *testing.T) {
+	b := NewPriceBroker(2)
+	first, err := b.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("first subscription: %v", err)
+	}
+	if _, err := b.Subscribe([]string{"TCS"}); err != nil {
+		t.Fatalf("second subscription: %v", err)
+	}
+	if _, err := b.Subscribe(nil); !errors.Is(err, ErrTooManySubscribers) {
+		t.Fatalf("third subscription: err = %v, want ErrTooManySubscribers", err)
+	}
+
+	first.Unsubscribe()
+	first.Unsubscribe()
+	if _, err := b.Subscribe(nil); err != nil {
+		t.Fatalf("subscription after one ended: %v", err)
+	}
+	if got := b.Subscribers(); got != 2 {
+		t.Errorf("Subscribers() = %d, want 2", got)
+	}
+}
+
+func TestPriceBrokerLimitConcurrent(t *testing.T) {
+	const limit = 10
+	b := NewPriceBroker(limit)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 5*limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.Subscribe(nil); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != limit || b.Subscribers() != limit {
+		t.Errorf("accepted %d, open %d; want %d of each", accepted, b.Subscribers(), limit)
+	}
+}
+
+func TestPriceBrokerUnlimitedAndClosed(t *testing.T) {
+	b := NewPriceBroker(0)
+	for i := 0; i < 3; i++ {
+		if _, err := b.Subscribe(nil); err != nil {
+			t.Fatalf("subscription %d: %v", i, err)
+		}
+	}
+
+	b.Close()
+	s, err := b.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("subscribing to a closed broker: %v", err)
+	}
+	if _, open := <-s.Ready(); open {
+		t.Error("subscription to a closed broker should already be closed")
+	}
+}
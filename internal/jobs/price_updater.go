@@ -1,189 +1,843 @@
 package jobs
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/market"
+	"github.com/LoganX64/stocky-api/internal/marketsim"
 	"github.com/LoganX64/stocky-api/internal/utils"
+	"github.com/lib/pq"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
-type PriceCache struct {
-	mu     sync.RWMutex
-	prices map[string]CachedPrice
-}
+// Price sources recorded with every stock_prices update and history row.
+const (
+	SourceProvider       = "provider"
+	SourceFallbackRandom = "fallback_random"
+	SourceCache          = "cache"
+	SourceCarriedForward = "carried_forward"
+)
 
-type CachedPrice struct {
-	Price     float64
-	UpdatedAt time.Time
+// PriceRecord is a price to store together with where it came from.
+// Provider and Attempts describe the provider fetch even when the price
+// itself came from a fallback. Consensus is set when several sources were
+// compared, and Provider is then the source whose price won.
+type PriceRecord struct {
+	Price             float64
+	Source            string
+	Provider          string
+	ProviderTimestamp *time.Time
+	Attempts          int
+	Consensus         *Consensus
 }
 
 var (
-	priceCache           = &PriceCache{prices: make(map[string]CachedPrice)}
-	maxPriceStaleMinutes = 120
+	maxFetchAttempts  = 3
+	fetchRetryBackoff = 500 * time.Millisecond
+	// writeTimeout bounds storing a run's prices. It is separate from the run
+	// timeout so prices fetched before the deadline are still saved.
+	writeTimeout = 30 * time.Second
 )
 
-func StartPriceUpdater(db *sql.DB) {
+// SymbolOutcome is what a run did for one symbol.
+type SymbolOutcome struct {
+	Symbol     string  `json:"symbol"`
+	OldPrice   float64 `json:"old_price"`
+	Price      float64 `json:"price"`
+	Source     string  `json:"source"`
+	Attempts   int     `json:"attempts"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS int64   `json:"duration_ms"`
+	// Quarantined is set when the provider price failed a sanity check and
+	// the stored price was carried forward instead.
+	Quarantined bool `json:"quarantined,omitempty"`
+	// Provider, SpreadPct and Divergent describe the sources' answers when
+	// several were compared.
+	Provider  string  `json:"provider,omitempty"`
+	SpreadPct float64 `json:"spread_pct,omitempty"`
+	Divergent bool    `json:"divergent,omitempty"`
+}
+
+// RunSummary describes one run of the price updater. Succeeded counts
+// provider prices, FellBack random and cached prices, Overridden pinned
+// prices, Quarantined provider prices held back by a sanity check, and Failed
+// symbols whose price was carried forward, could not be stored, or were
+// skipped when the run timed out. Divergent counts, across all of these,
+// symbols whose sources disagreed beyond the divergence threshold.
+type RunSummary struct {
+	Trigger          string          `json:"trigger"`
+	RequestedSymbols []string        `json:"requested_symbols,omitempty"`
+	StartedAt        time.Time       `json:"started_at"`
+	FinishedAt       time.Time       `json:"finished_at"`
+	DurationMS       int64           `json:"duration_ms"`
+	Provider         string          `json:"provider"`
+	Symbols          int             `json:"symbols"`
+	Succeeded        int             `json:"succeeded"`
+	FellBack         int             `json:"fell_back"`
+	Overridden       int             `json:"overridden"`
+	Quarantined      int             `json:"quarantined"`
+	Failed           int             `json:"failed"`
+	Divergent        int             `json:"divergent"`
+	TimedOut         bool            `json:"timed_out"`
+	Error            string          `json:"error,omitempty"`
+	Outcomes         []SymbolOutcome `json:"outcomes"`
+}
+
+func (s *RunSummary) count() {
+	s.Succeeded, s.FellBack, s.Overridden, s.Quarantined, s.Failed, s.Divergent = 0, 0, 0, 0, 0, 0
+	for _, o := range s.Outcomes {
+		if o.Divergent {
+			s.Divergent++
+		}
+		switch {
+		case o.Quarantined:
+			s.Quarantined++
+		case o.Error != "" && o.Source == "":
+			s.Failed++
+		case o.Source == SourceProvider:
+			s.Succeeded++
+		case o.Source == SourceFallbackRandom || o.Source == SourceCache:
+			s.FellBack++
+		case o.Source == SourceOverride:
+			s.Overridden++
+		default:
+			s.Failed++
+		}
+	}
+}
+
+// PriceUpdater refreshes stock_prices from a provider. Symbols are fetched by
+// a bounded pool of workers, each with its own deadline, and the results of a
+// run are written in one batch.
+type PriceUpdater struct {
+	db       *sql.DB
+	provider PriceProvider
+	cfg      config.PriceUpdater
+	calendar *market.Calendar
+	leader   Leadership
+
+	running sync.Mutex
+
+	mu            sync.RWMutex
+	lastSummary   *RunSummary
+	runningSince  *time.Time
+	cron          *cron.Cron
+	refreshEntry  cron.EntryID
+	snapshotEntry cron.EntryID
+
+	// fallbackRands holds a generator per symbol for fallback moves when
+	// fallbackSeeded is set.
+	fallbackMu     sync.Mutex
+	fallbackSeeded bool
+	fallbackSeed   int64
+	fallbackRands  map[string]*rand.Rand
+}
+
+// NewPriceUpdater returns an updater for provider. When provider is or
+// includes the market simulator, fallback moves are seeded with its seed, so
+// a simulated run is reproducible end to end.
+func NewPriceUpdater(db *sql.DB, provider PriceProvider, cfg config.PriceUpdater, calendar *market.Calendar) *PriceUpdater {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	u := &PriceUpdater{db: db, provider: provider, cfg: cfg, calendar: calendar}
+	if seed, ok := simulatorSeed(provider); ok {
+		u.SetFallbackSeed(seed)
+	}
+	return u
+}
+
+// simulatorSeed returns the seed of the market simulator behind p, if any.
+func simulatorSeed(p PriceProvider) (int64, bool) {
+	switch p := p.(type) {
+	case *MarketSimProvider:
+		return p.Sim.Config().Seed, true
+	case *ConsensusProvider:
+		for _, source := range p.Sources {
+			if seed, ok := simulatorSeed(source); ok {
+				return seed, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// SetFallbackSeed makes fallback moves reproducible. Each symbol draws from
+// its own generator seeded from seed and the symbol, so the moves do not
+// depend on the order the workers fetch in.
+func (u *PriceUpdater) SetFallbackSeed(seed int64) {
+	u.fallbackMu.Lock()
+	defer u.fallbackMu.Unlock()
+	u.fallbackSeeded, u.fallbackSeed = true, seed
+	u.fallbackRands = make(map[string]*rand.Rand)
+}
 
-	initializePriceCache(db)
+// fallbackDraw returns the next draw in [0, 1) for a symbol's fallback move.
+func (u *PriceUpdater) fallbackDraw(symbol string) float64 {
+	u.fallbackMu.Lock()
+	defer u.fallbackMu.Unlock()
+	if !u.fallbackSeeded {
+		return rand.Float64()
+	}
+	key := strings.ToUpper(symbol)
+	r, ok := u.fallbackRands[key]
+	if !ok {
+		// A stream of its own, apart from the simulator's path for the symbol.
+		r = marketsim.SymbolRand(u.fallbackSeed, "fallback:"+key)
+		u.fallbackRands[key] = r
+	}
+	return r.Float64()
+}
 
-	c := cron.New(cron.WithChain(
-		cron.Recover(cron.DefaultLogger),
-	))
+// Leadership tells whether this instance should run scheduled jobs.
+type Leadership interface {
+	IsLeader() bool
+}
 
-	_, err := c.AddFunc("0 * * * *", // "@every 10s" makes it 10 seconds and "0 * * * *" is every hour
-		func() {
-			updatePrices(db)
-		})
+// priceRunLockKey is the Postgres advisory lock held for the duration of a
+// run, so that runs never overlap across instances.
+const priceRunLockKey int64 = 7_301_043
+
+// SetLeadership makes scheduled runs and snapshots conditional on l. Without
+// it every instance runs them.
+func (u *PriceUpdater) SetLeadership(l Leadership) {
+	u.leader = l
+}
+
+func (u *PriceUpdater) isLeader() bool {
+	return u.leader == nil || u.leader.IsLeader()
+}
+
+// Start loads the price cache and schedules the refresh and the closing-price
+// snapshot. Both cron specs are read in the market time zone.
+func (u *PriceUpdater) Start() {
+
+	initializePriceCache(u.db)
+
+	c := cron.New(
+		cron.WithLocation(u.calendar.Location),
+		cron.WithChain(cron.Recover(cron.DefaultLogger)),
+	)
+
+	// e.g. "@every 10s" for every 10 seconds, "0 * * * *" for every hour
+	refreshEntry, err := c.AddFunc(u.cfg.Schedule, u.scheduledRun)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to schedule price updater")
 	}
+	snapshotEntry, err := c.AddFunc(u.cfg.SnapshotSchedule, u.closingSnapshot)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to schedule closing price snapshot")
+	}
 	c.Start()
-	logrus.Info("Hourly price updater started")
+
+	u.mu.Lock()
+	u.cron, u.refreshEntry, u.snapshotEntry = c, refreshEntry, snapshotEntry
+	u.mu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"provider": u.provider.Name(),
+		"workers":  u.cfg.Workers,
+		"schedule": u.cfg.Schedule,
+		"snapshot": u.cfg.SnapshotSchedule,
+	}).Info("Price updater started")
+}
+
+// scheduledRun refreshes prices if the market is open. Outside the session
+// prices do not move, so a refresh would only record fallback noise.
+func (u *PriceUpdater) scheduledRun() {
+	if !u.isLeader() {
+		logrus.Debug("Not the leader, skipping price update")
+		return
+	}
+	now := time.Now()
+	if !u.calendar.IsOpen(now) {
+		entry := logrus.WithField("next_open", u.calendar.NextOpen(now).Format(time.RFC3339))
+		if h, ok := u.calendar.Holiday(now); ok {
+			entry = entry.WithField("holiday", h.Description)
+		}
+		entry.Debug("Market closed, skipping price update")
+		return
+	}
+	u.Run(context.Background(), RunOptions{Trigger: TriggerScheduled})
 }
 
-func initializePriceCache(db *sql.DB) {
-	rows, err := db.Query(`
-		SELECT stock_symbol, price, updated_at 
+// closingSnapshot runs a final refresh after the session ends and stores the
+// resulting prices as the day's closing prices. It does nothing on days the
+// exchange is closed.
+func (u *PriceUpdater) closingSnapshot() {
+	if !u.isLeader() {
+		logrus.Debug("Not the leader, skipping closing price snapshot")
+		return
+	}
+	now := time.Now()
+	if !u.calendar.IsTradingDay(now) {
+		logrus.Debug("Not a trading day, skipping closing price snapshot")
+		return
+	}
+	if _, sessionClose := u.calendar.SessionBounds(now); now.Before(sessionClose) {
+		logrus.Warn("Closing price snapshot scheduled before the session ends, skipping")
+		return
+	}
+
+	if _, err := u.Run(context.Background(), RunOptions{Trigger: TriggerClosing}); err != nil {
+		logrus.WithError(err).Warn("Closing refresh not run, snapshotting the current prices")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	tradingDate := now.In(u.calendar.Location).Format("2006-01-02")
+	res, err := u.db.ExecContext(ctx, `
+		INSERT INTO stock_closing_prices (stock_symbol, date, price, price_source, captured_at)
+		SELECT stock_symbol, $1::date, price, price_source, NOW()
 		FROM stock_prices
-	`)
+		ON CONFLICT (stock_symbol, date) DO UPDATE
+		SET price = EXCLUDED.price,
+			price_source = EXCLUDED.price_source,
+			captured_at = EXCLUDED.captured_at
+	`, tradingDate)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to initialize price cache")
+		logrus.WithError(err).Error("Failed to store closing prices")
 		return
 	}
-	defer rows.Close()
+	n, _ := res.RowsAffected()
+	logrus.WithFields(logrus.Fields{"date": tradingDate, "symbols": n}).Info("Closing prices stored")
+}
 
-	for rows.Next() {
-		var symbol string
-		var price float64
-		var updatedAt time.Time
-		if err := rows.Scan(&symbol, &price, &updatedAt); err != nil {
-			continue
+// LastSummary returns the summary of the most recent completed run.
+func (u *PriceUpdater) LastSummary() (RunSummary, bool) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.lastSummary == nil {
+		return RunSummary{}, false
+	}
+	return *u.lastSummary, true
+}
+
+// ErrRunInProgress is returned by Run when a previous run has not finished.
+var ErrRunInProgress = errors.New("price update already in progress")
+
+// What started a run.
+const (
+	TriggerScheduled = "scheduled"
+	TriggerClosing   = "closing_snapshot"
+	TriggerManual    = "manual"
+)
+
+// RunOptions describes one run. Symbols limits the run to those stocks;
+// empty means all.
+type RunOptions struct {
+	Trigger string
+	Symbols []string
+}
+
+// Run refreshes every symbol once. Runs do not overlap: if one is still in
+// progress Run returns ErrRunInProgress without doing anything.
+func (u *PriceUpdater) Run(ctx context.Context, opts RunOptions) (RunSummary, error) {
+	if !u.running.TryLock() {
+		logrus.Warn("Previous price update still running, skipping this run")
+		return RunSummary{}, ErrRunInProgress
+	}
+	defer u.running.Unlock()
+	return u.runLocked(ctx, opts), nil
+}
+
+// Trigger starts a run in the background and returns once it has begun, or
+// ErrRunInProgress if another run holds the lock. Manual runs ignore market
+// hours.
+func (u *PriceUpdater) Trigger(opts RunOptions) error {
+	if !u.running.TryLock() {
+		return ErrRunInProgress
+	}
+	go func() {
+		defer u.running.Unlock()
+		u.runLocked(context.Background(), opts)
+	}()
+	return nil
+}
+
+func (u *PriceUpdater) runLocked(ctx context.Context, opts RunOptions) RunSummary {
+	started := time.Now()
+	u.mu.Lock()
+	u.runningSince = &started
+	u.mu.Unlock()
+
+	var summary RunSummary
+	if unlock, err := u.lockRun(ctx); err != nil {
+		logrus.WithError(err).Warn("Price update not run")
+		now := time.Now()
+		summary = RunSummary{
+			StartedAt: started, FinishedAt: now, Trigger: opts.Trigger, RequestedSymbols: opts.Symbols,
+			Provider: u.provider.Name(), Error: err.Error(), Outcomes: []SymbolOutcome{},
 		}
-		priceCache.SetPrice(symbol, price, updatedAt)
+	} else {
+		summary = u.run(ctx, opts)
+		unlock()
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"trigger":     summary.Trigger,
+		"provider":    summary.Provider,
+		"symbols":     summary.Symbols,
+		"succeeded":   summary.Succeeded,
+		"fell_back":   summary.FellBack,
+		"overridden":  summary.Overridden,
+		"failed":      summary.Failed,
+		"timed_out":   summary.TimedOut,
+		"duration_ms": summary.DurationMS,
+	}).Info("Price update finished")
+
+	u.mu.Lock()
+	u.lastSummary = &summary
+	u.runningSince = nil
+	u.mu.Unlock()
+	return summary
+}
+
+// errRunElsewhere is reported when another instance holds the run lock.
+var errRunElsewhere = errors.New("another instance is refreshing prices")
+
+// lockRun takes the cluster-wide run lock on a dedicated connection. The
+// returned func releases it.
+func (u *PriceUpdater) lockRun(ctx context.Context) (func(), error) {
+	conn, err := u.db.Conn(ctx)
+	if err != nil {
+		return nil, err
 	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, priceRunLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, errRunElsewhere
+	}
+	return func() {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, priceRunLockKey); err != nil {
+			logrus.WithError(err).Warn("Failed to release price run lock")
+			// Drop the session so the lock goes with it.
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
 }
 
-func (pc *PriceCache) SetPrice(symbol string, price float64, updatedAt time.Time) {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-	pc.prices[symbol] = CachedPrice{
-		Price:     price,
-		UpdatedAt: updatedAt,
+// Status describes the updater for operators.
+type Status struct {
+	Provider         string      `json:"provider"`
+	Schedule         string      `json:"schedule"`
+	SnapshotSchedule string      `json:"snapshot_schedule"`
+	Workers          int         `json:"workers"`
+	Leader           bool        `json:"leader"`
+	Running          bool        `json:"running"`
+	RunningSince     *time.Time  `json:"running_since,omitempty"`
+	MarketOpen       bool        `json:"market_open"`
+	NextRun          *time.Time  `json:"next_run,omitempty"`
+	NextSnapshot     *time.Time  `json:"next_snapshot,omitempty"`
+	LastRun          *RunSummary `json:"last_run"`
+}
+
+// Status reports whether a run is in progress, when the next one is due and
+// how the last one went. NextRun is the next scheduled refresh that falls in
+// the trading session, since refreshes outside it are skipped.
+func (u *PriceUpdater) Status() Status {
+	now := time.Now()
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	st := Status{
+		Provider:         u.provider.Name(),
+		Schedule:         u.cfg.Schedule,
+		SnapshotSchedule: u.cfg.SnapshotSchedule,
+		Workers:          u.cfg.Workers,
+		Leader:           u.isLeader(),
+		Running:          u.runningSince != nil,
+		RunningSince:     u.runningSince,
+		MarketOpen:       u.calendar.IsOpen(now),
+		LastRun:          u.lastSummary,
 	}
+	if u.cron != nil {
+		if next := u.nextOpenRun(u.cron.Entry(u.refreshEntry).Schedule, now); !next.IsZero() {
+			st.NextRun = &next
+		}
+		if next := u.cron.Entry(u.snapshotEntry).Next; !next.IsZero() {
+			st.NextSnapshot = &next
+		}
+	}
+	return st
 }
 
-func (pc *PriceCache) GetPrice(symbol string) (float64, time.Time, bool) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
-	if cached, ok := pc.prices[symbol]; ok {
-		return cached.Price, cached.UpdatedAt, true
+// nextOpenRun returns the first time after now at which schedule fires while
+// the market is open, looking at most a few weeks ahead.
+func (u *PriceUpdater) nextOpenRun(schedule cron.Schedule, now time.Time) time.Time {
+	if schedule == nil {
+		return time.Time{}
 	}
-	return 0, time.Time{}, false
+	limit := now.AddDate(0, 0, 21)
+	for t := schedule.Next(now.In(u.calendar.Location)); !t.IsZero() && t.Before(limit); t = schedule.Next(t) {
+		if u.calendar.IsOpen(t) {
+			return t
+		}
+		// Jump to the next session instead of stepping through a closed
+		// night or weekend one tick at a time.
+		if open := u.calendar.NextOpen(t); open.After(t) {
+			t = open.Add(-time.Second)
+		}
+	}
+	return time.Time{}
 }
 
-func updatePrices(db *sql.DB) {
-	logrus.Info("Updating stock prices...")
+type symbolJob struct {
+	symbol   string
+	oldPrice float64
+
+	// Sanity limits, see checkPrice.
+	referencePrice float64
+	bandPct        float64
+	maxMovePct     float64
+}
+
+func (u *PriceUpdater) run(parent context.Context, opts RunOptions) RunSummary {
+	summary := RunSummary{
+		StartedAt:        time.Now(),
+		Trigger:          opts.Trigger,
+		RequestedSymbols: opts.Symbols,
+		Provider:         u.provider.Name(),
+		Outcomes:         []SymbolOutcome{},
+	}
+	finish := func() RunSummary {
+		summary.count()
+		summary.FinishedAt = time.Now()
+		summary.DurationMS = summary.FinishedAt.Sub(summary.StartedAt).Milliseconds()
+		return summary
+	}
+
+	logrus.WithField("provider", u.provider.Name()).Info("Updating stock prices...")
+
+	ctx := parent
+	if u.cfg.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, u.cfg.RunTimeout)
+		defer cancel()
+	}
+
+	overrides, err := syncOverrides(ctx, u.db)
+	if err != nil {
+		// Without the override list we cannot tell which prices are pinned;
+		// skip the run rather than overwrite them.
+		logrus.WithError(err).Error("Failed to load price overrides, skipping price update")
+		summary.Error = "failed to load price overrides"
+		return finish()
+	}
 
-	rows, err := db.Query(`SELECT stock_symbol, price FROM stock_prices`)
+	jobs, err := u.loadSymbols(ctx, opts.Symbols)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to fetch stock prices")
-		return
+		summary.Error = "failed to fetch stock prices"
+		return finish()
+	}
+	summary.Symbols = len(jobs)
+
+	results := u.fetchAll(ctx, jobs, overrides)
+	summary.TimedOut = ctx.Err() == context.DeadlineExceeded
+
+	writeCtx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	summary.Outcomes = u.store(writeCtx, results)
+	return finish()
+}
+
+// loadSymbols returns every symbol with its stored price and sanity limits.
+// The circuit band is anchored on the last closing price before today,
+// falling back to the last daily bar and then the stored price.
+func (u *PriceUpdater) loadSymbols(ctx context.Context, symbols []string) ([]symbolJob, error) {
+	today := time.Now().In(u.calendar.Location).Format("2006-01-02")
+	rows, err := u.db.QueryContext(ctx, `
+		SELECT sp.stock_symbol, sp.price,
+			COALESCE(cp.price, bar.close, sp.price),
+			COALESCE(b.band_pct, $2), COALESCE(b.max_move_pct, $3)
+		FROM stock_prices sp
+		LEFT JOIN stock_price_bands b ON b.instrument_id = sp.instrument_id
+		LEFT JOIN LATERAL (
+			SELECT price FROM stock_closing_prices c
+			WHERE c.instrument_id = sp.instrument_id AND c.date < $1::date
+			ORDER BY c.date DESC LIMIT 1
+		) cp ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT close FROM stock_price_daily_bars d
+			WHERE d.instrument_id = sp.instrument_id AND d.date < $1::date
+			ORDER BY d.date DESC LIMIT 1
+		) bar ON TRUE
+		WHERE cardinality($4::text[]) = 0 OR UPPER(sp.stock_symbol) = ANY($4)
+		ORDER BY sp.stock_symbol
+	`, today, u.cfg.BandPct, u.cfg.MaxMovePct, pq.Array(upperAll(symbols)))
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
+	var jobs []symbolJob
 	for rows.Next() {
-		var symbol string
-		var oldPrice float64
-		if err := rows.Scan(&symbol, &oldPrice); err != nil {
+		var j symbolJob
+		if err := rows.Scan(&j.symbol, &j.oldPrice, &j.referencePrice, &j.bandPct, &j.maxMovePct); err != nil {
 			logrus.WithError(err).Warn("Failed to scan stock price")
 			continue
 		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
 
-		newPrice, err := getLatestPrice(symbol, oldPrice)
-		if err != nil {
-			logrus.WithError(err).Warnf("Failed to get new price for %s, using fallback", symbol)
-			factor := 0.99 + rand.Float64()*0.02
-			newPrice = utils.RoundAmount(oldPrice * factor)
-		}
-
-		updateSuccess := false
-		if err := safeUpdatePrice(db, symbol, newPrice); err != nil {
-
-			if cachedPrice, cachedTime, ok := priceCache.GetPrice(symbol); ok {
-				staleness := time.Since(cachedTime)
-				if staleness.Minutes() < float64(maxPriceStaleMinutes) {
-					logrus.Infof("Using cached price for %s (%.2f mins old)", symbol, staleness.Minutes())
-					if err := safeUpdatePrice(db, symbol, cachedPrice); err != nil {
-						logrus.Warnf("Failed to update with cached price for %s", symbol)
-					} else {
-						newPrice = cachedPrice
-						updateSuccess = true
-					}
-				} else {
-					logrus.Warnf("Cached price for %s too old (%.2f mins), keeping last DB price", symbol, staleness.Minutes())
-				}
-			}
-			if !updateSuccess {
-				continue
+type fetchResult struct {
+	job      symbolJob
+	record   PriceRecord
+	err      error
+	skipped  bool
+	duration time.Duration
+
+	// rejected is the provider price held back when violation is set; record
+	// then carries the stored price forward.
+	rejected  PriceRecord
+	violation *sanityViolation
+}
+
+// fetchAll resolves a price for every symbol using the worker pool. Symbols
+// not finished before ctx ends are returned as skipped.
+func (u *PriceUpdater) fetchAll(ctx context.Context, jobs []symbolJob, overrides map[string]PriceOverride) []fetchResult {
+	results := make([]fetchResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < u.cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = u.fetchOne(ctx, jobs[i], overrides)
 			}
-		} else {
-			updateSuccess = true
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(jobs) && ctx.Err() == nil; next++ {
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break dispatch
 		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := next; i < len(jobs); i++ {
+		results[i] = fetchResult{job: jobs[i], skipped: true, err: ctx.Err()}
+	}
+	return results
+}
+
+func (u *PriceUpdater) fetchOne(ctx context.Context, job symbolJob, overrides map[string]PriceOverride) fetchResult {
+	start := time.Now()
+	res := fetchResult{job: job}
 
-		if updateSuccess {
-			priceCache.SetPrice(symbol, newPrice, time.Now())
+	if o, ok := overrides[strings.ToUpper(job.symbol)]; ok {
+		res.record = PriceRecord{Price: o.Price, Source: SourceOverride}
+		res.duration = time.Since(start)
+		return res
+	}
+
+	symbolCtx := ctx
+	if u.cfg.SymbolTimeout > 0 {
+		var cancel context.CancelFunc
+		symbolCtx, cancel = context.WithTimeout(ctx, u.cfg.SymbolTimeout)
+		defer cancel()
+	}
+
+	res.record, res.err = fetchPrice(symbolCtx, u.provider, job.symbol, job.oldPrice)
+	if c := res.record.Consensus; c != nil && c.Divergent {
+		logrus.WithFields(logrus.Fields{
+			"spread_pct": c.SpreadPct,
+			"winner":     c.Winner,
+		}).Warnf("Price sources disagree for %s", job.symbol)
+	}
+	if res.err == nil {
+		if v := checkPrice(job, res.record.Price); v != nil {
+			logrus.WithField("reason", v.Reason).Warnf("Quarantining price for %s: %s", job.symbol, v.Detail)
+			res.rejected, res.violation, res.err = res.record, v, v
+			res.record = PriceRecord{Price: job.oldPrice, Source: SourceCarriedForward, Provider: res.rejected.Provider, Attempts: res.rejected.Attempts, Consensus: res.rejected.Consensus}
 		}
+	} else if ctx.Err() != nil {
+		// The run timed out mid-fetch; leave the price for the next run
+		// rather than invent a move.
+		res.skipped = true
+	} else {
+		logrus.WithError(res.err).Warnf("Failed to get new price for %s after %d attempts, using fallback", job.symbol, res.record.Attempts)
+		factor := 0.99 + u.fallbackDraw(job.symbol)*0.02
+		res.record.Price = utils.RoundAmount(job.oldPrice * factor)
+		res.record.Source = SourceFallbackRandom
+	}
+	res.duration = time.Since(start)
+	return res
+}
 
-		if err := safeInsertPriceHistory(db, symbol, newPrice); err != nil {
-			logrus.WithError(err).Errorf("Failed to insert/update price history for %s", symbol)
-			continue
+// store writes the fetched prices in one batch. If the batch cannot be
+// written, fresh cached prices are used where available and the remaining
+// symbols are carried forward, and the batch is written again.
+func (u *PriceUpdater) store(ctx context.Context, results []fetchResult) []SymbolOutcome {
+	var batch []symbolPrice
+	oldPrices := make(map[string]float64, len(results))
+	for _, r := range results {
+		if !r.skipped {
+			batch = append(batch, symbolPrice{Symbol: r.job.symbol, PriceRecord: r.record})
+			oldPrices[r.job.symbol] = r.job.oldPrice
 		}
+	}
 
-		logrus.Infof("Updated %s: %.2f -> %.2f", symbol, oldPrice, newPrice)
+	marketDate := time.Now().In(u.calendar.Location).Format("2006-01-02")
+	stored := true
+	if err := writePricesWithRetry(ctx, u.db, marketDate, batch); err != nil {
+		logrus.WithError(err).Error("Failed to write prices, falling back to cached prices")
+		for i := range batch {
+			p := &batch[i]
+			if p.Source == SourceOverride {
+				continue
+			}
+			if cached, ok := priceCache.freshPrice(p.Symbol); ok {
+				p.PriceRecord = PriceRecord{Price: cached, Source: SourceCache, Provider: p.Provider, Attempts: p.Attempts}
+			} else {
+				p.PriceRecord = PriceRecord{Price: oldPrices[p.Symbol], Source: SourceCarriedForward, Provider: p.Provider, Attempts: p.Attempts}
+			}
+		}
+		if err := writePricesWithRetry(ctx, u.db, marketDate, batch); err != nil {
+			logrus.WithError(err).Error("Failed to write fallback prices")
+			stored = false
+		}
 	}
-}
 
-func getLatestPrice(symbol string, lastPrice float64) (float64, error) {
+	var quarantined []quarantineEntry
+	for _, r := range results {
+		if r.violation != nil {
+			quarantined = append(quarantined, quarantineEntry{job: r.job, record: r.rejected, violation: r.violation})
+		}
+	}
+	if err := quarantinePrices(ctx, u.db, quarantined); err != nil {
+		logrus.WithError(err).Errorf("Failed to quarantine %d prices", len(quarantined))
+	}
 
-	if rand.Float64() < 0.1 {
-		return 0, sql.ErrConnDone
+	bySymbol := make(map[string]symbolPrice, len(batch))
+	for _, p := range batch {
+		bySymbol[p.Symbol] = p
 	}
 
-	factor := 0.95 + rand.Float64()*0.10
-	return utils.RoundAmount(lastPrice * factor), nil
+	now := time.Now()
+	outcomes := make([]SymbolOutcome, 0, len(results))
+	for _, r := range results {
+		o := SymbolOutcome{
+			Symbol:     r.job.symbol,
+			OldPrice:   r.job.oldPrice,
+			Attempts:   r.record.Attempts,
+			DurationMS: r.duration.Milliseconds(),
+		}
+		if r.err != nil {
+			o.Error = r.err.Error()
+		}
+		o.Quarantined = r.violation != nil
+		if c := r.record.Consensus; c != nil {
+			o.Provider, o.SpreadPct, o.Divergent = c.Winner, c.SpreadPct, c.Divergent
+		}
+		switch {
+		case r.skipped:
+			o.Error = "skipped: run timed out"
+		case !stored:
+			o.Error = "price could not be stored"
+		default:
+			p := bySymbol[r.job.symbol]
+			o.Price = p.Price
+			o.Source = p.Source
+			if p.Source != SourceCarriedForward {
+				priceCache.SetPrice(p.Symbol, p.Price, now)
+			}
+			logrus.WithField("source", p.Source).Infof("Updated %s: %.2f -> %.2f", p.Symbol, r.job.oldPrice, p.Price)
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes
 }
 
-func safeUpdatePrice(db *sql.DB, symbol string, newPrice float64) error {
-	const maxRetries = 3
-	for i := 0; i < maxRetries; i++ {
-		_, err := db.Exec(`UPDATE stock_prices SET price=$1, updated_at=NOW() WHERE stock_symbol=$2`, newPrice, symbol)
+// fetchPrice asks provider for a quote, retrying failed fetches until ctx
+// ends. The returned record always carries the number of attempts made, also
+// on error.
+func fetchPrice(ctx context.Context, provider PriceProvider, symbol string, lastPrice float64) (PriceRecord, error) {
+	record := PriceRecord{Provider: provider.Name()}
+
+	var err error
+	for record.Attempts < maxFetchAttempts {
+		if record.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return record, err
+			case <-time.After(time.Duration(record.Attempts) * fetchRetryBackoff):
+			}
+		}
+		record.Attempts++
+
+		var quote Quote
+		quote, err = provider.FetchPrice(ctx, symbol, lastPrice)
 		if err == nil {
-			return nil
+			record.Price = utils.RoundAmount(quote.Price)
+			record.Source = SourceProvider
+			record.ProviderTimestamp = &quote.Timestamp
+			if quote.Provider != "" {
+				record.Provider = quote.Provider
+			}
+			record.Consensus = quote.Consensus
+			return record, nil
+		}
+		if errors.Is(err, ErrSymbolNotFound) || ctx.Err() != nil {
+			break
 		}
-		logrus.WithError(err).Warnf("Retry %d: Failed to update price for %s", i+1, symbol)
-		time.Sleep(time.Duration(i+1) * time.Second)
 	}
-	return sql.ErrConnDone
+	return record, err
 }
 
-func safeInsertPriceHistory(db *sql.DB, symbol string, price float64) error {
-	const maxRetries = 3
-	for i := 0; i < maxRetries; i++ {
-		_, err := db.Exec(`
-			INSERT INTO stock_price_history (stock_symbol, price, date)
-			VALUES ($1, $2, CURRENT_DATE)
-			ON CONFLICT (stock_symbol, date) DO UPDATE 
-			SET price = EXCLUDED.price
-		`, symbol, price)
-		if err == nil {
-			return nil
+func upperAll(symbols []string) []string {
+	out := make([]string, len(symbols))
+	for i, s := range symbols {
+		out[i] = strings.ToUpper(s)
+	}
+	return out
+}
+
+// UnknownSymbols returns the symbols that have no stored price.
+func UnknownSymbols(ctx context.Context, db *sql.DB, symbols []string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT s FROM unnest($1::text[]) AS s
+		WHERE NOT EXISTS (SELECT 1 FROM stock_prices WHERE UPPER(stock_symbol) = s)
+		ORDER BY s
+	`, pq.Array(upperAll(symbols)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unknown []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
 		}
-		logrus.WithError(err).Warnf("Retry %d: Failed to insert price history for %s", i+1, symbol)
-		time.Sleep(time.Duration(i+1) * time.Second)
+		unknown = append(unknown, s)
 	}
-	return sql.ErrConnDone
+	return unknown, rows.Err()
 }
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/LoganX64/stocky-api/internal/marketsim"
+)
+
+func TestFallbackDrawsAreSeededPerSymbol(t *testing.T) {
+	sim, err := marketsim.New(marketsim.DefaultConfig())
+	if err != nil {
+		t.Fatalf("marketsim.New: %v", err)
+	}
+	provider := &ConsensusProvider{Sources: []PriceProvider{&SimulatedProvider{}, NewMarketSimProvider(sim)}}
+
+	a := NewPriceUpdater(nil, provider, config.PriceUpdater{}, nil)
+	b := NewPriceUpdater(nil, provider, config.PriceUpdater{}, nil)
+	if !a.fallbackSeeded {
+		t.Fatal("fallback moves not seeded from the market simulator")
+	}
+
+	// b asks for the symbols in another order; each symbol's draws match.
+	var tcsA, infyA, tcsB, infyB []float64
+	for i := 0; i < 3; i++ {
+		tcsA = append(tcsA, a.fallbackDraw("TCS"))
+		infyA = append(infyA, a.fallbackDraw("INFY"))
+	}
+	for i := 0; i < 3; i++ {
+		infyB = append(infyB, b.fallbackDraw("infy"))
+	}
+	for i := 0; i < 3; i++ {
+		tcsB = append(tcsB, b.fallbackDraw("TCS"))
+	}
+	for i := range tcsA {
+		if tcsA[i] != tcsB[i] || infyA[i] != infyB[i] {
+			t.Fatalf("draw %d differs: TCS %v/%v, INFY %v/%v", i, tcsA[i], tcsB[i], infyA[i], infyB[i])
+		}
+	}
+
+	if NewPriceUpdater(nil, &SimulatedProvider{}, config.PriceUpdater{}, nil).fallbackSeeded {
+		t.Error("fallback moves seeded without a market simulator")
+	}
+}
@@ -0,0 +1,191 @@
+// Package leader elects one instance among replicas sharing a database to
+// run scheduled jobs.
+//
+// Leadership is a session-level Postgres advisory lock held on a dedicated
+// connection. If the leader stops or loses its connection, Postgres releases
+// the lock and the next follower to try takes over. The job_leaders table
+// mirrors who holds the lock so that any instance can report it.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Name is the job_leaders row of the scheduled jobs leadership.
+const Name = "scheduled_jobs"
+
+// Elector campaigns for leadership until stopped.
+type Elector struct {
+	db  *sql.DB
+	cfg config.Leader
+
+	mu     sync.RWMutex
+	conn   *sql.Conn
+	leader bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Info describes the current leader as recorded in job_leaders.
+type Info struct {
+	InstanceID  string    `json:"instance_id"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	// Stale is set when the leader has not renewed for several intervals,
+	// e.g. because it was killed before it could clear the row.
+	Stale bool `json:"stale"`
+}
+
+func New(db *sql.DB, cfg config.Leader) *Elector {
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = 5 * time.Second
+	}
+	return &Elector{db: db, cfg: cfg, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// InstanceID returns this instance's name.
+func (e *Elector) InstanceID() string {
+	return e.cfg.InstanceID
+}
+
+// IsLeader reports whether this instance currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Run campaigns and renews until Stop is called.
+func (e *Elector) Run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.RenewInterval)
+	defer ticker.Stop()
+	for {
+		e.tick()
+		select {
+		case <-e.stop:
+			e.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop resigns leadership, if held, so that another instance takes over
+// without waiting for the connection to time out.
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+func (e *Elector) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.RenewInterval)
+	defer cancel()
+	if e.IsLeader() {
+		e.renew(ctx)
+	} else {
+		e.campaign(ctx)
+	}
+}
+
+// campaign tries to take the lock once.
+func (e *Elector) campaign(ctx context.Context) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Leader election: failed to get a connection")
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, e.cfg.LockKey).Scan(&acquired); err != nil {
+		logrus.WithError(err).Warn("Leader election: failed to try the lock")
+		conn.Close()
+		return
+	}
+	if !acquired {
+		conn.Close()
+		return
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO job_leaders (name, instance_id, acquired_at, heartbeat_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET instance_id = EXCLUDED.instance_id, acquired_at = NOW(), heartbeat_at = NOW()
+	`, Name, e.cfg.InstanceID); err != nil {
+		// Leading without being visible is still correct; keep the lock.
+		logrus.WithError(err).Warn("Leader election: failed to record leadership")
+	}
+
+	e.mu.Lock()
+	e.conn, e.leader = conn, true
+	e.mu.Unlock()
+	logrus.WithField("instance_id", e.cfg.InstanceID).Info("Became leader for scheduled jobs")
+}
+
+// renew checks that the lock's session is still alive. Losing the session
+// means Postgres has released the lock, so leadership ends at once.
+func (e *Elector) renew(ctx context.Context) {
+	e.mu.RLock()
+	conn := e.conn
+	e.mu.RUnlock()
+
+	_, err := conn.ExecContext(ctx, `
+		UPDATE job_leaders SET heartbeat_at = NOW() WHERE name = $1 AND instance_id = $2
+	`, Name, e.cfg.InstanceID)
+	if err == nil {
+		return
+	}
+	logrus.WithError(err).Error("Lost leadership for scheduled jobs")
+	e.mu.Lock()
+	e.leader, e.conn = false, nil
+	e.mu.Unlock()
+	// Discard the session rather than return it to the pool, so the lock
+	// cannot linger on it.
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
+
+func (e *Elector) resign() {
+	e.mu.Lock()
+	conn, wasLeader := e.conn, e.leader
+	e.leader, e.conn = false, nil
+	e.mu.Unlock()
+	if !wasLeader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, `DELETE FROM job_leaders WHERE name = $1 AND instance_id = $2`, Name, e.cfg.InstanceID); err != nil {
+		logrus.WithError(err).Warn("Failed to clear leader record")
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, e.cfg.LockKey); err != nil {
+		logrus.WithError(err).Warn("Failed to release leader lock")
+	}
+	conn.Close()
+	logrus.WithField("instance_id", e.cfg.InstanceID).Info("Resigned leadership for scheduled jobs")
+}
+
+// Current returns the leader recorded in job_leaders, if any.
+func (e *Elector) Current(ctx context.Context) (Info, bool, error) {
+	var info Info
+	err := e.db.QueryRowContext(ctx, `
+		SELECT instance_id, acquired_at, heartbeat_at, heartbeat_at < NOW() - $2 * INTERVAL '1 millisecond'
+		FROM job_leaders WHERE name = $1
+	`, Name, 3*e.cfg.RenewInterval.Milliseconds()).Scan(&info.InstanceID, &info.AcquiredAt, &info.HeartbeatAt, &info.Stale)
+	if err == sql.ErrNoRows {
+		return info, false, nil
+	}
+	if err != nil {
+		return info, false, err
+	}
+	return info, true, nil
+}
@@ -0,0 +1,196 @@
+// Package market describes when the exchange is open.
+package market
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// istOffset is used when the tz database is not available (e.g. in a
+// minimal container image). India has no daylight saving time.
+const istOffset = 5*60*60 + 30*60
+
+// Holiday is a weekday on which the exchange is closed.
+type Holiday struct {
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+}
+
+// Calendar knows the exchange's regular trading session and holidays. The
+// session is the same on every trading day; weekends are always closed.
+type Calendar struct {
+	Location *time.Location
+	// Open and Close are offsets from midnight in Location.
+	Open  time.Duration
+	Close time.Duration
+
+	holidays map[string]Holiday
+}
+
+// LoadLocation loads name, falling back to a fixed IST offset for
+// Asia/Kolkata when the tz database is missing.
+func LoadLocation(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err == nil {
+		return loc, nil
+	}
+	if name == "Asia/Kolkata" {
+		return time.FixedZone("IST", istOffset), nil
+	}
+	return nil, err
+}
+
+// ParseClock parses an "HH:MM" time of day into an offset from midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// NewCalendar builds a calendar for the session open–close (HH:MM) in the
+// named time zone.
+func NewCalendar(timezone, open, close string, holidays []Holiday) (*Calendar, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("loading market time zone: %w", err)
+	}
+	openAt, err := ParseClock(open)
+	if err != nil {
+		return nil, err
+	}
+	closeAt, err := ParseClock(close)
+	if err != nil {
+		return nil, err
+	}
+	if closeAt <= openAt {
+		return nil, errors.New("market close must be after market open")
+	}
+
+	c := &Calendar{Location: loc, Open: openAt, Close: closeAt, holidays: make(map[string]Holiday, len(holidays))}
+	for _, h := range holidays {
+		c.holidays[h.Date.Format("2006-01-02")] = h
+	}
+	return c, nil
+}
+
+// LoadHolidays reads a holiday file with one "YYYY-MM-DD,description" line
+// per holiday. Blank lines and lines starting with # are ignored, as is a
+// header row.
+func LoadHolidays(path string) ([]Holiday, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHolidays(f)
+}
+
+func ParseHolidays(r io.Reader) ([]Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var holidays []Holiday
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				continue // header row
+			}
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+		h := Holiday{Date: date}
+		if len(record) > 1 {
+			h.Description = strings.TrimSpace(record[1])
+		}
+		holidays = append(holidays, h)
+	}
+	return holidays, nil
+}
+
+// MustLoad builds the calendar, logging and continuing without holidays if
+// the holiday file cannot be read.
+func MustLoad(timezone, open, close, holidaysFile string) *Calendar {
+	var holidays []Holiday
+	if holidaysFile != "" {
+		var err error
+		holidays, err = LoadHolidays(holidaysFile)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to load market holidays from %s, only weekends will be closed", holidaysFile)
+		}
+	}
+	cal, err := NewCalendar(timezone, open, close, holidays)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid market calendar configuration")
+	}
+	logrus.WithFields(logrus.Fields{
+		"timezone": cal.Location.String(),
+		"holidays": len(holidays),
+	}).Info("Market calendar loaded")
+	return cal
+}
+
+// Holiday returns the holiday on t's date in the market time zone, if any.
+func (c *Calendar) Holiday(t time.Time) (Holiday, bool) {
+	h, ok := c.holidays[t.In(c.Location).Format("2006-01-02")]
+	return h, ok
+}
+
+// IsTradingDay reports whether the exchange trades on t's date.
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	local := t.In(c.Location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(local)
+	return !holiday
+}
+
+// SessionBounds returns the open and close of the session on t's date.
+func (c *Calendar) SessionBounds(t time.Time) (time.Time, time.Time) {
+	local := t.In(c.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.Location)
+	return midnight.Add(c.Open), midnight.Add(c.Close)
+}
+
+// IsOpen reports whether the regular session is in progress at t.
+func (c *Calendar) IsOpen(t time.Time) bool {
+	if !c.IsTradingDay(t) {
+		return false
+	}
+	open, close := c.SessionBounds(t)
+	return !t.Before(open) && t.Before(close)
+}
+
+// NextOpen returns the start of the next session at or after t.
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	day := t.In(c.Location)
+	for i := 0; i < 370; i++ {
+		if c.IsTradingDay(day) {
+			if open, _ := c.SessionBounds(day); !t.After(open) {
+				return open
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
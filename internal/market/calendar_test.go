@@ -0,0 +1,212 @@
+package market
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCalendar(t *testing.T) (*Calendar, *time.Location) {
+	t.Helper()
+	holidays := []Holiday{{Date: time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC), Description: "Republic Day"}}
+	cal, err := NewCalendar("Asia/Kolkata", "09:15", "15:30", holidays)
+	if err != nil {
+		t.Fatalf("NewCalendar: %v", err)
+	}
+	return cal, cal.Location
+}
+
+func TestIsOpen(t *testing.T) {
+	cal, ist := testCalendar(t)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "before the open", at: time.Date(2026, 1, 23, 9, 14, 59, 0, ist)},
+		{name: "at the open", at: time.Date(2026, 1, 23, 9, 15, 0, 0, ist), want: true},
+		{name: "during the session", at: time.Date(2026, 1, 23, 12, 0, 0, 0, ist), want: true},
+		{name: "just before the close", at: time.Date(2026, 1, 23, 15, 29, 59, 0, ist), want: true},
+		{name: "at the close", at: time.Date(2026, 1, 23, 15, 30, 0, 0, ist)},
+		{name: "open given in UTC", at: time.Date(2026, 1, 23, 3, 45, 0, 0, time.UTC), want: true},
+		{name: "before the open given in UTC", at: time.Date(2026, 1, 23, 3, 44, 0, 0, time.UTC)},
+		{name: "saturday", at: time.Date(2026, 1, 24, 12, 0, 0, 0, ist)},
+		{name: "sunday", at: time.Date(2026, 1, 25, 12, 0, 0, 0, ist)},
+		{name: "holiday", at: time.Date(2026, 1, 26, 12, 0, 0, 0, ist)},
+		{name: "day after the holiday", at: time.Date(2026, 1, 27, 12, 0, 0, 0, ist), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.IsOpen(tt.at); got != tt.want {
+				t.Errorf("IsOpen(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTradingDayUsesExchangeDate(t *testing.T) {
+	cal, _ := testCalendar(t)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "thursday evening UTC is friday in IST", at: time.Date(2026, 1, 22, 19, 0, 0, 0, time.UTC), want: true},
+		{name: "friday evening UTC is saturday in IST", at: time.Date(2026, 1, 23, 19, 0, 0, 0, time.UTC)},
+		{name: "sunday evening UTC is the monday holiday in IST", at: time.Date(2026, 1, 25, 19, 0, 0, 0, time.UTC)},
+		{name: "monday evening UTC is tuesday in IST", at: time.Date(2026, 1, 26, 19, 0, 0, 0, time.UTC), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.IsTradingDay(tt.at); got != tt.want {
+				t.Errorf("IsTradingDay(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+
+	h, ok := cal.Holiday(time.Date(2026, 1, 25, 19, 0, 0, 0, time.UTC))
+	if !ok || h.Description != "Republic Day" {
+		t.Errorf("Holiday = %+v, %v, want Republic Day", h, ok)
+	}
+}
+
+func TestSessionBounds(t *testing.T) {
+	cal, ist := testCalendar(t)
+
+	open, close := cal.SessionBounds(time.Date(2026, 1, 22, 20, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 1, 23, 9, 15, 0, 0, ist); !open.Equal(want) {
+		t.Errorf("open = %v, want %v", open, want)
+	}
+	if want := time.Date(2026, 1, 23, 15, 30, 0, 0, ist); !close.Equal(want) {
+		t.Errorf("close = %v, want %v", close, want)
+	}
+}
+
+func TestNextOpen(t *testing.T) {
+	cal, ist := testCalendar(t)
+	friday := time.Date(2026, 1, 23, 9, 15, 0, 0, ist)
+	tuesday := time.Date(2026, 1, 27, 9, 15, 0, 0, ist)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{name: "before the open", at: time.Date(2026, 1, 23, 7, 0, 0, 0, ist), want: friday},
+		{name: "at the open", at: friday, want: friday},
+		{name: "during the session skips the weekend and holiday", at: time.Date(2026, 1, 23, 10, 0, 0, 0, ist), want: tuesday},
+		{name: "weekend", at: time.Date(2026, 1, 24, 8, 0, 0, 0, ist), want: tuesday},
+		{name: "holiday", at: time.Date(2026, 1, 26, 8, 0, 0, 0, ist), want: tuesday},
+		{name: "UTC time on the previous UTC day", at: time.Date(2026, 1, 22, 20, 0, 0, 0, time.UTC), want: friday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.NextOpen(tt.at); !got.Equal(tt.want) {
+				t.Errorf("NextOpen(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "09:15", want: 9*time.Hour + 15*time.Minute},
+		{in: " 15:30 ", want: 15*time.Hour + 30*time.Minute},
+		{in: "00:00"},
+		{in: "24:00", wantErr: true},
+		{in: "0915", wantErr: true},
+		{in: "9am", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseClock(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseClock(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCalendarRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name                  string
+		timezone, open, close string
+	}{
+		{name: "unknown time zone", timezone: "Mars/Olympus", open: "09:15", close: "15:30"},
+		{name: "bad open", timezone: "Asia/Kolkata", open: "9.15", close: "15:30"},
+		{name: "bad close", timezone: "Asia/Kolkata", open: "09:15", close: "late"},
+		{name: "close before open", timezone: "Asia/Kolkata", open: "15:30", close: "09:15"},
+		{name: "close equals open", timezone: "Asia/Kolkata", open: "09:15", close: "09:15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCalendar(tt.timezone, tt.open, tt.close, nil); err == nil {
+				t.Error("NewCalendar accepted an invalid config")
+			}
+		})
+	}
+}
+
+func TestParseHolidays(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		wantDates []string
+		wantDesc  []string
+		wantErr   bool
+	}{
+		{
+			name:      "header, comments and blank lines",
+			in:        "# holidays\ndate,description\n\n2026-01-26,Republic Day\n2026-03-03, Holi \n",
+			wantDates: []string{"2026-01-26", "2026-03-03"},
+			wantDesc:  []string{"Republic Day", "Holi"},
+		},
+		{
+			name:      "no header and no description",
+			in:        "2026-01-26\n 2026-03-03,Holi\n",
+			wantDates: []string{"2026-01-26", "2026-03-03"},
+			wantDesc:  []string{"", "Holi"},
+		},
+		{name: "empty file"},
+		{name: "bad date after the first row", in: "2026-01-26,Republic Day\n26/01/2026,Republic Day\n", wantErr: true},
+		{name: "bad date after the header", in: "date,description\nsoon,Holi\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHolidays(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantDates) {
+				t.Fatalf("got %d holidays, want %d", len(got), len(tt.wantDates))
+			}
+			for i, h := range got {
+				if d := h.Date.Format("2006-01-02"); d != tt.wantDates[i] || h.Description != tt.wantDesc[i] {
+					t.Errorf("holiday %d = %s %q, want %s %q", i, d, h.Description, tt.wantDates[i], tt.wantDesc[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBundledHolidaysParse(t *testing.T) {
+	holidays, err := LoadHolidays("nse_holidays.csv")
+	if err != nil {
+		t.Fatalf("LoadHolidays: %v", err)
+	}
+	for _, h := range holidays {
+		if wd := h.Date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			t.Errorf("%s (%s) falls on a weekend", h.Date.Format("2006-01-02"), h.Description)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package marketsim generates reproducible stock price paths for local and
+// test environments.
+//
+// Each symbol follows a geometric Brownian motion stepped at a fixed
+// interval from an epoch. The random draws of a symbol come from its own
+// generator, seeded from the simulator seed and the symbol, so a symbol's
+// price at a given time depends only on the seed and its own parameters, not
+// on which other symbols are simulated or in what order they are asked for.
+package marketsim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoganX64/stocky-api/internal/utils"
+)
+
+// year is the time unit of Volatility and Drift.
+const year = 365 * 24 * time.Hour
+
+// ErrOutage is returned for prices asked for during an outage window.
+var ErrOutage = errors.New("simulated feed outage")
+
+// ErrNoStart is returned by PriceAt for a symbol without a starting price.
+var ErrNoStart = errors.New("no starting price")
+
+// Params are one symbol's path parameters. Volatility and Drift are
+// annualised, e.g. 0.25 and 0.08 for 25% and 8% a year; zero values take the
+// simulator defaults.
+type Params struct {
+	Start      float64 `json:"start"`
+	Volatility float64 `json:"volatility"`
+	Drift      float64 `json:"drift"`
+}
+
+// Outage is a window during which quotes fail, for every symbol or only the
+// listed ones. End is exclusive.
+type Outage struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Symbols []string  `json:"symbols,omitempty"`
+}
+
+func (o Outage) covers(symbol string, t time.Time) bool {
+	if t.Before(o.Start) || !t.Before(o.End) {
+		return false
+	}
+	if len(o.Symbols) == 0 {
+		return true
+	}
+	for _, s := range o.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config describes a simulation.
+type Config struct {
+	Seed  int64
+	Epoch time.Time
+	Step  time.Duration
+	// Start, Volatility and Drift apply to symbols without their own.
+	Start      float64
+	Volatility float64
+	Drift      float64
+	Symbols    map[string]Params
+	Outages    []Outage
+}
+
+// DefaultConfig steps every minute from 2026-01-01 UTC with 25% annual
+// volatility and no drift.
+func DefaultConfig() Config {
+	return Config{
+		Seed:       1,
+		Epoch:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Step:       time.Minute,
+		Volatility: 0.25,
+		Symbols:    map[string]Params{},
+	}
+}
+
+// fileConfig is the JSON form of Config.
+type fileConfig struct {
+	Seed       *int64            `json:"seed"`
+	Epoch      *time.Time        `json:"epoch"`
+	Step       string            `json:"step"`
+	Start      *float64          `json:"start"`
+	Volatility *float64          `json:"volatility"`
+	Drift      *float64          `json:"drift"`
+	Symbols    map[string]Params `json:"symbols"`
+	Outages    []Outage          `json:"outages"`
+}
+
+// LoadConfig reads a JSON config file over base. Fields missing from the file
+// keep base's values; symbols and outages are added to base's.
+//
+//	{
+//	  "seed": 42, "epoch": "2026-01-01T00:00:00Z", "step": "1m",
+//	  "volatility": 0.25, "drift": 0.05,
+//	  "symbols": {"TCS": {"start": 3450, "volatility": 0.2}},
+//	  "outages": [{"start": "2026-10-19T10:00:00+05:30", "end": "2026-10-19T10:30:00+05:30", "symbols": ["TCS"]}]
+//	}
+func LoadConfig(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return base, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	cfg := base
+	if fc.Seed != nil {
+		cfg.Seed = *fc.Seed
+	}
+	if fc.Epoch != nil {
+		cfg.Epoch = *fc.Epoch
+	}
+	if fc.Step != "" {
+		if cfg.Step, err = time.ParseDuration(fc.Step); err != nil {
+			return base, fmt.Errorf("reading %s: invalid step: %w", path, err)
+		}
+	}
+	if fc.Start != nil {
+		cfg.Start = *fc.Start
+	}
+	if fc.Volatility != nil {
+		cfg.Volatility = *fc.Volatility
+	}
+	if fc.Drift != nil {
+		cfg.Drift = *fc.Drift
+	}
+	cfg.Symbols = make(map[string]Params, len(base.Symbols)+len(fc.Symbols))
+	for s, p := range base.Symbols {
+		cfg.Symbols[s] = p
+	}
+	for s, p := range fc.Symbols {
+		cfg.Symbols[s] = p
+	}
+	cfg.Outages = append(append([]Outage{}, base.Outages...), fc.Outages...)
+	return cfg, nil
+}
+
+// Simulator computes price paths for a Config. It is safe for concurrent
+// use.
+type Simulator struct {
+	cfg     Config
+	symbols map[string]Params
+
+	mu    sync.Mutex
+	paths map[string]*path
+}
+
+// path is a symbol's generator advanced to step, with the cumulative factor
+// reached there.
+type path struct {
+	rng    *rand.Rand
+	step   int64
+	factor float64
+}
+
+// New validates cfg and returns a simulator for it.
+func New(cfg Config) (*Simulator, error) {
+	if cfg.Step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if cfg.Volatility < 0 {
+		return nil, errors.New("volatility must not be negative")
+	}
+	symbols := make(map[string]Params, len(cfg.Symbols))
+	for s, p := range cfg.Symbols {
+		if p.Start < 0 || p.Volatility < 0 {
+			return nil, fmt.Errorf("%s: start and volatility must not be negative", s)
+		}
+		symbols[strings.ToUpper(s)] = p
+	}
+	for i, o := range cfg.Outages {
+		if !o.End.After(o.Start) {
+			return nil, fmt.Errorf("outage %d ends before it starts", i+1)
+		}
+	}
+	return &Simulator{cfg: cfg, symbols: symbols, paths: make(map[string]*path)}, nil
+}
+
+// Config returns the simulation's configuration.
+func (s *Simulator) Config() Config {
+	return s.cfg
+}
+
+// Symbols lists the configured symbols in order.
+func (s *Simulator) Symbols() []string {
+	out := make([]string, 0, len(s.symbols))
+	for sym := range s.symbols {
+		out = append(out, sym)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Simulator) params(symbol string) Params {
+	p := s.symbols[strings.ToUpper(symbol)]
+	if p.Start == 0 {
+		p.Start = s.cfg.Start
+	}
+	if p.Volatility == 0 {
+		p.Volatility = s.cfg.Volatility
+	}
+	if p.Drift == 0 {
+		p.Drift = s.cfg.Drift
+	}
+	return p
+}
+
+// Start returns the symbol's price at the epoch, if one is configured.
+func (s *Simulator) Start(symbol string) (float64, bool) {
+	start := s.params(symbol).Start
+	return start, start > 0
+}
+
+// StepAt returns the index of the step in effect at t; times before the
+// epoch are step 0.
+func (s *Simulator) StepAt(t time.Time) int64 {
+	if !t.After(s.cfg.Epoch) {
+		return 0
+	}
+	return int64(t.Sub(s.cfg.Epoch) / s.cfg.Step)
+}
+
+// StepTime returns the start of step n.
+func (s *Simulator) StepTime(n int64) time.Time {
+	return s.cfg.Epoch.Add(time.Duration(n) * s.cfg.Step)
+}
+
+// InOutage reports whether quotes for symbol fail at t.
+func (s *Simulator) InOutage(symbol string, t time.Time) bool {
+	for _, o := range s.cfg.Outages {
+		if o.covers(symbol, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Factor returns how far the symbol's path has moved from the epoch to t, as
+// a multiple of its starting price.
+func (s *Simulator) Factor(symbol string, t time.Time) float64 {
+	symbol = strings.ToUpper(symbol)
+	step := s.StepAt(t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.paths[symbol]
+	if !ok || p.step > step {
+		// Paths only run forwards; going back restarts from the epoch.
+		p = &path{rng: SymbolRand(s.cfg.Seed, symbol), factor: 1}
+		s.paths[symbol] = p
+	}
+
+	params := s.params(symbol)
+	dt := s.cfg.Step.Seconds() / year.Seconds()
+	mu := (params.Drift - params.Volatility*params.Volatility/2) * dt
+	sigma := params.Volatility * math.Sqrt(dt)
+	for ; p.step < step; p.step++ {
+		p.factor *= math.Exp(mu + sigma*p.rng.NormFloat64())
+	}
+	return p.factor
+}
+
+// SymbolRand returns a generator for symbol seeded from seed and the
+// symbol, so its draws do not depend on what other symbols draw. Symbols are
+// upper-cased first.
+func SymbolRand(seed int64, symbol string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToUpper(symbol)))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+// PriceAt returns the symbol's price at t from its configured start.
+func (s *Simulator) PriceAt(symbol string, t time.Time) (float64, error) {
+	if s.InOutage(symbol, t) {
+		return 0, fmt.Errorf("%s: %w", symbol, ErrOutage)
+	}
+	start, ok := s.Start(symbol)
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", symbol, ErrNoStart)
+	}
+	return utils.RoundAmount(start * s.Factor(symbol, t)), nil
+}
+
+// Point is one step of a generated path. Outage points have no price.
+type Point struct {
+	Time   time.Time
+	Symbol string
+	Price  float64
+	Outage bool
+}
+
+// Path returns every step from from to to, inclusive, for each symbol in
+// time order. Every symbol needs a starting price.
+func (s *Simulator) Path(symbols []string, from, to time.Time) ([]Point, error) {
+	for _, sym := range symbols {
+		if _, ok := s.Start(sym); !ok {
+			return nil, fmt.Errorf("%s: %w", sym, ErrNoStart)
+		}
+	}
+	var points []Point
+	for n := s.StepAt(from); ; n++ {
+		t := s.StepTime(n)
+		if t.After(to) {
+			break
+		}
+		if t.Before(from) {
+			continue
+		}
+		for _, sym := range symbols {
+			price, err := s.PriceAt(sym, t)
+			if errors.Is(err, ErrOutage) {
+				points = append(points, Point{Time: t, Symbol: sym, Outage: true})
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, Point{Time: t, Symbol: sym, Price: price})
+		}
+	}
+	return points, nil
+}
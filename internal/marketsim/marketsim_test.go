@@ -0,0 +1,188 @@
+package marketsim
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testConfig(seed int64) Config {
+	cfg := DefaultConfig()
+	cfg.Seed = seed
+	cfg.Symbols = map[string]Params{
+		"TCS":  {Start: 3450},
+		"INFY": {Start: 1500, Volatility: 0.4},
+	}
+	return cfg
+}
+
+func mustNew(t *testing.T, cfg Config) *Simulator {
+	t.Helper()
+	sim, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return sim
+}
+
+func pathPrices(t *testing.T, sim *Simulator, symbols []string, from, to time.Time) []float64 {
+	t.Helper()
+	points, err := sim.Path(symbols, from, to)
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	prices := make([]float64, len(points))
+	for i, p := range points {
+		prices[i] = p.Price
+	}
+	return prices
+}
+
+func equalPrices(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSameSeedAndSymbolGiveSamePath(t *testing.T) {
+	from := time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		seedA     int64
+		seedB     int64
+		symbolsA  []string
+		symbolsB  []string
+		wantEqual bool
+	}{
+		{name: "same seed", seedA: 42, seedB: 42, symbolsA: []string{"TCS"}, symbolsB: []string{"TCS"}, wantEqual: true},
+		{name: "symbol case does not matter", seedA: 42, seedB: 42, symbolsA: []string{"TCS"}, symbolsB: []string{"tcs"}, wantEqual: true},
+		{name: "different seed", seedA: 42, seedB: 43, symbolsA: []string{"TCS"}, symbolsB: []string{"TCS"}, wantEqual: false},
+		{name: "different symbol", seedA: 42, seedB: 42, symbolsA: []string{"TCS"}, symbolsB: []string{"INFY"}, wantEqual: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := pathPrices(t, mustNew(t, testConfig(tt.seedA)), tt.symbolsA, from, to)
+			b := pathPrices(t, mustNew(t, testConfig(tt.seedB)), tt.symbolsB, from, to)
+			if len(a) != 121 {
+				t.Fatalf("got %d points, want 121", len(a))
+			}
+			if got := equalPrices(a, b); got != tt.wantEqual {
+				t.Errorf("paths equal = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestPathDoesNotDependOnOtherSymbolsOrOrder(t *testing.T) {
+	at := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	alone := mustNew(t, testConfig(7))
+	want, err := alone.PriceAt("TCS", at)
+	if err != nil {
+		t.Fatalf("PriceAt: %v", err)
+	}
+
+	busy := mustNew(t, testConfig(7))
+	if _, err := busy.PriceAt("INFY", at.Add(time.Hour)); err != nil {
+		t.Fatalf("PriceAt INFY: %v", err)
+	}
+	// Asking for a later time first makes the path restart from the epoch.
+	if _, err := busy.PriceAt("TCS", at.Add(24*time.Hour)); err != nil {
+		t.Fatalf("PriceAt later: %v", err)
+	}
+	got, err := busy.PriceAt("TCS", at)
+	if err != nil {
+		t.Fatalf("PriceAt: %v", err)
+	}
+	if got != want {
+		t.Errorf("price = %v, want %v", got, want)
+	}
+}
+
+func TestPriceAtStepsAndErrors(t *testing.T) {
+	cfg := testConfig(1)
+	cfg.Outages = []Outage{{
+		Start:   time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC),
+		End:     time.Date(2026, 2, 1, 10, 30, 0, 0, time.UTC),
+		Symbols: []string{"tcs"},
+	}}
+	sim := mustNew(t, cfg)
+
+	tests := []struct {
+		name    string
+		symbol  string
+		at      time.Time
+		want    float64
+		wantErr error
+	}{
+		{name: "epoch is the start price", symbol: "TCS", at: cfg.Epoch, want: 3450},
+		{name: "before the epoch is the start price", symbol: "TCS", at: cfg.Epoch.Add(-time.Hour), want: 3450},
+		{name: "no start", symbol: "WIPRO", at: cfg.Epoch, wantErr: ErrNoStart},
+		{name: "outage start is inclusive", symbol: "TCS", at: cfg.Outages[0].Start, wantErr: ErrOutage},
+		{name: "outage end is exclusive", symbol: "TCS", at: cfg.Outages[0].End},
+		{name: "outage only for listed symbols", symbol: "INFY", at: cfg.Outages[0].Start},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sim.PriceAt(tt.symbol, tt.at)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want != 0 && got != tt.want {
+				t.Errorf("price = %v, want %v", got, tt.want)
+			}
+			if got <= 0 {
+				t.Errorf("price = %v, want a positive price", got)
+			}
+		})
+	}
+}
+
+func TestSymbolRand(t *testing.T) {
+	a, b := SymbolRand(9, "TCS"), SymbolRand(9, "tcs")
+	for i := 0; i < 5; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("draw %d: %v != %v", i, x, y)
+		}
+	}
+	if SymbolRand(9, "TCS").Float64() == SymbolRand(9, "INFY").Float64() {
+		t.Error("different symbols gave the same first draw")
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+	}{
+		{name: "zero step", mutate: func(c *Config) { c.Step = 0 }},
+		{name: "negative volatility", mutate: func(c *Config) { c.Volatility = -0.1 }},
+		{name: "negative symbol start", mutate: func(c *Config) { c.Symbols["X"] = Params{Start: -1} }},
+		{name: "outage ends before it starts", mutate: func(c *Config) {
+			c.Outages = []Outage{{Start: c.Epoch.Add(time.Hour), End: c.Epoch}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(1)
+			tt.mutate(&cfg)
+			if _, err := New(cfg); err == nil {
+				t.Error("New accepted an invalid config")
+			}
+		})
+	}
+}
@@ -19,19 +19,104 @@ type Reward struct {
 	ID             int     `json:"id"`
 	User_ID        int     `json:"user_id"`
 	Stock_Symbol   string  `json:"stock_symbol"`
+	InstrumentID   int     `json:"instrument_id"`
 	Quantity       float64 `json:"quantity"`
 	IdempotencyKey string  `json:"idempotency_key"`
 	CreatedAt      string  `json:"created_at"`
 }
 
+// Stock_Events is a corporate action. For splits, bonuses and mergers
+// RatioNum/RatioDen is the number of units held after the event per unit held
+// before it, e.g. 2/1 for a 1:1 bonus; a merger also moves the units to
+// TargetSymbol. A delisting settles the holdings by its DelistOutcome. A dividend
+// pays AmountPerShare to the holders on RecordDate; its EffectiveDate is the
+// payment date.
 type Stock_Events struct {
-	ID            int    `json:"id"`
-	Stock_Symbol  string `json:"stock_symbol"`
-	EventType     string `json:"event_type"`
-	RatioNum      int    `json:"ratio_num"`
-	RatioDen      int    `json:"ratio_den"`
-	EffectiveDate string `json:"effective_date"`
-	CreatedAt     string `json:"created_at"`
+	ID             int      `json:"id"`
+	Stock_Symbol   string   `json:"stock_symbol"`
+	EventType      string   `json:"event_type"`
+	RatioNum       int      `json:"ratio_num"`
+	RatioDen       int      `json:"ratio_den"`
+	EffectiveDate  string   `json:"effective_date"`
+	TargetSymbol   *string  `json:"target_symbol"`
+	RecordDate     *string  `json:"record_date"`
+	AmountPerShare *float64 `json:"amount_per_share"`
+	// A merger may pay CashPerShare per share of the merging stock, and with
+	// FractionHandling "cash" pays for fractional target shares at
+	// FractionPrice, or the target's price when it is applied.
+	CashPerShare     *float64 `json:"cash_per_share"`
+	FractionHandling string   `json:"fraction_handling"`
+	FractionPrice    *float64 `json:"fraction_price"`
+	DelistOutcome    *string  `json:"delist_outcome"`
+	ExitPrice        *float64 `json:"exit_price"`
+	Note             *string  `json:"note"`
+	CreatedBy        *string  `json:"created_by"`
+	CreatedAt        string   `json:"created_at"`
+	UpdatedBy        *string  `json:"updated_by"`
+	UpdatedAt        *string  `json:"updated_at"`
+	// AppliedAt is set once the event has been posted to the ledger, and
+	// AppliedLots is the number of reward lots it changed; for a dividend,
+	// the number of holders paid.
+	AppliedAt   *string `json:"applied_at"`
+	AppliedLots *int    `json:"applied_lots"`
+}
+
+const (
+	EventSplit    = "split"
+	EventBonus    = "bonus"
+	EventMerger   = "merger"
+	EventDelist   = "delist"
+	EventDividend = "dividend"
+)
+
+// How a merger settles fractional target shares.
+const (
+	FractionKeep = "fractional"
+	FractionCash = "cash"
+)
+
+// What a delisting does with the holdings: buy them back at the exit price,
+// write them off, or move them to the unlisted holdings.
+const (
+	DelistExitOffer = "exit_offer"
+	DelistWriteOff  = "write_off"
+	DelistUnlisted  = "unlisted"
+)
+
+// StockEventRequest creates, updates or previews a stock event. Force allows
+// an effective date in the past, and changing an event already in effect.
+type StockEventRequest struct {
+	StockSymbol    string  `json:"stock_symbol"`
+	EventType      string  `json:"event_type"`
+	RatioNum       int     `json:"ratio_num"`
+	RatioDen       int     `json:"ratio_den"`
+	EffectiveDate  string  `json:"effective_date"`
+	TargetSymbol   string  `json:"target_symbol"`
+	RecordDate     string  `json:"record_date"`
+	AmountPerShare float64 `json:"amount_per_share"`
+	// Merger only; FractionHandling defaults to FractionKeep.
+	CashPerShare     float64 `json:"cash_per_share"`
+	FractionHandling string  `json:"fraction_handling"`
+	FractionPrice    float64 `json:"fraction_price"`
+	// Delisting only; DelistOutcome defaults to DelistUnlisted.
+	DelistOutcome string  `json:"delist_outcome"`
+	ExitPrice     float64 `json:"exit_price"`
+	Note          string  `json:"note"`
+	Force         bool    `json:"force"`
+}
+
+// StockEventImpact is one user's holding touched by a stock event, in units
+// just before and just after it takes effect. CashINR is the gross cash the
+// user would receive, e.g. a dividend, or a merger's cash part and the cash
+// for the FractionUnits it does not deliver.
+type StockEventImpact struct {
+	UserID        int     `json:"userId"`
+	Lots          int     `json:"lots"`
+	UnitsBefore   float64 `json:"unitsBefore"`
+	UnitsAfter    float64 `json:"unitsAfter"`
+	SymbolAfter   string  `json:"symbolAfter,omitempty"`
+	FractionUnits float64 `json:"fractionUnits,omitempty"`
+	CashINR       float64 `json:"cashInr,omitempty"`
 }
 
 const (
@@ -40,16 +125,26 @@ const (
 	BrokerageFee = "brokerage_fee"
 	STTFee       = "stt_fee"
 	GSTFee       = "gst_fee"
+	MergerCash   = "merger_cash"
+	CashInLieu   = "cash_in_lieu"
+	// A delisting's outcome rows; quantity is the units settled.
+	ExitOffer     = "exit_offer"
+	WriteOff      = "write_off"
+	UnlistedUnits = "unlisted_units"
 )
 
 type Ledger struct {
-	ID           int     `json:"id"`
-	Reward_ID    int     `json:"reward_id"`
-	Entry_Type   string  `json:"entry_type"`
-	Stock_Symbol string  `json:"stock_symbol"`
-	Quantity     float64 `json:"quantity"`
-	Amount       float64 `json:"amount"`
-	CreatedAt    string  `json:"created_at"`
+	ID            int     `json:"id"`
+	Reward_ID     int     `json:"reward_id"`
+	Adjustment_ID *int    `json:"adjustment_id,omitempty"`
+	StockEventID  *int    `json:"stock_event_id,omitempty"`
+	Entry_Type    string  `json:"entry_type"`
+	Stock_Symbol  string  `json:"stock_symbol"`
+	InstrumentID  *int    `json:"instrument_id,omitempty"`
+	Quantity      float64 `json:"quantity"`
+	Amount        float64 `json:"amount"`
+	BusinessDate  string  `json:"business_date"`
+	CreatedAt     string  `json:"created_at"`
 }
 
 const (
@@ -58,14 +153,153 @@ const (
 	Manual_Correction = "manual_correction"
 )
 
+// Unit bases for adjustment quantities. Pre-event units are the reward's own
+// units as originally granted; current units have every split, bonus and merger
+// effective since the reward date applied, as in user_portfolio.
+const (
+	UnitsPreEvent = "pre_event"
+	UnitsCurrent  = "current"
+)
+
+// Adjustment.DeltaQuantity is always stored in pre-event units.
+// InputDeltaQuantity is the quantity as entered, in UnitBasis units, and
+// UnitMultiplier the corporate-action multiplier used to convert it.
 type Adjustment struct {
-	ID             int     `json:"id"`
-	RewardID       int     `json:"reward_id"`
-	AdjustmentType string  `json:"adjustment_type"`
-	DeltaQuantity  float64 `json:"delta_quantity"`
-	DeltaAmount    float64 `json:"delta_amount"`
-	Reason         string  `json:"reason"`
-	CreatedAt      string  `json:"created_at"`
+	ID                  int     `json:"id"`
+	RewardID            int     `json:"reward_id"`
+	AdjustmentType      string  `json:"adjustment_type"`
+	UnitBasis           string  `json:"unit_basis"`
+	InputDeltaQuantity  float64 `json:"input_delta_quantity"`
+	UnitMultiplier      float64 `json:"unit_multiplier"`
+	DeltaQuantity       float64 `json:"delta_quantity"`
+	DeltaAmount         float64 `json:"delta_amount"`
+	Reason              string  `json:"reason"`
+	ReasonCode          string  `json:"reason_code"`
+	TicketRef           string  `json:"ticket_ref"`
+	InitiatedBy         string  `json:"initiated_by"`
+	RevertsAdjustmentID *int    `json:"reverts_adjustment_id,omitempty"`
+	CreatedAt           string  `json:"created_at"`
+}
+
+const (
+	SignAny      = "any"
+	SignPositive = "positive"
+	SignNegative = "negative"
+	SignZero     = "zero"
+)
+
+// AdjustmentReasonCode is an entry of the managed reason code taxonomy.
+// AllowedTypes lists the adjustment types that may use the code; QuantitySign
+// and AmountSign constrain delta_quantity and delta_amount.
+type AdjustmentReasonCode struct {
+	Code         string   `json:"code"`
+	Description  string   `json:"description"`
+	AllowedTypes []string `json:"allowed_types"`
+	QuantitySign string   `json:"quantity_sign"`
+	AmountSign   string   `json:"amount_sign"`
+	Active       bool     `json:"active"`
+	CreatedBy    string   `json:"created_by"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedBy    *string  `json:"updated_by"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+type AdjustmentReasonSummary struct {
+	ReasonCode         string  `json:"reasonCode"`
+	Description        string  `json:"description"`
+	AdjustmentCount    int     `json:"adjustmentCount"`
+	TotalDeltaQuantity float64 `json:"totalDeltaQuantity"`
+	TotalDeltaAmount   float64 `json:"totalDeltaAmount"`
+	FirstAt            string  `json:"firstAt"`
+	LastAt             string  `json:"lastAt"`
+}
+
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentRejected = "rejected"
+)
+
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+type AdjustmentRequest struct {
+	ID                  int                  `json:"id"`
+	RewardID            int                  `json:"reward_id"`
+	AdjustmentType      string               `json:"adjustment_type"`
+	UnitBasis           string               `json:"unit_basis"`
+	InputDeltaQuantity  float64              `json:"input_delta_quantity"`
+	UnitMultiplier      float64              `json:"unit_multiplier"`
+	DeltaQuantity       float64              `json:"delta_quantity"`
+	DeltaAmount         float64              `json:"delta_amount"`
+	Reason              string               `json:"reason"`
+	ReasonCode          string               `json:"reason_code"`
+	TicketRef           string               `json:"ticket_ref"`
+	INRValue            float64              `json:"inr_value"`
+	RequiredApprovals   int                  `json:"required_approvals"`
+	Status              string               `json:"status"`
+	InitiatedBy         string               `json:"initiated_by"`
+	RevertsAdjustmentID *int                 `json:"reverts_adjustment_id,omitempty"`
+	BatchID             *int                 `json:"batch_id,omitempty"`
+	AdjustmentID        *int                 `json:"adjustment_id"`
+	CreatedAt           string               `json:"created_at"`
+	DecidedAt           *string              `json:"decided_at"`
+	Approvals           []AdjustmentApproval `json:"approvals"`
+}
+
+type AdjustmentApproval struct {
+	ID        int    `json:"id"`
+	RequestID int    `json:"request_id"`
+	Approver  string `json:"approver"`
+	Decision  string `json:"decision"`
+	Comment   string `json:"comment"`
+	CreatedAt string `json:"created_at"`
+}
+
+// AdjustmentHistoryItem is an applied adjustment together with the reward it
+// belongs to, who requested and approved it, and the ledger rows it produced.
+type AdjustmentHistoryItem struct {
+	Adjustment
+	UserID              int      `json:"user_id"`
+	StockSymbol         string   `json:"stock_symbol"`
+	AdjustmentRequestID *int     `json:"adjustment_request_id"`
+	ApprovedBy          []string `json:"approved_by"`
+	RevertedBy          *int     `json:"reverted_by_adjustment_id"`
+	Ledger              []Ledger `json:"ledger"`
+}
+
+type AdjustmentBatch struct {
+	ID                int                 `json:"id"`
+	FileName          string              `json:"file_name"`
+	RowCount          int                 `json:"row_count"`
+	TotalINRValue     float64             `json:"total_inr_value"`
+	RequiredApprovals int                 `json:"required_approvals"`
+	Status            string              `json:"status"`
+	InitiatedBy       string              `json:"initiated_by"`
+	CreatedAt         string              `json:"created_at"`
+	DecidedAt         *string             `json:"decided_at"`
+	Approvers         []string            `json:"approvers"`
+	Requests          []AdjustmentRequest `json:"requests"`
+}
+
+// BulkAdjustmentRowError reports a problem with one CSV row. Row is the line
+// number in the uploaded file, counting the header as line 1.
+type BulkAdjustmentRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+type RevertAdjustmentRequest struct {
+	Reason     string `json:"reason"`
+	ReasonCode string `json:"reason_code"`
+	TicketRef  string `json:"ticket_ref"`
+}
+
+type AdjustmentDecisionRequest struct {
+	Comment string `json:"comment"`
 }
 
 type HistoricalINR struct {
@@ -85,9 +319,54 @@ type TodayReward struct {
 
 type PortfolioItem struct {
 	StockSymbol  string  `json:"stockSymbol"`
+	ISIN         *string `json:"isin"`
+	Name         string  `json:"name"`
 	Quantity     float64 `json:"quantity"`
 	CurrentPrice float64 `json:"currentPrice"`
 	INRValue     float64 `json:"inrValue"`
+	PriceSource  string  `json:"priceSource"`
+}
+
+// PriceOverrideRequest pins a price. Exactly one of ExpiresAt (RFC 3339) and
+// TTLMinutes sets how long the override lasts.
+type PriceOverrideRequest struct {
+	Price      float64 `json:"price"`
+	Reason     string  `json:"reason"`
+	ExpiresAt  string  `json:"expires_at"`
+	TTLMinutes int     `json:"ttl_minutes"`
+}
+
+type ReleasePriceOverrideRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PriceQuarantineReviewRequest accepts or rejects a quarantined price.
+type PriceQuarantineReviewRequest struct {
+	Decision string `json:"decision"` // accept | reject
+	Comment  string `json:"comment"`
+}
+
+// PriceRefreshRequest starts a manual price refresh, optionally limited to
+// some symbols.
+type PriceRefreshRequest struct {
+	Symbols []string `json:"symbols"`
+}
+
+// PriceBandRequest sets a symbol's sanity limits in percent; 0 disables a
+// check.
+type PriceBandRequest struct {
+	BandPct    *float64 `json:"band_pct"`
+	MaxMovePct *float64 `json:"max_move_pct"`
+}
+
+// PriceBar is one open/high/low/close bar of a stock's price history.
+type PriceBar struct {
+	Time      string  `json:"time"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	TickCount int     `json:"tickCount"`
 }
 
 type TodayStock struct {
@@ -97,6 +376,7 @@ type TodayStock struct {
 	CurrentPrice          float64 `json:"currentPrice"`
 	TotalAdjustmentAmount float64 `json:"totalAdjustmentAmount"`
 	INRValue              float64 `json:"inrValue"`
+	PriceSource           string  `json:"priceSource"`
 }
 
 type CreateRewardRequest struct {
@@ -104,3 +384,144 @@ type CreateRewardRequest struct {
 	StockSymbol string  `json:"stock_symbol"`
 	Quantity    float64 `json:"quantity"`
 }
+
+// Wallet is a user's INR balance from cash corporate actions.
+type Wallet struct {
+	UserID    int     `json:"userId"`
+	Balance   float64 `json:"balance"`
+	UpdatedAt *string `json:"updatedAt"`
+}
+
+// Wallet transaction types.
+const (
+	WalletDividend   = "dividend"
+	WalletTDS        = "tds"
+	WalletMergerCash = "merger_cash"
+	WalletCashInLieu = "cash_in_lieu"
+	WalletExitOffer  = "exit_offer"
+)
+
+// WalletTransaction is one change to a wallet balance. Amount is signed.
+type WalletTransaction struct {
+	ID               int     `json:"id"`
+	TxnType          string  `json:"txnType"`
+	Amount           float64 `json:"amount"`
+	BalanceAfter     float64 `json:"balanceAfter"`
+	StockEventID     *int    `json:"stockEventId,omitempty"`
+	DividendPayoutID *int    `json:"dividendPayoutId,omitempty"`
+	Note             *string `json:"note,omitempty"`
+	CreatedAt        string  `json:"createdAt"`
+}
+
+// DividendPayout is what one holder received from a dividend.
+type DividendPayout struct {
+	ID             int     `json:"id"`
+	EventID        int     `json:"eventId"`
+	UserID         int     `json:"userId"`
+	StockSymbol    string  `json:"stockSymbol"`
+	RecordDate     string  `json:"recordDate"`
+	PaymentDate    string  `json:"paymentDate"`
+	Units          float64 `json:"units"`
+	AmountPerShare float64 `json:"amountPerShare"`
+	GrossAmount    float64 `json:"grossAmount"`
+	TDSPct         float64 `json:"tdsPct"`
+	TDSAmount      float64 `json:"tdsAmount"`
+	NetAmount      float64 `json:"netAmount"`
+	PaidAt         string  `json:"paidAt"`
+}
+
+// UnlistedHolding is a user's units of a stock that was delisted with the
+// unlisted outcome. They have no market price.
+type UnlistedHolding struct {
+	StockSymbol string  `json:"stockSymbol"`
+	ISIN        *string `json:"isin"`
+	Name        string  `json:"name"`
+	Quantity    float64 `json:"quantity"`
+}
+
+// DelistingSettlement is what a delisting did with one user's holding:
+// Outcome is a Delist* value, and Payout the INR an exit offer credited.
+type DelistingSettlement struct {
+	EventID       int      `json:"eventId"`
+	StockSymbol   string   `json:"stockSymbol"`
+	ISIN          *string  `json:"isin"`
+	Name          string   `json:"name"`
+	EffectiveDate string   `json:"effectiveDate"`
+	Outcome       string   `json:"outcome"`
+	Lots          int      `json:"lots"`
+	Units         float64  `json:"units"`
+	ExitPrice     *float64 `json:"exitPrice"`
+	Payout        float64  `json:"payout"`
+	SettledAt     string   `json:"settledAt"`
+}
+
+// Instrument is a stock in the instrument master. Symbol is the canonical
+// symbol used across the API; the stock_symbol columns elsewhere record the
+// symbol in use when each row was written. Instruments created from price
+// data before the master existed have no ISIN until one is set.
+type Instrument struct {
+	ID            int                `json:"id"`
+	ISIN          *string            `json:"isin"`
+	Symbol        string             `json:"symbol"`
+	NSESymbol     *string            `json:"nse_symbol"`
+	BSESymbol     *string            `json:"bse_symbol"`
+	Name          string             `json:"name"`
+	Sector        *string            `json:"sector"`
+	FaceValue     *float64           `json:"face_value"`
+	ListingStatus string             `json:"listing_status"`
+	CreatedAt     string             `json:"created_at"`
+	UpdatedBy     *string            `json:"updated_by"`
+	UpdatedAt     *string            `json:"updated_at"`
+	Aliases       []InstrumentAlias  `json:"aliases,omitempty"`
+	SymbolChanges []InstrumentRename `json:"symbol_changes,omitempty"`
+}
+
+const (
+	ListingListed    = "listed"
+	ListingSuspended = "suspended"
+	ListingDelisted  = "delisted"
+)
+
+const (
+	AliasOther        = "alias"
+	AliasFormerSymbol = "former_symbol"
+)
+
+type InstrumentAlias struct {
+	Alias     string `json:"alias"`
+	AliasType string `json:"alias_type"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+// InstrumentRename is one change of an instrument's symbol.
+type InstrumentRename struct {
+	OldSymbol string `json:"old_symbol"`
+	NewSymbol string `json:"new_symbol"`
+	Reason    string `json:"reason"`
+	ChangedBy string `json:"changed_by"`
+	ChangedAt string `json:"changed_at"`
+}
+
+// InstrumentRequest creates an instrument or replaces its details. Symbol
+// defaults to NSESymbol, then BSESymbol, and is only read on create; renames
+// go through InstrumentRenameRequest.
+type InstrumentRequest struct {
+	ISIN          string   `json:"isin"`
+	Symbol        string   `json:"symbol"`
+	NSESymbol     string   `json:"nse_symbol"`
+	BSESymbol     string   `json:"bse_symbol"`
+	Name          string   `json:"name"`
+	Sector        string   `json:"sector"`
+	FaceValue     *float64 `json:"face_value"`
+	ListingStatus string   `json:"listing_status"`
+}
+
+type InstrumentRenameRequest struct {
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+}
+
+type InstrumentAliasRequest struct {
+	Alias string `json:"alias"`
+}